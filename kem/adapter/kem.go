@@ -7,7 +7,9 @@ package adapter
 
 import (
 	"crypto/hmac"
+	"encoding/binary"
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/blake2b"
 
@@ -23,12 +25,29 @@ const (
 
 var _ kem.PrivateKey = (*PrivateKey)(nil)
 var _ kem.PublicKey = (*PublicKey)(nil)
+var _ kem.MarshalerTo = (*PublicKey)(nil)
 var _ kem.Scheme = (*Scheme)(nil)
 
 // PublicKey is an adapter for nike.PublicKey to kem.PublicKey.
+// PublicKeys are immutable, so the encoding of the NIKE public key is
+// computed at most once and cached, which matters for the larger CTIDH
+// keys in hot paths.
 type PublicKey struct {
 	publicKey nike.PublicKey
 	scheme    *Scheme
+
+	encodeOnce sync.Once
+	encoded    []byte
+	encodeErr  error
+}
+
+// bytes returns the cached encoding of the NIKE public key, which
+// must not be modified.
+func (p *PublicKey) bytes() ([]byte, error) {
+	p.encodeOnce.Do(func() {
+		p.encoded, p.encodeErr = p.publicKey.MarshalBinary()
+	})
+	return p.encoded, p.encodeErr
 }
 
 func (p *PublicKey) Scheme() kem.Scheme {
@@ -40,7 +59,26 @@ func (p *PublicKey) MarshalText() (text []byte, err error) {
 }
 
 func (p *PublicKey) MarshalBinary() ([]byte, error) {
-	return p.publicKey.MarshalBinary()
+	b, err := p.bytes()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
+}
+
+// MarshalBinaryTo copies the encoding of the public key into dst and
+// returns the number of bytes written.
+func (p *PublicKey) MarshalBinaryTo(dst []byte) (int, error) {
+	b, err := p.bytes()
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < len(b) {
+		return 0, kem.ErrPubKeySize
+	}
+	return copy(dst, b), nil
 }
 
 func (p *PublicKey) Equal(pubkey kem.PublicKey) bool {
@@ -71,6 +109,11 @@ func (p *PrivateKey) Equal(privkey kem.PrivateKey) bool {
 	return hmac.Equal(privkey.(*PrivateKey).privateKey.Bytes(), p.privateKey.Bytes())
 }
 
+// Reset zeroizes the NIKE private key.
+func (p *PrivateKey) Reset() {
+	p.privateKey.Reset()
+}
+
 func (p *PrivateKey) Public() kem.PublicKey {
 	return &PublicKey{
 		publicKey: p.privateKey.Public(),
@@ -82,7 +125,8 @@ func (p *PrivateKey) Public() kem.PublicKey {
 // See docs/specs/kemsphinx.rst for some design notes
 // on this NIKE to KEM adapter.
 type Scheme struct {
-	nike nike.Scheme
+	nike  nike.Scheme
+	label []byte
 }
 
 var _ kem.Scheme = (*Scheme)(nil)
@@ -92,12 +136,27 @@ var _ kem.PrivateKey = (*PrivateKey)(nil)
 // FromNIKE creates a new KEM adapter Scheme
 // using the given NIKE Scheme.
 func FromNIKE(nike nike.Scheme) kem.Scheme {
+	return FromNIKEWithLabel(nike, nil)
+}
+
+// FromNIKEWithLabel creates a new KEM adapter Scheme using the given
+// NIKE Scheme and a domain separation label which is hashed together
+// with the DH output and public keys when deriving the shared secret.
+// Adapters over the same NIKE with different labels can share key pairs
+// yet derive unrelated shared secrets. An empty label is equivalent to
+// using FromNIKE.
+func FromNIKEWithLabel(nike nike.Scheme, label []byte) kem.Scheme {
 	if nike == nil {
 		return nil
 	}
-	return &Scheme{
+	s := &Scheme{
 		nike: nike,
 	}
+	if len(label) != 0 {
+		s.label = make([]byte, len(label))
+		copy(s.label, label)
+	}
+	return s
 }
 
 // Name of the scheme
@@ -105,6 +164,22 @@ func (a *Scheme) Name() string {
 	return a.nike.Name()
 }
 
+// NIKE returns the NIKE scheme the adapter is built on.
+func (a *Scheme) NIKE() nike.Scheme {
+	return a.nike
+}
+
+// Label returns a copy of the domain separation label given to
+// FromNIKEWithLabel, or nil if there is none.
+func (a *Scheme) Label() []byte {
+	if len(a.label) == 0 {
+		return nil
+	}
+	label := make([]byte, len(a.label))
+	copy(label, a.label)
+	return label
+}
+
 // GenerateKeyPair creates a new key pair.
 func (a *Scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
 	pubkey, privkey, err := a.nike.GenerateKeyPair()
@@ -131,10 +206,18 @@ func (a *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	return a.encapsulate(theirPubkey, myPubkey, sk2)
+}
+
+func (a *Scheme) encapsulate(theirPubkey *PublicKey, myPubkey kem.PublicKey, sk2 kem.PrivateKey) (ct, ss []byte, err error) {
 	// ss = DH(my_privkey, their_pubkey)
 	ss = a.nike.DeriveSecret(sk2.(*PrivateKey).privateKey, theirPubkey.publicKey)
+	theirPubkeyBytes, err := theirPubkey.bytes()
+	if err != nil {
+		return nil, nil, err
+	}
 	// ss2 = H(ss || their_pubkey || my_pubkey)
-	ss2 := a.hash(ss, theirPubkey.publicKey.Bytes(), myPubkey.(*PublicKey).publicKey.Bytes())
+	ss2 := a.hash(ss, theirPubkeyBytes, myPubkey.(*PublicKey).publicKey.Bytes())
 	ct, _ = myPubkey.MarshalBinary()
 	return ct, ss2, nil
 }
@@ -151,6 +234,16 @@ func (a *Scheme) hash(ss []byte, pubkey1 []byte, pubkey2 []byte) []byte {
 	if err != nil {
 		panic(err)
 	}
+	if len(a.label) != 0 {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(a.label)))
+		if _, err = h.Write(l[:]); err != nil {
+			panic(err)
+		}
+		if _, err = h.Write(a.label); err != nil {
+			panic(err)
+		}
+	}
 	_, err = h.Write(pubkey1)
 	if err != nil {
 		panic(err)
@@ -173,7 +266,7 @@ func (a *Scheme) hash(ss []byte, pubkey1 []byte, pubkey2 []byte) []byte {
 // see docs/specs/kemsphinx.rst
 func (a *Scheme) Decapsulate(myPrivkey kem.PrivateKey, ct []byte) ([]byte, error) {
 	if len(ct) != a.CiphertextSize() {
-		return nil, kem.ErrCiphertextSize
+		return nil, kem.CiphertextSizeError(a.CiphertextSize(), len(ct))
 	}
 	theirPubkey, err := a.UnmarshalBinaryPublicKey(ct)
 	if err != nil {
@@ -186,10 +279,28 @@ func (a *Scheme) Decapsulate(myPrivkey kem.PrivateKey, ct []byte) ([]byte, error
 	return ss2, nil
 }
 
+// ImplicitRejection returns false because a ciphertext which doesn't
+// decode as a valid NIKE public key causes Decapsulate to return
+// an error.
+func (a *Scheme) ImplicitRejection() bool {
+	return false
+}
+
+// Capabilities returns the capability flags of the adapter, with
+// kem.CapPostQuantum set if the NIKE reports being post quantum, as the
+// CTIDH schemes and hybrids containing them do.
+func (a *Scheme) Capabilities() kem.Caps {
+	caps := kem.CapDeterministicKeygen
+	if nike.PostQuantum(a.nike) {
+		caps |= kem.CapPostQuantum
+	}
+	return caps
+}
+
 // Unmarshals a PublicKey from the provided buffer.
 func (a *Scheme) UnmarshalBinaryPublicKey(b []byte) (kem.PublicKey, error) {
 	if len(b) != a.PublicKeySize() {
-		return nil, fmt.Errorf("UnmarshalBinaryPublicKey: wrong key size %d != %d", len(b), a.PublicKeySize())
+		return nil, kem.PubKeySizeError(a.PublicKeySize(), len(b))
 	}
 	pubkey, err := a.nike.UnmarshalBinaryPublicKey(b)
 	if err != nil {
@@ -204,7 +315,7 @@ func (a *Scheme) UnmarshalBinaryPublicKey(b []byte) (kem.PublicKey, error) {
 // Unmarshals a PrivateKey from the provided buffer.
 func (a *Scheme) UnmarshalBinaryPrivateKey(b []byte) (kem.PrivateKey, error) {
 	if len(b) != a.PrivateKeySize() {
-		return nil, fmt.Errorf("UnmarshalBinaryPrivateKey: wrong key size %d != %d", len(b), a.PrivateKeySize())
+		return nil, kem.PrivKeySizeError(a.PrivateKeySize(), len(b))
 	}
 	privkey, err := a.nike.UnmarshalBinaryPrivateKey(b)
 	if err != nil {
@@ -249,7 +360,7 @@ func (a *Scheme) PublicKeySize() int {
 // SeedSize.
 func (a *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
 	if len(seed) != a.SeedSize() {
-		panic(fmt.Errorf("%s: provided len(seed) %d != a.SeedSize() %d", kem.ErrSeedSize, len(seed), a.SeedSize()))
+		panic(fmt.Errorf("%w: provided len(seed) %d != a.SeedSize() %d", kem.ErrSeedSize, len(seed), a.SeedSize()))
 	}
 	h, err := blake2b.NewXOF(0, nil)
 	if err != nil {
@@ -289,5 +400,19 @@ func (a *Scheme) SeedSize() int {
 // see docs/specs/kemsphinx.rst
 func (a *Scheme) EncapsulateDeterministically(pk kem.PublicKey, seed []byte) (
 	ct, ss []byte, err error) {
-	panic("not implemented")
+	if len(seed) != a.EncapsulationSeedSize() {
+		return nil, nil, kem.ErrSeedSize
+	}
+	theirPubkey, ok := pk.(*PublicKey)
+	if !ok || theirPubkey.scheme != a {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+	myPubkey, sk2 := a.DeriveKeyPair(seed)
+	return a.encapsulate(theirPubkey, myPubkey, sk2)
+}
+
+// EncapsulationSeedSize returns the size of the seed used by
+// EncapsulateDeterministically.
+func (a *Scheme) EncapsulationSeedSize() int {
+	return SeedSize
 }
@@ -1,11 +1,14 @@
 package adapter
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/katzenpost/hpqc/nike"
 	ecdh "github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
 	"github.com/katzenpost/hpqc/rand"
 )
 
@@ -31,3 +34,103 @@ func TestNikeToKemAdapter(t *testing.T) {
 
 	t.Logf("our shared key is %x", ssA)
 }
+
+func TestPublicFromPrivateKey(t *testing.T) {
+	for _, n := range []nike.Scheme{ecdh.Scheme(rand.Reader), x448.Scheme(rand.Reader)} {
+		s := FromNIKE(n)
+
+		pubkey1, privkey1, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+
+		// load only the private key and recompute its public key
+		blob, err := privkey1.MarshalBinary()
+		require.NoError(t, err)
+		privkey2, err := s.UnmarshalBinaryPrivateKey(blob)
+		require.NoError(t, err)
+
+		pubkey2 := privkey2.Public()
+		require.True(t, pubkey1.Equal(pubkey2), n.Name())
+
+		// the NIKE scheme's DerivePublicKey must agree as well
+		derived := n.DerivePublicKey(privkey2.(*PrivateKey).privateKey)
+		require.Equal(t, pubkey1.(*PublicKey).publicKey.Bytes(), derived.Bytes(), n.Name())
+	}
+}
+
+func TestFromNIKEWithLabel(t *testing.T) {
+	ecdhNike := ecdh.Scheme(rand.Reader)
+	plain := FromNIKE(ecdhNike).(*Scheme)
+	empty := FromNIKEWithLabel(ecdhNike, []byte{}).(*Scheme)
+	labeled1 := FromNIKEWithLabel(ecdhNike, []byte("protocol one")).(*Scheme)
+	labeled2 := FromNIKEWithLabel(ecdhNike, []byte("protocol two")).(*Scheme)
+
+	pubkey, privkey, err := plain.GenerateKeyPair()
+	require.NoError(t, err)
+	blob, err := pubkey.MarshalBinary()
+	require.NoError(t, err)
+
+	seed := make([]byte, SeedSize)
+	_, err = rand.Reader.Read(seed)
+	require.NoError(t, err)
+
+	encap := func(s *Scheme) ([]byte, []byte) {
+		pk, err := s.UnmarshalBinaryPublicKey(blob)
+		require.NoError(t, err)
+		ct, ss, err := s.EncapsulateDeterministically(pk, seed)
+		require.NoError(t, err)
+		return ct, ss
+	}
+
+	ct, ss := encap(plain)
+	ctEmpty, ssEmpty := encap(empty)
+	require.Equal(t, ct, ctEmpty)
+	require.Equal(t, ss, ssEmpty)
+
+	ct1, ss1 := encap(labeled1)
+	ct2, ss2 := encap(labeled2)
+	require.Equal(t, ct, ct1)
+	require.Equal(t, ct, ct2)
+	require.NotEqual(t, ss, ss1)
+	require.NotEqual(t, ss1, ss2)
+
+	skBlob, err := privkey.MarshalBinary()
+	require.NoError(t, err)
+	sk1, err := labeled1.UnmarshalBinaryPrivateKey(skBlob)
+	require.NoError(t, err)
+	ss1b, err := labeled1.Decapsulate(sk1, ct1)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss1b)
+}
+
+func TestPublicKeyEncodingCache(t *testing.T) {
+	s := FromNIKE(ecdh.Scheme(rand.Reader))
+	pubkey, privkey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	blobs := make([][]byte, 8)
+	for i := range blobs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blobs[i], _ = pubkey.MarshalBinary()
+			ct, ss, err := s.Encapsulate(pubkey)
+			require.NoError(t, err)
+			ss2, err := s.Decapsulate(privkey, ct)
+			require.NoError(t, err)
+			require.Equal(t, ss, ss2)
+		}(i)
+	}
+	wg.Wait()
+
+	want := pubkey.(*PublicKey).publicKey.Bytes()
+	for _, blob := range blobs {
+		require.Equal(t, want, blob)
+	}
+
+	// Callers get their own copy of the cached encoding.
+	blobs[0][0] ^= 0xff
+	blob, err := pubkey.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, want, blob)
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package bufpool provides a pool of byte buffers for short lived
+// ciphertexts, shared secrets and scratch space, to reduce allocations
+// on busy KEM code paths. Buffers are zeroized when they are returned,
+// so secrets never linger in the pool.
+package bufpool
+
+import (
+	"math/bits"
+	"sync"
+
+	"github.com/katzenpost/hpqc/util"
+)
+
+const (
+	// minShift and maxShift bound the size classes of pooled buffers,
+	// from 64 bytes to 2 MiB, which fits every Classic McEliece key.
+	minShift = 6
+	maxShift = 21
+)
+
+// pools holds one pool per power of two size class. The pools hold
+// pointers to slices, since storing a slice in an interface allocates.
+// Get hands the emptied pointers to headers, where Put picks them up
+// again, so that a steady Get and Put cycle doesn't allocate.
+var (
+	pools   [maxShift - minShift + 1]sync.Pool
+	headers sync.Pool
+)
+
+// class returns the index of the smallest size class holding size
+// bytes, or -1 if size is too large to pool.
+func class(size int) int {
+	if size <= 1<<minShift {
+		return 0
+	}
+	shift := bits.Len(uint(size - 1))
+	if shift > maxShift {
+		return -1
+	}
+	return shift - minShift
+}
+
+// Get returns a zeroed buffer of length size, taken from the pool if
+// possible. Its capacity may be larger. Return it with Put once it is no
+// longer used. It panics if size is negative.
+func Get(size int) []byte {
+	if size < 0 {
+		panic("bufpool: negative buffer size")
+	}
+	c := class(size)
+	if c < 0 {
+		return make([]byte, size)
+	}
+	if p, ok := pools[c].Get().(*[]byte); ok {
+		buf := (*p)[:size]
+		*p = nil
+		headers.Put(p)
+		return buf
+	}
+	return make([]byte, size, 1<<(c+minShift))
+}
+
+// Put zeroizes the whole capacity of buf and returns it to the pool.
+// Buffers which didn't come from Get are accepted, but only kept if
+// their capacity is one of the pooled sizes. buf must not be used
+// afterwards, and slices of it must not be held.
+func Put(buf []byte) {
+	buf = buf[:cap(buf)]
+	util.ExplicitBzero(buf)
+	c := class(len(buf))
+	if c < 0 || len(buf) != 1<<(c+minShift) {
+		return
+	}
+	p, _ := headers.Get().(*[]byte)
+	if p == nil {
+		p = new([]byte)
+	}
+	*p = buf
+	pools[c].Put(p)
+}
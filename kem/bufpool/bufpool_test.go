@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package bufpool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPut(t *testing.T) {
+	for _, size := range []int{0, 1, 32, 64, 65, 1088, 1 << maxShift} {
+		buf := Get(size)
+		require.Len(t, buf, size)
+		require.Equal(t, make([]byte, size), buf)
+		for i := range buf {
+			buf[i] = 0xaa
+		}
+		Put(buf)
+
+		// Whether or not the same buffer comes back, it is zeroed.
+		buf = Get(size)
+		require.Equal(t, make([]byte, size), buf)
+		Put(buf)
+	}
+
+	buf := Get(1<<maxShift + 1)
+	require.Len(t, buf, 1<<maxShift+1)
+	Put(buf)
+
+	require.Panics(t, func() { Get(-1) })
+}
+
+func TestPutZeroizes(t *testing.T) {
+	buf := Get(100)
+	secret := bytes.Repeat([]byte{0x42}, cap(buf))
+	copy(buf[:cap(buf)], secret)
+	full := buf[:cap(buf)]
+	Put(buf[:10])
+	require.Equal(t, make([]byte, len(full)), full)
+
+	// Foreign buffers are zeroized even if they aren't kept.
+	foreign := bytes.Repeat([]byte{0x42}, 100)
+	Put(foreign)
+	require.Equal(t, make([]byte, 100), foreign)
+}
+
+func TestGetPutAllocs(t *testing.T) {
+	Put(Get(1088))
+	allocs := testing.AllocsPerRun(100, func() {
+		Put(Get(1088))
+	})
+	require.Zero(t, allocs)
+}
+
+func BenchmarkGetPut(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Put(Get(1088))
+	}
+}
+
+func BenchmarkMake(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		buf := make([]byte, 1088)
+		_ = buf
+	}
+}
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import "strings"
+
+// Caps is a set of scheme capability flags, for checking several
+// properties of a scheme at once, for example:
+//
+//	if kem.Capabilities(s)&kem.CapPostQuantum == 0 { ... }
+type Caps uint32
+
+const (
+	// CapPostQuantum is set if the scheme, or at least one of its
+	// components, is believed to resist quantum attacks.
+	CapPostQuantum Caps = 1 << iota
+
+	// CapImplicitReject is set if Decapsulate uses implicit rejection,
+	// as reported by ImplicitRejection.
+	CapImplicitReject
+
+	// CapDeterministicKeygen is set if DeriveKeyPair derives the same
+	// key pair from the same seed.
+	CapDeterministicKeygen
+
+	// CapHybrid is set if the scheme combines several KEMs.
+	CapHybrid
+)
+
+var capNames = []string{"post-quantum", "implicit-reject", "deterministic-keygen", "hybrid"}
+
+// Has returns true if all the flags in want are set.
+func (c Caps) Has(want Caps) bool {
+	return c&want == want
+}
+
+// String returns the names of the set flags separated by "|".
+func (c Caps) String() string {
+	names := []string{}
+	for i, name := range capNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// CapabilityReporter is implemented by schemes which report their own
+// capability flags.
+type CapabilityReporter interface {
+	// Capabilities returns the scheme's capability flags.
+	Capabilities() Caps
+}
+
+// Capabilities returns the capability flags of the given scheme. For
+// schemes which don't implement CapabilityReporter, such as those used
+// directly from circl, the flags are inferred from the other optional
+// interfaces: CapPostQuantum and CapDeterministicKeygen are set for the
+// schemes found by Circl, since every KEM of our circl fork is post
+// quantum and derives its key pairs deterministically from the seed.
+// Other schemes can't be assumed to derive deterministically, even
+// though every Scheme has a DeriveKeyPair method, so they don't get
+// CapDeterministicKeygen. The schemes package has a Capabilities
+// function which also fills in CapPostQuantum for other schemes it
+// knows.
+func Capabilities(s Scheme) Caps {
+	if r, ok := s.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	var caps Caps
+	if Circl(s) != nil {
+		caps |= CapPostQuantum | CapDeterministicKeygen
+	}
+	if ImplicitRejection(s) {
+		caps |= CapImplicitReject
+	}
+	if _, ok := s.(Composite); ok {
+		caps |= CapHybrid
+	}
+	return caps
+}
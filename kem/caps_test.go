@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"testing"
+
+	"github.com/katzenpost/circl/kem/kyber/kyber768"
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/hybrid"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/kem/sntrup"
+	"github.com/katzenpost/hpqc/kem/xwing"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestCapabilities(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+
+	caps := kem.Capabilities(x25519KEM)
+	require.Equal(t, kem.CapDeterministicKeygen, caps)
+	require.Equal(t, "deterministic-keygen", caps.String())
+
+	caps = kem.Capabilities(mlkem768.Scheme())
+	require.True(t, caps.Has(kem.CapPostQuantum|kem.CapImplicitReject))
+	require.False(t, caps.Has(kem.CapHybrid))
+
+	caps = kem.Capabilities(xwing.Scheme())
+	require.True(t, caps.Has(kem.CapPostQuantum|kem.CapImplicitReject|kem.CapHybrid))
+
+	caps = kem.Capabilities(sntrup.Scheme())
+	require.True(t, caps.Has(kem.CapPostQuantum))
+	require.False(t, caps.Has(kem.CapImplicitReject))
+
+	caps = kem.Capabilities(combiner.New("MLKEM768-X25519", []kem.Scheme{mlkem768.Scheme(), x25519KEM}))
+	require.Equal(t, kem.CapPostQuantum|kem.CapDeterministicKeygen|kem.CapHybrid, caps)
+	require.Equal(t, "post-quantum|deterministic-keygen|hybrid", caps.String())
+
+	caps = kem.Capabilities(kyber768.Scheme())
+	require.True(t, caps.Has(kem.CapPostQuantum|kem.CapDeterministicKeygen))
+	caps = kem.Capabilities(combiner.New("Kyber768-X25519", []kem.Scheme{kyber768.Scheme(), x25519KEM}))
+	require.True(t, caps.Has(kem.CapPostQuantum))
+	caps = kem.Capabilities(hybrid.New("Kyber768-X25519", kyber768.Scheme(), x25519KEM))
+	require.True(t, caps.Has(kem.CapPostQuantum))
+
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	caps = kem.Capabilities(combiner.New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM}))
+	require.Zero(t, caps&kem.CapPostQuantum)
+
+	// nothing is assumed of schemes which don't report their capabilities
+	unknown := struct{ kem.Scheme }{x25519KEM}
+	require.Zero(t, kem.Capabilities(unknown))
+	caps = kem.Capabilities(combiner.New("Unknown-X448", []kem.Scheme{unknown, x448KEM}))
+	require.False(t, caps.Has(kem.CapDeterministicKeygen))
+}
@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"reflect"
+	"strings"
+)
+
+// circlPath is the import path of our circl fork.
+const circlPath = "github.com/katzenpost/circl/"
+
+// CirclProvider is implemented by wrapper schemes which can hand out
+// the circl scheme they are built on.
+type CirclProvider interface {
+	// Circl returns the underlying circl scheme, or nil if there is
+	// none. See the Circl function.
+	Circl() interface{}
+}
+
+// Circl returns the circl scheme underneath the given scheme, or nil if
+// it isn't built on circl. Wrappers are looked through using their
+// Circl or Unwrap methods, so a scheme such as Classic McEliece, which
+// is used directly from circl, is found even when wrapped by the
+// instrument or policy packages. Composite schemes are not looked
+// through, and note that ML-KEM-768 and X-Wing are built on
+// filippo.io/mlkem768 rather than circl.
+//
+// This is an escape hatch for advanced users who need circl specific
+// APIs. The result depends on implementation details which may change
+// in any release, and anything done with it bypasses the checks made
+// by this module.
+func Circl(s Scheme) interface{} {
+	for s != nil {
+		if p, ok := s.(CirclProvider); ok {
+			return p.Circl()
+		}
+		t := reflect.TypeOf(s)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if strings.HasPrefix(t.PkgPath(), circlPath) {
+			return s
+		}
+		u, ok := s.(interface{ Unwrap() Scheme })
+		if !ok {
+			return nil
+		}
+		s = u.Unwrap()
+	}
+	return nil
+}
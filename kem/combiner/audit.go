@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/nike"
+)
+
+// WarningKind identifies the kind of weakness reported by Audit.
+type WarningKind int
+
+const (
+	// WarningNoPostQuantum means that every component is classical,
+	// so the combiner offers no protection against quantum adversaries.
+	WarningNoPostQuantum WarningKind = iota
+
+	// WarningDuplicateComponent means that a component scheme is used
+	// more than once, possibly nested inside another component, so the
+	// combiner has fewer independent components than it appears to.
+	WarningDuplicateComponent
+
+	// WarningSingleComponent means that the combiner has only one
+	// component and so isn't a hybrid at all.
+	WarningSingleComponent
+)
+
+// Warning is a potential weakness of a combiner found by Audit.
+type Warning struct {
+	Kind WarningKind
+
+	// Index is the index of the offending component as returned by
+	// Components, or -1 if the warning is about the combiner as a whole.
+	Index int
+
+	// Message is a human readable description of the warning.
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// isClassical returns true if the scheme is known to offer no
+// post-quantum security, which is the case of KEMs adapted from a NIKE
+// that isn't post-quantum, such as X25519, X448 and finite field
+// Diffie-Hellman. Any other scheme is assumed to be post-quantum.
+func isClassical(s kem.Scheme) bool {
+	for {
+		u, ok := s.(interface{ Unwrap() kem.Scheme })
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	a, ok := s.(*adapter.Scheme)
+	return ok && !nike.PostQuantum(a.NIKE())
+}
+
+// Audit checks the combiner for accidentally weak constructions, such
+// as having no post-quantum component or using the same scheme twice,
+// and returns a warning for each problem found. Components which are
+// themselves composite, such as nested combiners, are inspected
+// recursively through kem.Composite. Scheme names are compared case
+// insensitively. A combiner without warnings returns nil.
+func (sch *Scheme) Audit() []Warning {
+	var warnings []Warning
+	if len(sch.schemes) == 1 {
+		warnings = append(warnings, Warning{
+			Kind:    WarningSingleComponent,
+			Index:   -1,
+			Message: fmt.Sprintf("%s: only one component", sch.name),
+		})
+	}
+
+	postQuantum := false
+	seen := make(map[string]int)
+	for i, s := range sch.schemes {
+		for _, leaf := range leafSchemes(s) {
+			name := strings.ToLower(leaf.Name())
+			if !isClassical(leaf) {
+				postQuantum = true
+			}
+			if j, ok := seen[name]; ok {
+				warnings = append(warnings, Warning{
+					Kind:    WarningDuplicateComponent,
+					Index:   i,
+					Message: fmt.Sprintf("%s: duplicate component %s at index %d, first used at index %d", sch.name, leaf.Name(), i, j),
+				})
+				continue
+			}
+			seen[name] = i
+		}
+	}
+	if !postQuantum {
+		warnings = append(warnings, Warning{
+			Kind:    WarningNoPostQuantum,
+			Index:   -1,
+			Message: fmt.Sprintf("%s: no post-quantum component", sch.name),
+		})
+	}
+	return warnings
+}
+
+// leafSchemes returns the non composite schemes making up s.
+func leafSchemes(s kem.Scheme) []kem.Scheme {
+	c, ok := s.(kem.Composite)
+	if !ok {
+		return []kem.Scheme{s}
+	}
+	var leaves []kem.Scheme
+	for _, component := range c.Components() {
+		leaves = append(leaves, leafSchemes(component)...)
+	}
+	return leaves
+}
+
+// ComponentContributes checks at runtime that the component at index
+// actually influences the combined shared secret. It generates a fresh
+// key pair and encapsulates to it twice, then returns false if the
+// component's shared secret was the same both times, as it would be for
+// a broken or stubbed out component which returns a constant, or if
+// flipping a bit of the component's shared secret leaves the combined
+// secret unchanged. Each call costs a key generation, two
+// encapsulations and several decapsulations, so this is meant for self
+// tests at startup rather than for every Decapsulate.
+func (sch *Scheme) ComponentContributes(index int) (bool, error) {
+	if index < 0 || index >= len(sch.schemes) {
+		return false, fmt.Errorf("combiner: component index %d out of range [0, %d)", index, len(sch.schemes))
+	}
+	pk, sk, err := sch.GenerateKeyPair()
+	if err != nil {
+		return false, err
+	}
+	ct1, _, err := sch.Encapsulate(pk)
+	if err != nil {
+		return false, err
+	}
+	ct2, _, err := sch.Encapsulate(pk)
+	if err != nil {
+		return false, err
+	}
+	ss2, err := sch.DecapsulateComponent(sk, ct2, index)
+	if err != nil {
+		return false, err
+	}
+
+	offsets := sch.CiphertextOffsets()
+	secrets := make([][]byte, len(sch.schemes))
+	ciphertexts := make([][]byte, len(sch.schemes))
+	for i, s := range sch.schemes {
+		ciphertexts[i] = ct1[offsets[i] : offsets[i]+s.CiphertextSize()]
+		secrets[i], err = sch.DecapsulateComponent(sk, ct1, i)
+		if err != nil {
+			return false, err
+		}
+	}
+	if bytes.Equal(secrets[index], ss2) {
+		return false, nil
+	}
+
+	combined := sch.combine(secrets, ciphertexts)
+	perturbed := make([][]byte, len(secrets))
+	copy(perturbed, secrets)
+	perturbed[index] = bytes.Clone(secrets[index])
+	perturbed[index][0] ^= 0x01
+	return !bytes.Equal(combined, sch.combine(perturbed, ciphertexts)), nil
+}
@@ -24,23 +24,55 @@
 package combiner
 
 import (
+	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-faster/xor"
 
 	"github.com/katzenpost/hpqc/kem"
 	"github.com/katzenpost/hpqc/kem/pem"
 	"github.com/katzenpost/hpqc/kem/util"
+	hpqcutil "github.com/katzenpost/hpqc/util"
 	"golang.org/x/crypto/blake2b"
 )
 
 var (
 	// ErrUninitialized indicates a key wasn't initialized.
 	ErrUninitialized = errors.New("public or private key not initialized")
+
+	// ErrNoSchemes indicates that a combiner was given no KEM schemes.
+	ErrNoSchemes = errors.New("combiner: at least one KEM scheme is required")
+
+	// ErrNilScheme indicates that one of the given KEM schemes was nil.
+	ErrNilScheme = errors.New("combiner: KEM scheme cannot be nil")
+
+	// ErrDuplicateScheme indicates that the same KEM scheme was given
+	// more than once. Combining a KEM with itself adds no security and
+	// hides the fact that the hybrid has fewer independent components
+	// than it appears to.
+	ErrDuplicateScheme = errors.New("combiner: duplicate KEM scheme")
+
+	// ErrWeakSharedSecret indicates that a component KEM returned a
+	// shared secret which is all zeros or all 0xff bytes, which is
+	// only checked by combiners created with WithSanityCheck.
+	ErrWeakSharedSecret = errors.New("combiner: degenerate component shared secret")
+
+	// ErrIncompatibleOptions indicates that options were given which
+	// can't be combined.
+	ErrIncompatibleOptions = errors.New("combiner: incompatible options")
 )
 
 var _ kem.PrivateKey = (*PrivateKey)(nil)
 var _ kem.PublicKey = (*PublicKey)(nil)
+var _ kem.MarshalerTo = (*PublicKey)(nil)
 var _ kem.Scheme = (*Scheme)(nil)
+var _ kem.Composite = (*Scheme)(nil)
+var _ kem.ContextKeyGenerator = (*Scheme)(nil)
 
 // Public key of a combined KEMs.
 type PublicKey struct {
@@ -58,8 +90,35 @@ type PrivateKey struct {
 type Scheme struct {
 	name    string
 	schemes []kem.Scheme
+
+	// outLen is the shared key size when a final KDF is
+	// applied to the split PRF output, or 0 if it isn't.
+	outLen int
+
+	// nameBound is true if the name is hashed by the split PRF.
+	nameBound bool
+
+	// sanityCheck is true if degenerate component shared secrets
+	// are rejected.
+	sanityCheck bool
+
+	// ccaBound is true if the full ciphertext is hashed together with
+	// the split PRF output.
+	ccaBound bool
+
+	// kdf replaces BLAKE2b in the split PRF if not nil, and kdfLen
+	// is the length of its output.
+	kdf    func(inputs ...[]byte) []byte
+	kdfLen int
 }
 
+// fixedOutputLabel domain separates the final KDF used by WithFixedOutput.
+var fixedOutputLabel = []byte("hpqc combiner fixed output")
+
+// ccaBoundLabel domain separates the ciphertext binding used by
+// WithCCABound.
+var ccaBoundLabel = []byte("hpqc combiner cca bound")
+
 // PrivateKey methods
 
 // Scheme returns the given private key's scheme object.
@@ -103,6 +162,27 @@ func (sk *PrivateKey) Equal(other kem.PrivateKey) bool {
 	return true
 }
 
+// Reset zeroizes every component private key which implements
+// kem.Resetter. Components which don't are left untouched, see
+// Resettable.
+func (sk *PrivateKey) Reset() {
+	for _, k := range sk.keys {
+		if r, ok := k.(kem.Resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// Resettable returns true if Reset zeroizes every component private key.
+func (sk *PrivateKey) Resettable() bool {
+	for _, k := range sk.keys {
+		if !kem.Resettable(k) {
+			return false
+		}
+	}
+	return true
+}
+
 // Public returns a public key, given a private key.
 func (sk *PrivateKey) Public() kem.PublicKey {
 	pubkeys := make([]kem.PublicKey, len(sk.keys))
@@ -158,28 +238,265 @@ func (sk *PublicKey) MarshalBinary() ([]byte, error) {
 	return blobs, nil
 }
 
+// MarshalBinaryTo writes the binary encoding of the key into dst, which
+// must be at least PublicKeySize() bytes long, and returns the number of
+// bytes written. It doesn't allocate as long as every component
+// implements kem.MarshalerTo.
+func (sk *PublicKey) MarshalBinaryTo(dst []byte) (int, error) {
+	if sk.keys == nil {
+		return 0, ErrUninitialized
+	}
+	for _, s := range sk.keys {
+		if s == nil {
+			return 0, ErrUninitialized
+		}
+	}
+	if len(dst) < sk.scheme.PublicKeySize() {
+		return 0, kem.ErrPubKeySize
+	}
+	n := 0
+	for i := 0; i < len(sk.keys); i++ {
+		m, err := kem.MarshalPublicKeyTo(dst[n:], sk.keys[i])
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
 func (sk *PublicKey) MarshalText() (text []byte, err error) {
 	return pem.ToPublicPEMBytes(sk), nil
 }
 
 // Scheme methods
 
-// New creates a new hybrid KEM given the slices of KEM schemes.
-func New(name string, schemes []kem.Scheme) *Scheme {
-	for _, x := range schemes {
-		if x == nil {
-			panic("KEM scheme cannot be nil")
+// Option configures a combiner created by New or NewOrErr. Options can
+// be combined freely and in any order, except that WithKDF can't be
+// combined with WithFixedOutput or WithCCABound, which hash with BLAKE2b.
+type Option func(*Scheme) error
+
+// New creates a new hybrid KEM given the slices of KEM schemes and
+// options. It panics if any of the schemes is nil or the options are
+// rejected. Unlike NewOrErr it doesn't check for an empty or duplicated
+// list of schemes.
+func New(name string, schemes []kem.Scheme, opts ...Option) *Scheme {
+	if err := checkNil(schemes); err != nil {
+		panic(err)
+	}
+	s := &Scheme{
+		name:    name,
+		schemes: schemes,
+	}
+	if err := s.apply(opts); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewOrErr creates a new hybrid KEM given the slices of KEM schemes and
+// options, returning an error which names the offending index if any of
+// the schemes is nil or appears more than once, or if the options are
+// rejected.
+func NewOrErr(name string, schemes []kem.Scheme, opts ...Option) (*Scheme, error) {
+	if len(schemes) == 0 {
+		return nil, ErrNoSchemes
+	}
+	if err := checkNil(schemes); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]int, len(schemes))
+	for i, x := range schemes {
+		if j, ok := seen[x.Name()]; ok {
+			return nil, fmt.Errorf("%w: %s at index %d and %d", ErrDuplicateScheme, x.Name(), j, i)
 		}
+		seen[x.Name()] = i
 	}
-	return &Scheme{
+	s := &Scheme{
 		name:    name,
 		schemes: schemes,
 	}
+	if err := s.apply(opts); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func checkNil(schemes []kem.Scheme) error {
+	for i, x := range schemes {
+		if x == nil {
+			return fmt.Errorf("%w: index %d", ErrNilScheme, i)
+		}
+	}
+	return nil
+}
+
+// apply applies the options and checks the resulting combination.
+func (sch *Scheme) apply(opts []Option) error {
+	for _, opt := range opts {
+		if err := opt(sch); err != nil {
+			return err
+		}
+	}
+	if sch.kdf != nil && (sch.outLen != 0 || sch.ccaBound) {
+		return fmt.Errorf("%w: WithKDF with WithFixedOutput or WithCCABound", ErrIncompatibleOptions)
+	}
+	if sch.nameBound && len(sch.name) > math.MaxUint16 {
+		return errors.New("combiner: name too long")
+	}
+	if sch.kdf != nil {
+		ciphertexts := make([][]byte, len(sch.schemes))
+		for i, x := range sch.schemes {
+			ciphertexts[i] = make([]byte, x.CiphertextSize())
+		}
+		inputs := [][]byte{make([]byte, sch.schemes[0].SharedKeySize())}
+		inputs = append(inputs, sch.prfCiphertexts(ciphertexts)...)
+		sch.kdfLen = len(sch.kdf(inputs...))
+		if sch.kdfLen == 0 {
+			return errors.New("combiner: KDF returned no output")
+		}
+	}
+	return nil
+}
+
+// WithCanonicalOrder sorts the schemes by name, compared case
+// insensitively, so that the resulting KEM doesn't depend on the order
+// in which the schemes are given. The sorted order is used for the keys,
+// ciphertexts and the split PRF alike, so the wire format differs from
+// that of a combiner created with the schemes in some other order. The
+// given slice isn't modified.
+func WithCanonicalOrder() Option {
+	return func(sch *Scheme) error {
+		sorted := make([]kem.Scheme, len(sch.schemes))
+		copy(sorted, sch.schemes)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return strings.ToLower(sorted[i].Name()) < strings.ToLower(sorted[j].Name())
+		})
+		sch.schemes = sorted
+		return nil
+	}
+}
+
+// WithFixedOutput makes the shared secret outLen bytes long. The split
+// PRF output, after the ciphertext binding of WithCCABound if that is
+// given too, is passed through a final BLAKE2Xb KDF which squeezes it to
+// outLen bytes, therefore the shared secrets differ from those of a
+// combiner without this option even when outLen is 32. outLen must be
+// positive and small enough for BLAKE2Xb.
+func WithFixedOutput(outLen int) Option {
+	return func(sch *Scheme) error {
+		if outLen <= 0 || int64(outLen) >= 1<<32-1 {
+			return fmt.Errorf("combiner: invalid output length %d", outLen)
+		}
+		sch.outLen = outLen
+		return nil
+	}
+}
+
+// WithNameBound binds the combiner's name into the shared secret, so
+// that combiners with the same components but different names derive
+// unrelated shared secrets and a ciphertext can't be reinterpreted under
+// another combiner, for instance after a negotiation downgrade. The
+// name, prefixed with its length as a big endian uint16, is hashed by
+// the split PRF ahead of the ciphertexts. Keys and ciphertexts are
+// unchanged, but the shared secrets differ. The name must be at most
+// 65535 bytes long.
+func WithNameBound() Option {
+	return func(sch *Scheme) error {
+		sch.nameBound = true
+		return nil
+	}
+}
+
+// WithSanityCheck rejects any component shared secret which is all zeros
+// or all 0xff bytes with ErrWeakSharedSecret, whether encapsulating or
+// decapsulating. Such secrets indicate a broken or misconfigured
+// component scheme. Keys, ciphertexts and shared secrets are otherwise
+// unchanged.
+func WithSanityCheck() Option {
+	return func(sch *Scheme) error {
+		sch.sanityCheck = true
+		return nil
+	}
+}
+
+// WithCCABound hashes the entire ciphertext into the shared key after
+// the split PRF:
+//
+//	ss = BLAKE2b-256("hpqc combiner cca bound" || prf || ct1 || ... || ctn)
+//
+// where prf is the split PRF output. The split PRF already hashes all
+// the ciphertexts into each component's term, so this is defense in
+// depth: whatever a weak component contributes, the shared key is a
+// hash of the exact ciphertext received, and a modified ciphertext
+// yields an unrelated key. Keys and ciphertexts are unchanged, but the
+// shared secrets differ.
+func WithCCABound() Option {
+	return func(sch *Scheme) error {
+		sch.ccaBound = true
+		return nil
+	}
+}
+
+// WithKDF uses kdf instead of BLAKE2b-256 as the PRF in the split PRF
+// combiner, for environments where only approved primitives such as
+// HKDF-SHA256 may be used. The shared key is
+//
+//	kdf(ss1, ct1, ..., ctn) XOR ... XOR kdf(ssn, ct1, ..., ctn)
+//
+// so kdf must be a PRF keyed by its first input. Each input is passed
+// as a separate slice, and since the shared secrets and ciphertexts of
+// a given combiner all have fixed sizes kdf may simply concatenate
+// them. With WithNameBound, ct1 is preceded by the length prefixed
+// name. kdf must be deterministic and always return the same number of
+// bytes, which becomes the shared key size; it is called once on zeroed
+// inputs to learn that size, and must not return an empty output. Keys
+// and ciphertexts are unchanged.
+func WithKDF(kdf func(inputs ...[]byte) []byte) Option {
+	return func(sch *Scheme) error {
+		if kdf == nil {
+			return errors.New("combiner: nil KDF")
+		}
+		sch.kdf = kdf
+		return nil
+	}
 }
 
 // Name returns the name of the KEM.
 func (sch *Scheme) Name() string { return sch.name }
 
+// Components returns the component KEM schemes in the order in which
+// their keys and ciphertexts are concatenated.
+func (sch *Scheme) Components() []kem.Scheme {
+	components := make([]kem.Scheme, len(sch.schemes))
+	copy(components, sch.schemes)
+	return components
+}
+
+// CiphertextOffsets returns the byte offsets of the component ciphertexts
+// within a combined ciphertext. It has one more entry than Components,
+// such that ct[offsets[i]:offsets[i+1]] is the ciphertext of component i
+// and the last entry equals CiphertextSize.
+func (sch *Scheme) CiphertextOffsets() []int {
+	offsets := make([]int, len(sch.schemes)+1)
+	for i, s := range sch.schemes {
+		offsets[i+1] = offsets[i] + s.CiphertextSize()
+	}
+	return offsets
+}
+
+// SharedKeySizes returns the size in bytes of the shared secret each
+// component contributes to the split PRF, in the same order as
+// Components. Note that these differ from SharedKeySize, which is the
+// size of the combined output.
+func (sch *Scheme) SharedKeySizes() []int {
+	sizes := make([]int, len(sch.schemes))
+	for i, s := range sch.schemes {
+		sizes[i] = s.SharedKeySize()
+	}
+	return sizes
+}
+
 // PublicKeySize returns the KEM's public key size in bytes.
 func (sch *Scheme) PublicKeySize() int {
 	sum := 0
@@ -209,6 +526,12 @@ func (sch *Scheme) SeedSize() int {
 
 // SharedKeySize returns the KEM's shared key size in bytes.
 func (sch *Scheme) SharedKeySize() int {
+	if sch.outLen != 0 {
+		return sch.outLen
+	}
+	if sch.kdf != nil {
+		return sch.kdfLen
+	}
 	return blake2b.Size256
 }
 
@@ -244,7 +567,37 @@ func (sch *Scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
 		}, nil
 }
 
+// GenerateKeyPairContext generates a key pair like GenerateKeyPair, but
+// checks ctx before each component key generation and passes it on to
+// components which implement kem.ContextKeyGenerator. If ctx is done it
+// resets the component private keys generated so far and returns
+// ctx.Err().
+func (sch *Scheme) GenerateKeyPairContext(ctx context.Context) (kem.PublicKey, kem.PrivateKey, error) {
+	pubKeys := make([]kem.PublicKey, len(sch.schemes))
+	privKeys := make([]kem.PrivateKey, len(sch.schemes))
+
+	for i := 0; i < len(sch.schemes); i++ {
+		pk, sk, err := kem.GenerateKeyPairContext(ctx, sch.schemes[i])
+		if err != nil {
+			kem.ResetPrivateKeys(privKeys[:i]...)
+			return nil, nil, err
+		}
+		pubKeys[i] = pk
+		privKeys[i] = sk
+	}
+
+	return &PublicKey{
+			scheme: sch,
+			keys:   pubKeys,
+		}, &PrivateKey{
+			scheme: sch,
+			keys:   privKeys,
+		}, nil
+}
+
 // DeriveKeyPair uses a seed value to deterministically generate a key pair.
+// Panics of the component schemes are redacted by the top level
+// util.SafePanic.
 func (sch *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
 	if len(seed) != sch.SeedSize() {
 		panic(fmt.Sprintf("seed size must be %d", sch.SeedSize()))
@@ -253,14 +606,55 @@ func (sch *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
 	pubKeys := make([]kem.PublicKey, len(sch.schemes))
 	privKeys := make([]kem.PrivateKey, len(sch.schemes))
 
-	offset := sch.schemes[0].SeedSize()
-	pubKeys[0], privKeys[0] = sch.schemes[0].DeriveKeyPair(seed[:offset])
+	hpqcutil.SafePanic(func() {
+		offset := sch.schemes[0].SeedSize()
+		pubKeys[0], privKeys[0] = sch.schemes[0].DeriveKeyPair(seed[:offset])
 
-	for i := 1; i < len(sch.schemes); i++ {
-		seedSize := sch.schemes[i].SeedSize()
-		pubKeys[i], privKeys[i] = sch.schemes[i].DeriveKeyPair(seed[offset : offset+seedSize])
-		offset += seedSize
+		for i := 1; i < len(sch.schemes); i++ {
+			seedSize := sch.schemes[i].SeedSize()
+			pubKeys[i], privKeys[i] = sch.schemes[i].DeriveKeyPair(seed[offset : offset+seedSize])
+			offset += seedSize
+		}
+	})
+
+	return &PublicKey{
+			scheme: sch,
+			keys:   pubKeys,
+		}, &PrivateKey{
+			scheme: sch,
+			keys:   privKeys,
+		}
+}
+
+// DeriveKeyPairExpanded deterministically derives a key pair from a single
+// seed of at least util.MinExpandSeedSize bytes, expanding it with BLAKE2b
+// and its XOF into an independent seed per component. DeriveKeyPair slices
+// its seed linearly instead and remains the method to use for keys which
+// were previously derived with it. Panics if the seed is too short.
+// Panics of the component schemes are redacted by the top level
+// util.SafePanic, which also zeroizes the expanded seeds.
+func (sch *Scheme) DeriveKeyPairExpanded(seed []byte) (kem.PublicKey, kem.PrivateKey) {
+	if len(seed) < util.MinExpandSeedSize {
+		panic(fmt.Sprintf("seed size must be at least %d", util.MinExpandSeedSize))
+	}
+	sizes := make([]int, len(sch.schemes))
+	for i, s := range sch.schemes {
+		sizes[i] = s.SeedSize()
 	}
+	seeds := util.ExpandSeeds(seed, sizes)
+	defer func() {
+		for _, s := range seeds {
+			hpqcutil.ExplicitBzero(s)
+		}
+	}()
+
+	pubKeys := make([]kem.PublicKey, len(sch.schemes))
+	privKeys := make([]kem.PrivateKey, len(sch.schemes))
+	hpqcutil.SafePanic(func() {
+		for i := 0; i < len(sch.schemes); i++ {
+			pubKeys[i], privKeys[i] = sch.schemes[i].DeriveKeyPair(seeds[i])
+		}
+	}, seeds...)
 
 	return &PublicKey{
 			scheme: sch,
@@ -272,7 +666,15 @@ func (sch *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
 }
 
 // Encapsulate creates a shared secret and ciphertext given a public key.
+// Panics of the component schemes are redacted by util.SafePanic.
 func (sch *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	hpqcutil.SafePanic(func() {
+		ct, ss, err = sch.encapsulate(pk)
+	})
+	return ct, ss, err
+}
+
+func (sch *Scheme) encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
 	pub, ok := pk.(*PublicKey)
 	if !ok {
 		return nil, nil, kem.ErrTypeMismatch
@@ -292,25 +694,152 @@ func (sch *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
 		ciphertextBlob = append(ciphertextBlob, cct...)
 	}
 
-	ss = util.SplitPRF(sharedSecrets, ciphertexts)
-
-	return ciphertextBlob, ss, nil
+	if err := sch.checkSharedSecrets(sharedSecrets); err != nil {
+		return nil, nil, err
+	}
+	return ciphertextBlob, sch.combine(sharedSecrets, ciphertexts), nil
 }
 
 // EncapsulateDeterministically deterministircally encapsulates a share secret to the given public key and the given seed value.
+// The seed is split linearly into one encapsulation seed per component,
+// therefore every component must implement kem.DeterministicEncapsulator.
+// Prefer EncapsulateFromSeed unless the seed is already the concatenation
+// of independent uniformly random component seeds.
 func (sch *Scheme) EncapsulateDeterministically(publicKey kem.PublicKey, seed []byte) (ct, ss []byte, err error) {
-	panic("not implemented")
+	if len(seed) != sch.EncapsulationSeedSize() {
+		return nil, nil, kem.ErrSeedSize
+	}
+	pub, ok := publicKey.(*PublicKey)
+	if !ok {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+
+	ciphertexts := make([][]byte, len(sch.schemes))
+	sharedSecrets := make([][]byte, len(sch.schemes))
+	ciphertextBlob := []byte{}
+	offset := 0
+
+	for i := 0; i < len(sch.schemes); i++ {
+		d, ok := sch.schemes[i].(kem.DeterministicEncapsulator)
+		if !ok {
+			return nil, nil, fmt.Errorf("combiner: %s does not support deterministic encapsulation", sch.schemes[i].Name())
+		}
+		seedSize := d.EncapsulationSeedSize()
+		cct, ss, err := d.EncapsulateDeterministically(pub.keys[i], seed[offset:offset+seedSize])
+		if err != nil {
+			return nil, nil, err
+		}
+		ciphertexts[i] = cct
+		sharedSecrets[i] = ss
+		ciphertextBlob = append(ciphertextBlob, cct...)
+		offset += seedSize
+	}
+
+	if err := sch.checkSharedSecrets(sharedSecrets); err != nil {
+		return nil, nil, err
+	}
+	return ciphertextBlob, sch.combine(sharedSecrets, ciphertexts), nil
+}
+
+// EncapsulateFromSeed deterministically encapsulates a shared secret to
+// the given public key using a single short seed of at least
+// util.MinExpandSeedSize bytes. The seed is expanded with BLAKE2b and
+// its XOF into independent per-component encapsulation seeds, so that
+// a seed which isn't uniformly random can't bias one component's
+// encapsulation without affecting the others.
+func (sch *Scheme) EncapsulateFromSeed(publicKey kem.PublicKey, seed []byte) (ct, ss []byte, err error) {
+	if len(seed) < util.MinExpandSeedSize {
+		return nil, nil, kem.ErrSeedSize
+	}
+	sizes := make([]int, len(sch.schemes))
+	for i, s := range sch.schemes {
+		d, ok := s.(kem.DeterministicEncapsulator)
+		if !ok {
+			return nil, nil, fmt.Errorf("combiner: %s does not support deterministic encapsulation", s.Name())
+		}
+		sizes[i] = d.EncapsulationSeedSize()
+	}
+	seeds := util.ExpandSeeds(seed, sizes)
+	expanded := []byte{}
+	for _, s := range seeds {
+		expanded = append(expanded, s...)
+	}
+	return sch.EncapsulateDeterministically(publicKey, expanded)
+}
+
+// EncapsulationSeedSize returns the size of the seed used by
+// EncapsulateDeterministically, which is the sum of the component
+// encapsulation seed sizes. It returns 0 if any component doesn't
+// support deterministic encapsulation.
+func (sch *Scheme) EncapsulationSeedSize() int {
+	sum := 0
+	for _, s := range sch.schemes {
+		d, ok := s.(kem.DeterministicEncapsulator)
+		if !ok {
+			return 0
+		}
+		sum += d.EncapsulationSeedSize()
+	}
+	return sum
 }
 
 // Decapsulate decrypts a given KEM ciphertext using the given private key.
-func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+// Panics of the component schemes are redacted by util.SafePanic.
+func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) (ss []byte, err error) {
+	hpqcutil.SafePanic(func() {
+		var sharedSecrets, ciphertexts [][]byte
+		sharedSecrets, ciphertexts, err = sch.decapsulate(sk, ct)
+		if err == nil {
+			ss = sch.combine(sharedSecrets, ciphertexts)
+		}
+	})
+	return ss, err
+}
+
+// DecapsulateTranscript decapsulates like Decapsulate but also returns
+// the exact bytes hashed by the split PRF, for auditing and analysis
+// tools. With N components the transcript is
+//
+//	ss1 || ct || ss2 || ct || ... || ssN || ct
+//
+// where ssi is the shared secret of component i, whose length is given
+// by SharedKeySizes, and ct is the whole ciphertext, which is the
+// concatenation of the component ciphertexts. For combiners created
+// with WithNameBound, ct is preceded by the length prefixed name. Each
+// ssi || ct is hashed separately with BLAKE2b-256 and the hashes are
+// XORed together. For combiners created with WithKDF the same bytes go
+// to the KDF instead, split into the separate inputs ssi, ct1, ..., ctN,
+// and the KDF outputs are XORed together. For combiners created with
+// WithCCABound or WithFixedOutput the result then goes through the
+// ciphertext binding or final KDF, whose inputs aren't part of the
+// transcript.
+//
+// The transcript contains the component shared secrets and so must be
+// handled as secret key material.
+func (sch *Scheme) DecapsulateTranscript(sk kem.PrivateKey, ct []byte) (ss []byte, transcript []byte, err error) {
+	sharedSecrets, ciphertexts, err := sch.decapsulate(sk, ct)
+	if err != nil {
+		return nil, nil, err
+	}
+	prfCiphertexts := sch.prfCiphertexts(ciphertexts)
+	for _, s := range sharedSecrets {
+		transcript = append(transcript, s...)
+		for _, c := range prfCiphertexts {
+			transcript = append(transcript, c...)
+		}
+	}
+	return sch.combine(sharedSecrets, ciphertexts), transcript, nil
+}
+
+// decapsulate returns the component shared secrets and ciphertexts.
+func (sch *Scheme) decapsulate(sk kem.PrivateKey, ct []byte) ([][]byte, [][]byte, error) {
 	if len(ct) != sch.CiphertextSize() {
-		return nil, kem.ErrCiphertextSize
+		return nil, nil, kem.CiphertextSizeError(sch.CiphertextSize(), len(ct))
 	}
 
 	priv, ok := sk.(*PrivateKey)
 	if !ok {
-		return nil, kem.ErrTypeMismatch
+		return nil, nil, kem.ErrTypeMismatch
 	}
 
 	sharedSecrets := make([][]byte, len(sch.schemes))
@@ -319,7 +848,7 @@ func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
 
 	ss, err := sch.schemes[0].Decapsulate(priv.keys[0], ct[:offset])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	sharedSecrets[0] = ss
@@ -330,30 +859,201 @@ func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
 		ciphertexts[i] = ct[offset : offset+ciphertextSize]
 		sharedSecrets[i], err = sch.schemes[i].Decapsulate(priv.keys[i], ciphertexts[i])
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		offset += ciphertextSize
 	}
 
-	return util.SplitPRF(sharedSecrets, ciphertexts), nil
+	if err := sch.checkSharedSecrets(sharedSecrets); err != nil {
+		return nil, nil, err
+	}
+	return sharedSecrets, ciphertexts, nil
+}
+
+// checkSharedSecrets returns ErrWeakSharedSecret if sanity checks are
+// enabled and any of the component shared secrets is all zeros or
+// all 0xff bytes.
+func (sch *Scheme) checkSharedSecrets(sharedSecrets [][]byte) error {
+	if !sch.sanityCheck {
+		return nil
+	}
+	for i, ss := range sharedSecrets {
+		and, or := byte(0xff), byte(0)
+		for _, b := range ss {
+			and &= b
+			or |= b
+		}
+		if or == 0 || and == 0xff {
+			return fmt.Errorf("%w: %s at index %d", ErrWeakSharedSecret, sch.schemes[i].Name(), i)
+		}
+	}
+	return nil
+}
+
+// CombineOnly returns the shared key that a combiner created by New
+// derives from the given component shared secrets and ciphertexts,
+// without doing any component KEM operations. It is meant for
+// benchmarking the combination step on its own and for checking other
+// implementations of the split PRF; it doesn't apply the name binding
+// or final KDF of the options. It panics if the slices have
+// different lengths or contain nil or empty entries.
+func CombineOnly(secrets, ciphertexts [][]byte) []byte {
+	return util.SplitPRF(secrets, ciphertexts)
+}
+
+// combine derives the shared key from the component shared secrets
+// and ciphertexts.
+func (sch *Scheme) combine(sharedSecrets, ciphertexts [][]byte) []byte {
+	if sch.kdf != nil {
+		return sch.kdfSplitPRF(sharedSecrets, sch.prfCiphertexts(ciphertexts))
+	}
+	ss := util.SplitPRF(sharedSecrets, sch.prfCiphertexts(ciphertexts))
+	if sch.ccaBound {
+		ss = bindCiphertexts(ss, ciphertexts)
+	}
+	if sch.outLen == 0 {
+		return ss
+	}
+	h, err := blake2b.NewXOF(uint32(sch.outLen), ss)
+	if err != nil {
+		panic(err)
+	}
+	_, err = h.Write(fixedOutputLabel)
+	if err != nil {
+		panic(err)
+	}
+	out := make([]byte, sch.outLen)
+	_, err = h.Read(out)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// bindCiphertexts hashes the ciphertexts together with the split PRF
+// output for combiners created with WithCCABound. The component ciphertexts
+// have fixed sizes, so their concatenation is unambiguous.
+func bindCiphertexts(ss []byte, ciphertexts [][]byte) []byte {
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		panic(err)
+	}
+	h.Write(ccaBoundLabel)
+	h.Write(ss)
+	for _, ct := range ciphertexts {
+		h.Write(ct)
+	}
+	return h.Sum(nil)
+}
+
+// kdfSplitPRF is the split PRF of combiners created with WithKDF.
+func (sch *Scheme) kdfSplitPRF(sharedSecrets, ciphertexts [][]byte) []byte {
+	out := make([]byte, sch.kdfLen)
+	inputs := make([][]byte, len(ciphertexts)+1)
+	copy(inputs[1:], ciphertexts)
+	for _, secret := range sharedSecrets {
+		inputs[0] = secret
+		h := sch.kdf(inputs...)
+		if len(h) != sch.kdfLen {
+			panic(fmt.Sprintf("combiner: KDF returned %d bytes instead of %d", len(h), sch.kdfLen))
+		}
+		xor.Bytes(out, out, h)
+	}
+	return out
+}
+
+// prfCiphertexts returns the ciphertexts as hashed by the split PRF,
+// which for name bound combiners have the length prefixed name prepended
+// to the first ciphertext.
+func (sch *Scheme) prfCiphertexts(ciphertexts [][]byte) [][]byte {
+	if !sch.nameBound {
+		return ciphertexts
+	}
+	first := make([]byte, 0, 2+len(sch.name)+len(ciphertexts[0]))
+	first = binary.BigEndian.AppendUint16(first, uint16(len(sch.name)))
+	first = append(first, sch.name...)
+	first = append(first, ciphertexts[0]...)
+
+	out := make([][]byte, len(ciphertexts))
+	out[0] = first
+	copy(out[1:], ciphertexts[1:])
+	return out
+}
+
+// DecapsulateComponent decapsulates only the component ciphertext at the
+// given index and returns that component's shared secret before it is
+// combined. This is meant for debugging and interop testing; the result
+// is NOT the shared secret returned by Decapsulate and must never be
+// used as a session key.
+func (sch *Scheme) DecapsulateComponent(sk kem.PrivateKey, ct []byte, index int) ([]byte, error) {
+	if index < 0 || index >= len(sch.schemes) {
+		return nil, fmt.Errorf("combiner: component index %d out of range [0, %d)", index, len(sch.schemes))
+	}
+	if len(ct) != sch.CiphertextSize() {
+		return nil, kem.CiphertextSizeError(sch.CiphertextSize(), len(ct))
+	}
+	priv, ok := sk.(*PrivateKey)
+	if !ok {
+		return nil, kem.ErrTypeMismatch
+	}
+
+	offset := 0
+	for i := 0; i < index; i++ {
+		offset += sch.schemes[i].CiphertextSize()
+	}
+	return sch.schemes[index].Decapsulate(priv.keys[index], ct[offset:offset+sch.schemes[index].CiphertextSize()])
+}
+
+// ImplicitRejection returns true only if every component KEM uses
+// implicit rejection. Decapsulate stops at the first component error,
+// so a single component which rejects explicitly means the combined
+// KEM may return an error for an invalid ciphertext. Otherwise a
+// tampered ciphertext produces a shared secret unrelated to the
+// sender's, since both the component secrets and the ciphertexts
+// are inputs to the split PRF.
+func (sch *Scheme) ImplicitRejection() bool {
+	for _, s := range sch.schemes {
+		if !kem.ImplicitRejection(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Capabilities returns CapHybrid, plus CapPostQuantum if any component
+// reports it, CapDeterministicKeygen if every component reports it and
+// CapImplicitReject if ImplicitRejection returns true.
+func (sch *Scheme) Capabilities() kem.Caps {
+	caps := kem.CapHybrid | kem.CapDeterministicKeygen
+	for _, s := range sch.schemes {
+		c := kem.Capabilities(s)
+		caps |= c & kem.CapPostQuantum
+		if !c.Has(kem.CapDeterministicKeygen) {
+			caps &^= kem.CapDeterministicKeygen
+		}
+	}
+	if sch.ImplicitRejection() {
+		caps |= kem.CapImplicitReject
+	}
+	return caps
 }
 
 // UnmarshalBinaryPublicKey unmarshals a binary blob representing a public key.
 func (sch *Scheme) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
 	if len(buf) != sch.PublicKeySize() {
-		return nil, kem.ErrPubKeySize
+		return nil, kem.PubKeySizeError(sch.PublicKeySize(), len(buf))
 	}
 	publicKeys := make([]kem.PublicKey, len(sch.schemes))
 	offset := sch.schemes[0].PublicKeySize()
 	pk1, err := sch.schemes[0].UnmarshalBinaryPublicKey(buf[:offset])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("combiner: %s: %w", sch.schemes[0].Name(), err)
 	}
 	publicKeys[0] = pk1
 	for i := 1; i < len(sch.schemes); i++ {
 		pk, err := sch.schemes[i].UnmarshalBinaryPublicKey(buf[offset : offset+sch.schemes[i].PublicKeySize()])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("combiner: %s: %w", sch.schemes[i].Name(), err)
 		}
 		publicKeys[i] = pk
 		offset += sch.schemes[i].PublicKeySize()
@@ -365,16 +1065,24 @@ func (sch *Scheme) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
 }
 
 // UnmarshalBinaryPrivateKey unmarshals a binary blob representing a private key.
-func (sch *Scheme) UnmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
+// Panics of the component schemes are redacted by util.SafePanic.
+func (sch *Scheme) UnmarshalBinaryPrivateKey(buf []byte) (sk kem.PrivateKey, err error) {
+	hpqcutil.SafePanic(func() {
+		sk, err = sch.unmarshalBinaryPrivateKey(buf)
+	})
+	return sk, err
+}
+
+func (sch *Scheme) unmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
 	if len(buf) != sch.PrivateKeySize() {
-		return nil, kem.ErrPubKeySize
+		return nil, kem.PrivKeySizeError(sch.PrivateKeySize(), len(buf))
 	}
 	privateKeys := make([]kem.PrivateKey, len(sch.schemes))
 	offset := 0
 	for i := 0; i < len(sch.schemes); i++ {
 		pk, err := sch.schemes[i].UnmarshalBinaryPrivateKey(buf[offset : offset+sch.schemes[i].PrivateKeySize()])
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("combiner: %s: %w", sch.schemes[i].Name(), err)
 		}
 		privateKeys[i] = pk
 		offset += sch.schemes[i].PrivateKeySize()
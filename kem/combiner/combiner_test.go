@@ -0,0 +1,801 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package combiner
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-faster/xor"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/kem/sntrup"
+	"github.com/katzenpost/hpqc/kem/util"
+	"github.com/katzenpost/hpqc/kem/xwing"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+	hpqcutil "github.com/katzenpost/hpqc/util"
+)
+
+func TestNewOrErr(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	s, err := NewOrErr("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	require.NoError(t, err)
+	require.Equal(t, "X25519-X448", s.Name())
+
+	_, err = NewOrErr("empty", nil)
+	require.ErrorIs(t, err, ErrNoSchemes)
+
+	_, err = NewOrErr("nil", []kem.Scheme{x25519KEM, nil})
+	require.ErrorIs(t, err, ErrNilScheme)
+	require.Contains(t, err.Error(), "index 1")
+
+	_, err = NewOrErr("dup", []kem.Scheme{x25519KEM, x448KEM, adapter.FromNIKE(x25519.Scheme(rand.Reader))})
+	require.ErrorIs(t, err, ErrDuplicateScheme)
+	require.Contains(t, err.Error(), "index 0 and 2")
+
+	require.PanicsWithError(t, "combiner: KEM scheme cannot be nil: index 0", func() {
+		New("nil", []kem.Scheme{nil})
+	})
+	require.NotPanics(t, func() {
+		New("dup", []kem.Scheme{x25519KEM, x25519KEM})
+	})
+}
+
+func TestImplicitRejection(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	require.False(t, s.ImplicitRejection())
+	require.False(t, kem.ImplicitRejection(s))
+
+	s = New("MLKEM768-XWING", []kem.Scheme{mlkem768.Scheme(), xwing.Scheme()})
+	require.True(t, s.ImplicitRejection())
+
+	s = New("MLKEM768-sntrup4591761", []kem.Scheme{mlkem768.Scheme(), sntrup.Scheme()})
+	require.False(t, s.ImplicitRejection())
+
+	s = New("MLKEM768-X25519", []kem.Scheme{x25519KEM, mlkem768.Scheme()})
+	require.False(t, s.ImplicitRejection())
+}
+
+func TestEncapsulateFromSeed(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	require.Equal(t, 2*adapter.SeedSize, s.EncapsulationSeedSize())
+
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	seed := make([]byte, 32)
+	_, err = rand.Reader.Read(seed)
+	require.NoError(t, err)
+
+	ct1, ss1, err := s.EncapsulateFromSeed(pubKey, seed)
+	require.NoError(t, err)
+	ct2, ss2, err := s.EncapsulateFromSeed(pubKey, seed)
+	require.NoError(t, err)
+	require.Equal(t, ct1, ct2)
+	require.Equal(t, ss1, ss2)
+
+	ss3, err := s.Decapsulate(privKey, ct1)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss3)
+
+	// expansion must differ from a linear split of the same bytes
+	ct4, _, err := s.EncapsulateDeterministically(pubKey, append(seed, seed...))
+	require.NoError(t, err)
+	require.NotEqual(t, ct1, ct4)
+
+	_, _, err = s.EncapsulateFromSeed(pubKey, seed[:16])
+	require.ErrorIs(t, err, kem.ErrSeedSize)
+
+	s = New("MLKEM768-X25519", []kem.Scheme{x25519KEM, mlkem768.Scheme()})
+	require.Equal(t, 0, s.EncapsulationSeedSize())
+	pubKey, _, err = s.GenerateKeyPair()
+	require.NoError(t, err)
+	_, _, err = s.EncapsulateFromSeed(pubKey, seed)
+	require.Error(t, err)
+}
+
+func TestDecapsulateComponent(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+
+	ss1, err := s.DecapsulateComponent(privKey, ct, 0)
+	require.NoError(t, err)
+	ss2, err := s.DecapsulateComponent(privKey, ct, 1)
+	require.NoError(t, err)
+
+	x25519CtSize := x25519KEM.CiphertextSize()
+	ss1b, err := x25519KEM.Decapsulate(privKey.(*PrivateKey).keys[0], ct[:x25519CtSize])
+	require.NoError(t, err)
+	require.Equal(t, ss1b, ss1)
+	require.NotEqual(t, ss, ss1)
+
+	combined := util.SplitPRF([][]byte{ss1, ss2}, [][]byte{ct[:x25519CtSize], ct[x25519CtSize:]})
+	require.Equal(t, ss, combined)
+
+	_, err = s.DecapsulateComponent(privKey, ct, 2)
+	require.Error(t, err)
+	_, err = s.DecapsulateComponent(privKey, ct, -1)
+	require.Error(t, err)
+	_, err = s.DecapsulateComponent(privKey, ct[1:], 0)
+	require.ErrorIs(t, err, kem.ErrCiphertextSize)
+}
+
+func TestCiphertextOffsets(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+
+	offsets := s.CiphertextOffsets()
+	require.Equal(t, []int{0, x25519KEM.CiphertextSize(), s.CiphertextSize()}, offsets)
+
+	components := s.Components()
+	require.Len(t, components, 2)
+	components[0] = nil
+	require.NotNil(t, s.Components()[0])
+
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, _, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+	for i, c := range s.Components() {
+		ss1, err := c.Decapsulate(privKey.(*PrivateKey).keys[i], ct[offsets[i]:offsets[i+1]])
+		require.NoError(t, err)
+		ss2, err := s.DecapsulateComponent(privKey, ct, i)
+		require.NoError(t, err)
+		require.Equal(t, ss1, ss2)
+	}
+}
+
+func TestWithFixedOutput(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	for _, outLen := range []int{16, 32, 64, 100} {
+		s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM}, WithFixedOutput(outLen))
+		require.Equal(t, outLen, s.SharedKeySize())
+
+		pubKey, privKey, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		ct, ss1, err := s.Encapsulate(pubKey)
+		require.NoError(t, err)
+		require.Len(t, ss1, outLen)
+		ss2, err := s.Decapsulate(privKey, ct)
+		require.NoError(t, err)
+		require.Equal(t, ss1, ss2)
+	}
+
+	require.Panics(t, func() {
+		New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM}, WithFixedOutput(0))
+	})
+}
+
+// rejectingScheme is a KEM whose public keys never unmarshal.
+type rejectingScheme struct {
+	kem.Scheme
+}
+
+func (s *rejectingScheme) UnmarshalBinaryPublicKey([]byte) (kem.PublicKey, error) {
+	return nil, fmt.Errorf("rejecting: %w", kem.ErrPubKey)
+}
+
+func TestComponentErrorsIs(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	s := New("X25519-Rejecting", []kem.Scheme{
+		x25519KEM,
+		&rejectingScheme{adapter.FromNIKE(x448.Scheme(rand.Reader))},
+	})
+
+	_, err := s.UnmarshalBinaryPublicKey(make([]byte, s.PublicKeySize()))
+	require.ErrorIs(t, err, kem.ErrPubKey)
+	require.Contains(t, err.Error(), "x448")
+
+	_, err = s.UnmarshalBinaryPrivateKey(make([]byte, s.PrivateKeySize()-1))
+	require.ErrorIs(t, err, kem.ErrPrivKeySize)
+
+	_, err = s.Decapsulate(nil, nil)
+	require.ErrorIs(t, err, kem.ErrCiphertextSize)
+}
+
+func TestWithCanonicalOrder(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	given := []kem.Scheme{mlkem768.Scheme(), x25519KEM}
+	s1 := New("canonical", given, WithCanonicalOrder())
+	s2 := New("canonical", []kem.Scheme{x25519KEM, mlkem768.Scheme()}, WithCanonicalOrder())
+
+	// the caller's slice is left alone
+	require.Equal(t, "MLKEM768", given[0].Name())
+	require.Equal(t, "MLKEM768", s1.Components()[0].Name())
+	require.Equal(t, "x25519", s1.Components()[1].Name())
+
+	seed := make([]byte, s1.SeedSize())
+	_, err := rand.Reader.Read(seed)
+	require.NoError(t, err)
+	pubKey1, privKey1 := s1.DeriveKeyPair(seed)
+	pubKey2, _ := s2.DeriveKeyPair(seed)
+	blob1, err := pubKey1.MarshalBinary()
+	require.NoError(t, err)
+	blob2, err := pubKey2.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, blob1, blob2)
+
+	ct, ss1, err := s2.Encapsulate(pubKey2)
+	require.NoError(t, err)
+	ss2, err := s1.Decapsulate(privKey1, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss2)
+
+	require.Panics(t, func() {
+		New("nil", []kem.Scheme{x25519KEM, nil}, WithCanonicalOrder())
+	})
+}
+
+func TestSharedKeySizes(t *testing.T) {
+	s := New("X25519-X448-MLKEM768", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		adapter.FromNIKE(x448.Scheme(rand.Reader)),
+		mlkem768.Scheme(),
+	})
+	require.Equal(t, []int{32, 56, 32}, s.SharedKeySizes())
+	require.Equal(t, 32, s.SharedKeySize())
+}
+
+func TestDecapsulateTranscript(t *testing.T) {
+	s := New("X25519-X448-MLKEM768", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		adapter.FromNIKE(x448.Scheme(rand.Reader)),
+		mlkem768.Scheme(),
+	})
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss1, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+
+	ss2, transcript, err := s.DecapsulateTranscript(privKey, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss2)
+
+	// recompute the split PRF from the transcript alone
+	acc := make([]byte, blake2b.Size256)
+	for i, size := range s.SharedKeySizes() {
+		require.GreaterOrEqual(t, len(transcript), size+len(ct), i)
+		sum := blake2b.Sum256(transcript[:size+len(ct)])
+		require.Equal(t, ct, transcript[size:size+len(ct)])
+		for j := range acc {
+			acc[j] ^= sum[j]
+		}
+		transcript = transcript[size+len(ct):]
+	}
+	require.Empty(t, transcript)
+	require.Equal(t, ss1, acc)
+
+	_, _, err = s.DecapsulateTranscript(privKey, ct[1:])
+	require.ErrorIs(t, err, kem.ErrCiphertextSize)
+}
+
+func TestWithNameBound(t *testing.T) {
+	components := []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		mlkem768.Scheme(),
+	}
+	plain := New("A", components)
+	boundA := New("A", components, WithNameBound())
+	boundB := New("B", components, WithNameBound())
+
+	pubKey, privKey, err := plain.GenerateKeyPair()
+	require.NoError(t, err)
+	pubBlob, err := pubKey.MarshalBinary()
+	require.NoError(t, err)
+	privBlob, err := privKey.MarshalBinary()
+	require.NoError(t, err)
+
+	// keys are interchangeable with those of an unbound combiner
+	pubKeyA, err := boundA.UnmarshalBinaryPublicKey(pubBlob)
+	require.NoError(t, err)
+	privKeyA, err := boundA.UnmarshalBinaryPrivateKey(privBlob)
+	require.NoError(t, err)
+	privKeyB, err := boundB.UnmarshalBinaryPrivateKey(privBlob)
+	require.NoError(t, err)
+
+	ct, ss, err := boundA.Encapsulate(pubKeyA)
+	require.NoError(t, err)
+	ssA, transcript, err := boundA.DecapsulateTranscript(privKeyA, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ssA)
+
+	ssB, err := boundB.Decapsulate(privKeyB, ct)
+	require.NoError(t, err)
+	require.NotEqual(t, ss, ssB)
+	ssPlain, err := plain.Decapsulate(privKey, ct)
+	require.NoError(t, err)
+	require.NotEqual(t, ss, ssPlain)
+
+	// the transcript holds the framed name ahead of the ciphertext
+	size := boundA.SharedKeySizes()[0]
+	require.Equal(t, append([]byte{0, 1, 'A'}, ct...), transcript[size:size+3+len(ct)])
+}
+
+func TestAudit(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	require.Nil(t, New("X25519-MLKEM768", []kem.Scheme{x25519KEM, mlkem768.Scheme()}).Audit())
+
+	warnings := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM}).Audit()
+	require.Len(t, warnings, 1)
+	require.Equal(t, WarningNoPostQuantum, warnings[0].Kind)
+	require.Equal(t, -1, warnings[0].Index)
+	require.Contains(t, warnings[0].String(), "no post-quantum component")
+
+	warnings = New("X25519", []kem.Scheme{x25519KEM}).Audit()
+	require.Len(t, warnings, 2)
+	require.Equal(t, WarningSingleComponent, warnings[0].Kind)
+	require.Equal(t, WarningNoPostQuantum, warnings[1].Kind)
+
+	inner := New("X25519-MLKEM768", []kem.Scheme{x25519KEM, mlkem768.Scheme()})
+	warnings = New("nested", []kem.Scheme{sntrup.Scheme(), inner, x448KEM, adapter.FromNIKE(x25519.Scheme(rand.Reader))}).Audit()
+	require.Len(t, warnings, 1)
+	require.Equal(t, WarningDuplicateComponent, warnings[0].Kind)
+	require.Equal(t, 3, warnings[0].Index)
+	require.Contains(t, warnings[0].Message, "duplicate component x25519 at index 3, first used at index 1")
+}
+
+// constantSecretScheme is a broken KEM whose shared secrets are
+// always the same byte.
+type constantSecretScheme struct {
+	kem.Scheme
+	b byte
+}
+
+func (s *constantSecretScheme) Encapsulate(pk kem.PublicKey) ([]byte, []byte, error) {
+	ct, ss, err := s.Scheme.Encapsulate(pk)
+	return ct, bytes.Repeat([]byte{s.b}, len(ss)), err
+}
+
+func (s *constantSecretScheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+	ss, err := s.Scheme.Decapsulate(sk, ct)
+	return bytes.Repeat([]byte{s.b}, len(ss)), err
+}
+
+func TestWithSanityCheck(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM}, WithSanityCheck())
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	plain := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	ss3, err := plain.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss3)
+
+	for _, b := range []byte{0x00, 0xff} {
+		broken := []kem.Scheme{x25519KEM, &constantSecretScheme{x448KEM, b}}
+
+		s = New("X25519-Broken", broken, WithSanityCheck())
+		pk, sk, err = s.GenerateKeyPair()
+		require.NoError(t, err)
+		_, _, err = s.Encapsulate(pk)
+		require.ErrorIs(t, err, ErrWeakSharedSecret)
+		require.Contains(t, err.Error(), "index 1")
+
+		// Without the sanity check the degenerate secret goes unnoticed.
+		ct, _, err = New("X25519-Broken", broken).Encapsulate(pk)
+		require.NoError(t, err)
+		_, err = s.Decapsulate(sk, ct)
+		require.ErrorIs(t, err, ErrWeakSharedSecret)
+	}
+}
+
+func TestComponentContributes(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	for i := range s.Components() {
+		ok, err := s.ComponentContributes(i)
+		require.NoError(t, err)
+		require.True(t, ok, i)
+	}
+
+	// A constant which the sanity check wouldn't catch.
+	s = New("X25519-Broken", []kem.Scheme{x25519KEM, &constantSecretScheme{x448KEM, 0x42}})
+	ok, err := s.ComponentContributes(0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = s.ComponentContributes(1)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, err = s.ComponentContributes(2)
+	require.Error(t, err)
+}
+
+// testResolver stands in for schemes.ByName, which this package can't
+// import.
+func testResolver(name string) kem.Scheme {
+	switch strings.ToLower(name) {
+	case "x25519":
+		return adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	case "x448":
+		return adapter.FromNIKE(x448.Scheme(rand.Reader))
+	case "mlkem768":
+		return mlkem768.Scheme()
+	}
+	return nil
+}
+
+func TestSpec(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	components := []kem.Scheme{x25519KEM, x448KEM}
+
+	for _, s := range []*Scheme{
+		New("X25519-X448", components),
+		New("my hybrid & co", components, WithNameBound()),
+		New("X25519-X448", components, WithFixedOutput(64)),
+		New("X25519-X448", components, WithSanityCheck()),
+		New("X25519-X448", components, WithCCABound()),
+		New("X25519-X448", components, WithNameBound(), WithFixedOutput(64), WithSanityCheck(), WithCCABound()),
+	} {
+		spec := s.Spec()
+		s2, err := FromSpec(spec, testResolver)
+		require.NoError(t, err, spec)
+		require.Equal(t, spec, s2.Spec())
+		require.Equal(t, s.Name(), s2.Name())
+		require.True(t, kem.WireCompatible(s, s2))
+
+		pk, sk := s.DeriveKeyPair(make([]byte, s.SeedSize()))
+		blob, err := pk.MarshalBinary()
+		require.NoError(t, err)
+		pk2, err := s2.UnmarshalBinaryPublicKey(blob)
+		require.NoError(t, err)
+		ct, ss, err := s2.Encapsulate(pk2)
+		require.NoError(t, err)
+		ss2, err := s.Decapsulate(sk, ct)
+		require.NoError(t, err)
+		require.Equal(t, ss, ss2, spec)
+	}
+	require.Equal(t, "hpqc-combiner-v1?component=x25519&component=x448&name=X25519-X448&namebound=true",
+		New("X25519-X448", components, WithNameBound()).Spec())
+
+	for _, spec := range []string{
+		"X25519-X448",
+		"hpqc-combiner-v1?component=x25519&component=x448",
+		"hpqc-combiner-v1?component=x25519&name=X25519",
+		"hpqc-combiner-v1?component=x25519&component=x448&name=a&color=blue",
+		"hpqc-combiner-v1?component=x25519&component=x448&name=a&namebound=yes",
+		"hpqc-combiner-v1?component=x25519&component=x448&name=a&outlen=-1",
+		"hpqc-combiner-v1?component=x25519&component=x448&name=a&name=b",
+		"hpqc-combiner-v1?component=x25519&component=x448&name=a&%zz",
+	} {
+		_, err := FromSpec(spec, testResolver)
+		require.ErrorIs(t, err, ErrInvalidSpec, spec)
+	}
+	_, err := FromSpec("hpqc-combiner-v1?component=x25519&component=nope&name=a", testResolver)
+	require.ErrorIs(t, err, ErrUnknownComponent)
+	_, err = FromSpec(New("X25519-X448", components).Spec(), nil)
+	require.ErrorIs(t, err, ErrNilResolver)
+}
+
+func TestSpecLabel(t *testing.T) {
+	labeled := adapter.FromNIKEWithLabel(x25519.Scheme(rand.Reader), []byte("hpqc test"))
+	s := New("X25519-X448", []kem.Scheme{labeled, adapter.FromNIKE(x448.Scheme(rand.Reader))})
+	spec := s.Spec()
+	require.Contains(t, spec, "label="+fmt.Sprintf("%x", "hpqc test"))
+
+	s2, err := FromSpec(spec, testResolver)
+	require.NoError(t, err)
+	require.Equal(t, spec, s2.Spec())
+
+	// the rebuilt combiner derives the same shared secrets as the
+	// original, which it wouldn't without the label
+	unlabeled := New("X25519-X448", []kem.Scheme{adapter.FromNIKE(x25519.Scheme(rand.Reader)), adapter.FromNIKE(x448.Scheme(rand.Reader))})
+	pk, sk := s.DeriveKeyPair(make([]byte, s.SeedSize()))
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	ss2, err := s2.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+	ss3, err := unlabeled.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.NotEqual(t, ss, ss3)
+
+	_, err = FromSpecWithComponents(spec, []kem.Scheme{labeled, adapter.FromNIKE(x448.Scheme(rand.Reader))})
+	require.NoError(t, err)
+	_, err = FromSpecWithComponents(spec, unlabeled.schemes)
+	require.ErrorIs(t, err, ErrInvalidSpec)
+	_, err = FromSpec("hpqc-combiner-v1?component=x25519&component=x448&name=a&label=00", testResolver)
+	require.ErrorIs(t, err, ErrInvalidSpec)
+	_, err = FromSpec("hpqc-combiner-v1?component=x25519&component=x448&name=a&label=&label=zz", testResolver)
+	require.ErrorIs(t, err, ErrInvalidSpec)
+}
+
+func TestWithCCABound(t *testing.T) {
+	components := []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		mlkem768.Scheme(),
+	}
+	plain := New("MLKEM768-X25519", components)
+	bound := New("MLKEM768-X25519", components, WithCCABound())
+	require.True(t, kem.WireCompatible(plain, bound))
+
+	pubKey, privKey, err := bound.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := bound.Encapsulate(pubKey)
+	require.NoError(t, err)
+	ss2, err := bound.Decapsulate(privKey, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	// The shared key is the split PRF output hashed with the ciphertext.
+	secrets, ciphertexts, err := bound.decapsulate(privKey, ct)
+	require.NoError(t, err)
+	h, err := blake2b.New256(nil)
+	require.NoError(t, err)
+	h.Write([]byte("hpqc combiner cca bound"))
+	h.Write(CombineOnly(secrets, ciphertexts))
+	h.Write(ct)
+	require.Equal(t, h.Sum(nil), ss)
+
+	// Keys are interchangeable with those of New, shared secrets aren't.
+	privBlob, err := privKey.MarshalBinary()
+	require.NoError(t, err)
+	plainPrivKey, err := plain.UnmarshalBinaryPrivateKey(privBlob)
+	require.NoError(t, err)
+	ssPlain, err := plain.Decapsulate(plainPrivKey, ct)
+	require.NoError(t, err)
+	require.NotEqual(t, ss, ssPlain)
+}
+
+func TestCombineOnly(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	s := New("MLKEM768-X25519", []kem.Scheme{mlkem768.Scheme(), x25519KEM})
+
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+
+	offsets := s.CiphertextOffsets()
+	secrets := make([][]byte, 2)
+	ciphertexts := make([][]byte, 2)
+	for i, component := range s.Components() {
+		ciphertexts[i] = ct[offsets[i] : offsets[i]+component.CiphertextSize()]
+		secrets[i], err = s.DecapsulateComponent(sk, ct, i)
+		require.NoError(t, err)
+	}
+	require.Equal(t, ss, CombineOnly(secrets, ciphertexts))
+	require.Panics(t, func() { CombineOnly(secrets, ciphertexts[:1]) })
+}
+
+// The benchmarks below split the cost of decapsulation between the
+// component KEMs and the split PRF which combines their outputs.
+
+func benchmarkScheme(b *testing.B) (*Scheme, kem.PrivateKey, []byte) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	s := New("MLKEM768-X25519", []kem.Scheme{mlkem768.Scheme(), x25519KEM})
+	pk, sk, err := s.GenerateKeyPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ct, _, err := s.Encapsulate(pk)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return s, sk, ct
+}
+
+func BenchmarkDecapsulate(b *testing.B) {
+	s, sk, ct := benchmarkScheme(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Decapsulate(sk, ct); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecapsulateComponents(b *testing.B) {
+	s, sk, ct := benchmarkScheme(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range s.Components() {
+			if _, err := s.DecapsulateComponent(sk, ct, j); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkCombineOnly(b *testing.B) {
+	s, sk, ct := benchmarkScheme(b)
+	offsets := s.CiphertextOffsets()
+	secrets := make([][]byte, 2)
+	ciphertexts := make([][]byte, 2)
+	for i, component := range s.Components() {
+		ciphertexts[i] = ct[offsets[i] : offsets[i]+component.CiphertextSize()]
+		ss, err := s.DecapsulateComponent(sk, ct, i)
+		if err != nil {
+			b.Fatal(err)
+		}
+		secrets[i] = ss
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CombineOnly(secrets, ciphertexts)
+	}
+}
+
+func TestWithKDF(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	components := []kem.Scheme{x25519KEM, x448KEM}
+
+	// HMAC-SHA512 keyed by the component shared secret.
+	hmacSHA512 := func(inputs ...[]byte) []byte {
+		mac := hmac.New(sha512.New, inputs[0])
+		for _, input := range inputs[1:] {
+			mac.Write(input)
+		}
+		return mac.Sum(nil)
+	}
+	s := New("X25519-X448", components, WithKDF(hmacSHA512))
+	require.Equal(t, sha512.Size, s.SharedKeySize())
+
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	require.Len(t, ss, sha512.Size)
+	ss2, err := s.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	ct1, ct2 := ct[:x25519KEM.CiphertextSize()], ct[x25519KEM.CiphertextSize():]
+	ss1, err := s.DecapsulateComponent(sk, ct, 0)
+	require.NoError(t, err)
+	ss2, err = s.DecapsulateComponent(sk, ct, 1)
+	require.NoError(t, err)
+	want := hmacSHA512(ss1, ct1, ct2)
+	xor.Bytes(want, want, hmacSHA512(ss2, ct1, ct2))
+	require.Equal(t, want, ss)
+
+	// the transcript holds the KDF inputs, concatenated
+	ss3, transcript, err := s.DecapsulateTranscript(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss3)
+	n := len(ss1) + len(ct)
+	require.Equal(t, [][]byte{append(ss1, ct...), append(ss2, ct...)},
+		[][]byte{transcript[:n], transcript[n:]})
+
+	require.Contains(t, s.Spec(), "kdf=custom")
+	_, err = FromSpec(s.Spec(), testResolver)
+	require.ErrorIs(t, err, ErrInvalidSpec)
+
+	require.Panics(t, func() { New("X25519-X448", components, WithKDF(nil)) })
+	require.Panics(t, func() {
+		New("X25519-X448", components, WithKDF(func(...[]byte) []byte { return nil }))
+	})
+
+	// the name is bound by prefixing it to the first ciphertext
+	bound := New("X25519-X448", components, WithKDF(hmacSHA512), WithNameBound())
+	ss4, err := bound.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	prefixed := append([]byte{0, 11}, "X25519-X448"...)
+	want = hmacSHA512(ss1, append(prefixed, ct1...), ct2)
+	xor.Bytes(want, want, hmacSHA512(ss2, append(prefixed, ct1...), ct2))
+	require.Equal(t, want, ss4)
+
+	_, err = NewOrErr("X25519-X448", components, WithKDF(hmacSHA512), WithFixedOutput(32))
+	require.ErrorIs(t, err, ErrIncompatibleOptions)
+	_, err = NewOrErr("X25519-X448", components, WithCCABound(), WithKDF(hmacSHA512))
+	require.ErrorIs(t, err, ErrIncompatibleOptions)
+}
+
+// cancellingScheme cancels a context once it has generated a key pair,
+// standing in for a component whose key generation outlasts a deadline.
+type cancellingScheme struct {
+	kem.Scheme
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (s *cancellingScheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	s.calls++
+	s.cancel()
+	return s.Scheme.GenerateKeyPair()
+}
+
+func TestGenerateKeyPairContext(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	s := New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	pk, sk, err := kem.GenerateKeyPairContext(context.Background(), s)
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = s.GenerateKeyPairContext(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	first := &cancellingScheme{Scheme: x25519KEM, cancel: cancel}
+	last := &cancellingScheme{Scheme: x448KEM, cancel: cancel}
+	s = New("Cancelling", []kem.Scheme{first, last})
+	pk, sk, err = s.GenerateKeyPairContext(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, pk)
+	require.Nil(t, sk)
+	require.Equal(t, 1, first.calls)
+	require.Equal(t, 0, last.calls)
+}
+
+// leakyScheme panics with its private key in the message on
+// Decapsulate.
+type leakyScheme struct {
+	kem.Scheme
+}
+
+func (s leakyScheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+	blob, _ := sk.MarshalBinary()
+	panic(fmt.Sprintf("leaky: %x", blob))
+}
+
+func TestDecapsulateRedactsPanics(t *testing.T) {
+	s := New("X25519-leaky", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		leakyScheme{adapter.FromNIKE(x448.Scheme(rand.Reader))},
+	})
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, _, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+	blob, err := privKey.(*PrivateKey).keys[1].MarshalBinary()
+	require.NoError(t, err)
+
+	defer func() {
+		p, ok := recover().(*hpqcutil.RedactedPanic)
+		require.True(t, ok)
+		require.Equal(t, "leaky: [redacted]", p.Msg)
+		require.NotContains(t, p.Msg, fmt.Sprintf("%x", blob))
+	}()
+	s.Decapsulate(privKey, ct)
+}
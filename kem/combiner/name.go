@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package combiner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+var (
+	// ErrUnknownComponent indicates that part of a combiner name could
+	// not be resolved to a KEM scheme.
+	ErrUnknownComponent = errors.New("combiner: unknown component scheme")
+
+	// ErrNilResolver indicates that no Resolver was given.
+	ErrNilResolver = errors.New("combiner: resolver cannot be nil")
+)
+
+// Resolver looks up a KEM scheme by name, returning nil if the name is
+// unknown. The ByName function of the kem/schemes package is the usual
+// Resolver; this package can't use it directly without an import cycle,
+// and doesn't look up names itself so that it doesn't depend on every
+// scheme, including those needing cgo.
+type Resolver func(name string) kem.Scheme
+
+// ParseName splits a combiner name such as "X25519-MLKEM768-CTIDH512"
+// on "-" and resolves each part to a KEM scheme with resolve, which
+// must not be nil. Since some scheme names themselves contain "-", the
+// shortest run of parts which names a known scheme is used for each
+// component. Names are matched case insensitively, so a scheme named
+// twice is reported as a duplicate even if the two spellings differ in
+// case.
+func ParseName(name string, resolve Resolver) ([]kem.Scheme, error) {
+	if resolve == nil {
+		return nil, ErrNilResolver
+	}
+	tokens := strings.Split(name, "-")
+	components := []kem.Scheme{}
+	seen := make(map[string]string)
+	for i := 0; i < len(tokens); {
+		var s kem.Scheme
+		j := i + 1
+		for ; j <= len(tokens); j++ {
+			s = resolveComponent(strings.Join(tokens[i:j], "-"), resolve)
+			if s != nil {
+				break
+			}
+		}
+		if s == nil {
+			return nil, fmt.Errorf("%w: %q in %q", ErrUnknownComponent, tokens[i], name)
+		}
+		part := strings.Join(tokens[i:j], "-")
+		key := strings.ToLower(s.Name())
+		if prev, ok := seen[key]; ok {
+			if prev != part {
+				return nil, fmt.Errorf("%w: %q and %q in %q both name %s", ErrDuplicateScheme, prev, part, name, s.Name())
+			}
+			return nil, fmt.Errorf("%w: %q appears more than once in %q", ErrDuplicateScheme, part, name)
+		}
+		seen[key] = part
+		components = append(components, s)
+		i = j
+	}
+	if len(components) < 2 {
+		return nil, fmt.Errorf("combiner: %q names fewer than two component schemes", name)
+	}
+	return components, nil
+}
+
+// FromName builds a combiner named name from the components given by
+// ParseName.
+func FromName(name string, resolve Resolver) (*Scheme, error) {
+	components, err := ParseName(name, resolve)
+	if err != nil {
+		return nil, err
+	}
+	return NewOrErr(name, components)
+}
+
+func resolveComponent(name string, resolve Resolver) kem.Scheme {
+	if name == "" {
+		return nil
+	}
+	return resolve(name)
+}
@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package combiner
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+)
+
+// specPrefix starts every spec string and versions its format.
+const specPrefix = "hpqc-combiner-v1?"
+
+// ErrInvalidSpec indicates that a spec string could not be parsed.
+var ErrInvalidSpec = errors.New("combiner: invalid spec")
+
+// Spec returns a canonical string which fully describes the combiner:
+// its name, its component names in order and the options it was created
+// with. FromSpec turns it back into an equivalent combiner, for instance
+// to pin an exact hybrid in a config file. The string looks like
+//
+//	hpqc-combiner-v1?component=MLKEM768&component=X25519&name=MLKEM768-X25519&namebound=true
+//
+// Components are only recorded by name, so FromSpec can only rebuild
+// combiners whose components can be found as by ParseName. The labels of
+// NIKE adapters created with adapter.FromNIKEWithLabel are recorded too,
+// hex encoded as one "label" per component, empty for those without one,
+// and FromSpec puts them back. A custom KDF given with WithKDF is only
+// recorded as "kdf=custom", and FromSpec refuses to rebuild such
+// combiners.
+func (sch *Scheme) Spec() string {
+	v := url.Values{}
+	v.Set("name", sch.name)
+	labeled := false
+	for _, s := range sch.schemes {
+		v.Add("component", s.Name())
+		labeled = labeled || len(componentLabel(s)) != 0
+	}
+	if labeled {
+		for _, s := range sch.schemes {
+			v.Add("label", hex.EncodeToString(componentLabel(s)))
+		}
+	}
+	if sch.outLen != 0 {
+		v.Set("outlen", strconv.Itoa(sch.outLen))
+	}
+	if sch.nameBound {
+		v.Set("namebound", "true")
+	}
+	if sch.sanityCheck {
+		v.Set("sanitycheck", "true")
+	}
+	if sch.ccaBound {
+		v.Set("ccabound", "true")
+	}
+	if sch.kdf != nil {
+		v.Set("kdf", "custom")
+	}
+	return specPrefix + v.Encode()
+}
+
+// FromSpec builds the combiner described by a string returned by Spec,
+// looking up its components with resolve, which must not be nil. It
+// returns an error wrapping ErrInvalidSpec if the string is malformed
+// or has an unknown option, or ErrUnknownComponent if a component name
+// can't be resolved.
+func FromSpec(spec string, resolve Resolver) (*Scheme, error) {
+	if resolve == nil {
+		return nil, ErrNilResolver
+	}
+	v, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := specLabels(v)
+	if err != nil {
+		return nil, err
+	}
+	components := v["component"]
+	schemes := make([]kem.Scheme, len(components))
+	for i, component := range components {
+		schemes[i] = resolveComponent(component, resolve)
+		if schemes[i] == nil {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownComponent, component)
+		}
+		if len(labels[i]) != 0 {
+			a, ok := schemes[i].(*adapter.Scheme)
+			if !ok {
+				return nil, fmt.Errorf("%w: component %q can't have a label", ErrInvalidSpec, component)
+			}
+			schemes[i] = adapter.FromNIKEWithLabel(a.NIKE(), labels[i])
+		}
+	}
+	return fromSpec(v, schemes)
+}
+
+// FromSpecWithComponents builds the combiner described by a string
+// returned by Spec like FromSpec, but with the given component schemes
+// instead of those found by name, for combiners whose components can't
+// be resolved by ParseName, such as other combiners. The components
+// must have the names recorded in the spec, in the same order.
+func FromSpecWithComponents(spec string, components []kem.Scheme) (*Scheme, error) {
+	v, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := specLabels(v)
+	if err != nil {
+		return nil, err
+	}
+	names := v["component"]
+	if len(components) != len(names) {
+		return nil, fmt.Errorf("%w: spec has %d components, got %d", ErrInvalidSpec, len(names), len(components))
+	}
+	for i, s := range components {
+		if s == nil {
+			return nil, ErrNilScheme
+		}
+		if !strings.EqualFold(s.Name(), names[i]) {
+			return nil, fmt.Errorf("%w: component %d is %q, not %q", ErrInvalidSpec, i, s.Name(), names[i])
+		}
+		if !bytes.Equal(componentLabel(s), labels[i]) {
+			return nil, fmt.Errorf("%w: component %d has a different label", ErrInvalidSpec, i)
+		}
+	}
+	return fromSpec(v, components)
+}
+
+// parseSpec parses and checks the options of a spec string.
+func parseSpec(spec string) (url.Values, error) {
+	if !strings.HasPrefix(spec, specPrefix) {
+		return nil, fmt.Errorf("%w: missing %q prefix", ErrInvalidSpec, specPrefix)
+	}
+	v, err := url.ParseQuery(spec[len(specPrefix):])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSpec, err)
+	}
+	for key, values := range v {
+		switch key {
+		case "component", "label":
+		case "kdf":
+			return nil, fmt.Errorf("%w: a custom KDF can't be rebuilt from a spec", ErrInvalidSpec)
+		case "name", "outlen", "namebound", "sanitycheck", "ccabound":
+			if len(values) != 1 {
+				return nil, fmt.Errorf("%w: option %q given %d times", ErrInvalidSpec, key, len(values))
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown option %q", ErrInvalidSpec, key)
+		}
+	}
+	if v.Get("name") == "" {
+		return nil, fmt.Errorf("%w: missing name", ErrInvalidSpec)
+	}
+	if len(v["component"]) < 2 {
+		return nil, fmt.Errorf("%w: fewer than two components", ErrInvalidSpec)
+	}
+	return v, nil
+}
+
+// fromSpec builds the combiner of the parsed spec v out of schemes.
+func fromSpec(v url.Values, schemes []kem.Scheme) (*Scheme, error) {
+	var err error
+	name := v.Get("name")
+	outLen := 0
+	if s, ok := v["outlen"]; ok {
+		outLen, err = strconv.Atoi(s[0])
+		if err != nil || outLen <= 0 || int64(outLen) >= 1<<32-1 {
+			return nil, fmt.Errorf("%w: invalid outlen %q", ErrInvalidSpec, s[0])
+		}
+	}
+	nameBound, err := specFlag(v, "namebound")
+	if err != nil {
+		return nil, err
+	}
+	if nameBound && len(name) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: name too long", ErrInvalidSpec)
+	}
+	sanityCheck, err := specFlag(v, "sanitycheck")
+	if err != nil {
+		return nil, err
+	}
+	ccaBound, err := specFlag(v, "ccabound")
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+	if outLen != 0 {
+		opts = append(opts, WithFixedOutput(outLen))
+	}
+	if nameBound {
+		opts = append(opts, WithNameBound())
+	}
+	if sanityCheck {
+		opts = append(opts, WithSanityCheck())
+	}
+	if ccaBound {
+		opts = append(opts, WithCCABound())
+	}
+	return NewOrErr(name, schemes, opts...)
+}
+
+// componentLabel returns the label of a NIKE adapter component, or nil.
+func componentLabel(s kem.Scheme) []byte {
+	if a, ok := s.(*adapter.Scheme); ok {
+		return a.Label()
+	}
+	return nil
+}
+
+// specLabels returns the decoded label of every component of the parsed
+// spec v, all nil if it has none.
+func specLabels(v url.Values) ([][]byte, error) {
+	components := v["component"]
+	labels := make([][]byte, len(components))
+	encoded, ok := v["label"]
+	if !ok {
+		return labels, nil
+	}
+	if len(encoded) != len(components) {
+		return nil, fmt.Errorf("%w: %d labels for %d components", ErrInvalidSpec, len(encoded), len(components))
+	}
+	for i, e := range encoded {
+		label, err := hex.DecodeString(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid label %q", ErrInvalidSpec, e)
+		}
+		labels[i] = label
+	}
+	return labels, nil
+}
+
+func specFlag(v url.Values, key string) (bool, error) {
+	s, ok := v[key]
+	if !ok {
+		return false, nil
+	}
+	if s[0] != "true" {
+		return false, fmt.Errorf("%w: invalid %s %q", ErrInvalidSpec, key, s[0])
+	}
+	return true, nil
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import "strings"
+
+// Composite is implemented by KEMs built out of other KEMs, such as the
+// hybrid and combiner KEMs.
+type Composite interface {
+	// Components returns the component schemes in the order in which
+	// their keys and ciphertexts are concatenated.
+	Components() []Scheme
+}
+
+// WireCompatible returns true if keys and ciphertexts produced by one
+// scheme can be used with the other, for example when replacing a
+// scheme with a differently named but equivalent definition. The schemes
+// must have identical sizes and the same structure: composite schemes
+// must have pairwise compatible components in the same order, and other
+// schemes must have the same name, compared case insensitively.
+//
+// Note that this doesn't detect composite schemes which combine their
+// component shared secrets differently.
+func WireCompatible(a, b Scheme) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a.PublicKeySize() != b.PublicKeySize() ||
+		a.PrivateKeySize() != b.PrivateKeySize() ||
+		a.CiphertextSize() != b.CiphertextSize() ||
+		a.SharedKeySize() != b.SharedKeySize() ||
+		a.SeedSize() != b.SeedSize() {
+		return false
+	}
+
+	compA, okA := a.(Composite)
+	compB, okB := b.(Composite)
+	if okA != okB {
+		return false
+	}
+	if !okA {
+		return strings.EqualFold(a.Name(), b.Name())
+	}
+
+	componentsA := compA.Components()
+	componentsB := compB.Components()
+	if len(componentsA) != len(componentsB) {
+		return false
+	}
+	for i := range componentsA {
+		if !WireCompatible(componentsA[i], componentsB[i]) {
+			return false
+		}
+	}
+	return true
+}
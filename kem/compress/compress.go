@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package compress provides a lossless compressed encoding for KEM
+// public and private keys, intended for schemes with very large keys
+// such as Classic McEliece.
+//
+// The encoding is a single format byte followed by either the DEFLATE
+// compressed or the raw binary key. The raw form is used whenever
+// compression would not make the key smaller, so keys of schemes which
+// don't compress are only ever one byte larger than their binary form.
+// Private keys which can be regenerated from a seed, such as those of
+// Classic McEliece, are encoded as the seed instead.
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/util"
+)
+
+const (
+	formatRaw     byte = 0
+	formatDeflate byte = 1
+	formatSeed    byte = 2
+)
+
+// seedMarshaler is implemented by private keys which can return the
+// seed they are derived from, such as circl's Classic McEliece keys.
+type seedMarshaler interface {
+	MarshalCompressedBinary() []byte
+}
+
+// ErrInvalidEncoding indicates that a compressed key could not be
+// decoded.
+var ErrInvalidEncoding = errors.New("compress: invalid compressed key")
+
+// MarshalCompressed returns the compressed encoding of the public key.
+func MarshalCompressed(pk kem.PublicKey) ([]byte, error) {
+	blob, err := pk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return compress(blob)
+}
+
+// UnmarshalCompressed decodes a public key of the given scheme which was
+// encoded with MarshalCompressed. The decompressed size is bounded by the
+// scheme's public key size.
+func UnmarshalCompressed(sch kem.Scheme, b []byte) (kem.PublicKey, error) {
+	blob, err := decompress(b, sch.PublicKeySize())
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) != sch.PublicKeySize() {
+		return nil, kem.PubKeySizeError(sch.PublicKeySize(), len(blob))
+	}
+	return sch.UnmarshalBinaryPublicKey(blob)
+}
+
+// MarshalCompressedPrivate returns the compressed encoding of the
+// private key, for storing many large private keys such as those of
+// Classic McEliece on disk. Keys which can return the seed they were
+// derived from, as circl's Classic McEliece keys do, are encoded as
+// that seed, once the key pair derived from it has been checked to be
+// the same as sk. Other keys get the same encoding as public keys.
+// Note that checking, and later decoding, a seed runs the scheme's key
+// derivation, which for Classic McEliece is far slower than DEFLATE.
+func MarshalCompressedPrivate(sk kem.PrivateKey) ([]byte, error) {
+	if out, ok := marshalSeed(sk); ok {
+		return out, nil
+	}
+	blob, err := sk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	defer util.ExplicitBzero(blob)
+	return compress(blob)
+}
+
+// UnmarshalCompressedPrivate decodes a private key of the given scheme
+// which was encoded with MarshalCompressedPrivate. The decompressed size
+// is bounded by the scheme's private key size. Callers which stored the
+// public key alongside should check that it Equals the Public key of
+// the result.
+func UnmarshalCompressedPrivate(sch kem.Scheme, b []byte) (kem.PrivateKey, error) {
+	if len(b) > 0 && b[0] == formatSeed {
+		seed := b[1:]
+		if len(seed) != sch.SeedSize() {
+			return nil, fmt.Errorf("%w: seed of %d bytes, expected %d", ErrInvalidEncoding, len(seed), sch.SeedSize())
+		}
+		_, sk, err := kem.DeriveKeyPair(sch, seed)
+		if err != nil {
+			return nil, err
+		}
+		return sk, nil
+	}
+	blob, err := decompress(b, sch.PrivateKeySize())
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) != sch.PrivateKeySize() {
+		return nil, kem.PrivKeySizeError(sch.PrivateKeySize(), len(blob))
+	}
+	return sch.UnmarshalBinaryPrivateKey(blob)
+}
+
+// marshalSeed returns the seed encoding of sk, provided sk can return
+// its seed and the key pair derived from that seed has the same private
+// and public keys as sk.
+func marshalSeed(sk kem.PrivateKey) ([]byte, bool) {
+	m, ok := sk.(seedMarshaler)
+	if !ok {
+		return nil, false
+	}
+	sch := sk.Scheme()
+	seed := m.MarshalCompressedBinary()
+	defer util.ExplicitBzero(seed)
+	if len(seed) != sch.SeedSize() {
+		return nil, false
+	}
+	pk, derived, err := kem.DeriveKeyPair(sch, seed)
+	if err != nil || !derived.Equal(sk) || !pk.Equal(sk.Public()) {
+		return nil, false
+	}
+	out := make([]byte, 1+len(seed))
+	out[0] = formatSeed
+	copy(out[1:], seed)
+	return out, true
+}
+
+func compress(blob []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(formatDeflate)
+	w, err := flate.NewWriter(buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(blob); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	if buf.Len() >= len(blob)+1 {
+		out := make([]byte, len(blob)+1)
+		out[0] = formatRaw
+		copy(out[1:], blob)
+		return out, nil
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(b []byte, size int) ([]byte, error) {
+	if len(b) < 1 {
+		return nil, ErrInvalidEncoding
+	}
+	switch b[0] {
+	case formatRaw:
+		return b[1:], nil
+	case formatDeflate:
+		r := flate.NewReader(bytes.NewReader(b[1:]))
+		defer r.Close()
+		// read one byte more than expected to detect oversized keys
+		// without inflating an unbounded amount of data
+		blob, err := io.ReadAll(io.LimitReader(r, int64(size)+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEncoding, err)
+		}
+		return blob, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrInvalidEncoding, b[0])
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package compress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/circl/kem/mceliece/mceliece348864"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece8192128f"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	for _, s := range []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		mceliece8192128f.Scheme(),
+	} {
+		pubKey, _, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+
+		blob, err := MarshalCompressed(pubKey)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(blob), s.PublicKeySize()+1)
+
+		pubKey2, err := UnmarshalCompressed(s, blob)
+		require.NoError(t, err)
+		require.True(t, pubKey.Equal(pubKey2), s.Name())
+	}
+}
+
+func TestCompressPrivateRoundTrip(t *testing.T) {
+	for _, s := range []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		mceliece8192128f.Scheme(),
+	} {
+		pubKey, privKey, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+
+		blob, err := MarshalCompressedPrivate(privKey)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(blob), s.PrivateKeySize()+1)
+
+		privKey2, err := UnmarshalCompressedPrivate(s, blob)
+		require.NoError(t, err)
+		require.True(t, privKey.Equal(privKey2), s.Name())
+		require.True(t, pubKey.Equal(privKey2.Public()), s.Name())
+
+		_, err = UnmarshalCompressedPrivate(s, blob[:len(blob)/2])
+		require.Error(t, err)
+	}
+}
+
+func TestCompressPrivateSeed(t *testing.T) {
+	s := mceliece348864.Scheme()
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	blob, err := MarshalCompressedPrivate(privKey)
+	require.NoError(t, err)
+	require.Equal(t, formatSeed, blob[0])
+	require.Len(t, blob, 1+s.SeedSize())
+	privKey2, err := UnmarshalCompressedPrivate(s, blob)
+	require.NoError(t, err)
+	require.True(t, privKey.Equal(privKey2))
+	require.True(t, pubKey.Equal(privKey2.Public()))
+
+	_, err = UnmarshalCompressedPrivate(s, blob[:len(blob)-1])
+	require.ErrorIs(t, err, ErrInvalidEncoding)
+
+	// a key which its seed doesn't regenerate falls back to DEFLATE
+	raw, err := privKey.MarshalBinary()
+	require.NoError(t, err)
+	raw[len(raw)-1] ^= 1
+	privKey3, err := s.UnmarshalBinaryPrivateKey(raw)
+	require.NoError(t, err)
+	blob, err = MarshalCompressedPrivate(privKey3)
+	require.NoError(t, err)
+	require.NotEqual(t, formatSeed, blob[0])
+	privKey4, err := UnmarshalCompressedPrivate(s, blob)
+	require.NoError(t, err)
+	require.True(t, privKey3.Equal(privKey4))
+}
+
+func TestUnmarshalCompressedRejectsGarbage(t *testing.T) {
+	s := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+
+	_, err := UnmarshalCompressed(s, nil)
+	require.ErrorIs(t, err, ErrInvalidEncoding)
+
+	_, err = UnmarshalCompressed(s, []byte{7, 1, 2, 3})
+	require.ErrorIs(t, err, ErrInvalidEncoding)
+
+	_, err = UnmarshalCompressed(s, []byte{formatDeflate, 1, 2, 3})
+	require.Error(t, err)
+}
+
+func BenchmarkMarshalCompressedMcEliece8192128f(b *testing.B) {
+	s := mceliece8192128f.Scheme()
+	pubKey, _, err := s.GenerateKeyPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+	var blob []byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		blob, err = MarshalCompressed(pubKey)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(s.PublicKeySize()), "raw-bytes")
+	b.ReportMetric(float64(len(blob)), "compressed-bytes")
+}
+
+func BenchmarkMarshalCompressedPrivateMcEliece8192128f(b *testing.B) {
+	s := mceliece8192128f.Scheme()
+	_, privKey, err := s.GenerateKeyPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+	var blob []byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		blob, err = MarshalCompressedPrivate(privKey)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(s.PrivateKeySize()), "raw-bytes")
+	b.ReportMetric(float64(len(blob)), "compressed-bytes")
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const connectionIDLabel = "hpqc kem connection id v1"
+
+// ConnectionID returns an identifier for the connection between the
+// peers with the public keys a and b, for logging and correlating the
+// two ends of a session. It doesn't depend on which peer is a and which
+// is b, so both sides derive the same ID. The canonical order puts the
+// binary encoding of the two keys in ascending lexicographic order, as
+// compared by bytes.Compare, and the ID is the 16 byte BLAKE2b hash of
+//
+//	label || uint32(len(lo)) || lo || uint32(len(hi)) || hi
+//
+// where lo and hi are the lesser and greater encoding and the lengths
+// are big endian. The ID is computed from public values only, so it
+// isn't secret.
+func ConnectionID(a, b PublicKey) ([16]byte, error) {
+	var id [16]byte
+	lo, err := a.MarshalBinary()
+	if err != nil {
+		return id, err
+	}
+	hi, err := b.MarshalBinary()
+	if err != nil {
+		return id, err
+	}
+	if bytes.Compare(lo, hi) > 0 {
+		lo, hi = hi, lo
+	}
+	h, err := blake2b.New(len(id), nil)
+	if err != nil {
+		panic(err)
+	}
+	var l [4]byte
+	h.Write([]byte(connectionIDLabel))
+	binary.BigEndian.PutUint32(l[:], uint32(len(lo)))
+	h.Write(l[:])
+	h.Write(lo)
+	binary.BigEndian.PutUint32(l[:], uint32(len(hi)))
+	h.Write(l[:])
+	h.Write(hi)
+	copy(id[:], h.Sum(nil))
+	return id, nil
+}
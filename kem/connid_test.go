@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestConnectionID(t *testing.T) {
+	s := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	alice, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	bob, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	carol, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	id, err := kem.ConnectionID(alice, bob)
+	require.NoError(t, err)
+	id2, err := kem.ConnectionID(bob, alice)
+	require.NoError(t, err)
+	require.Equal(t, id, id2)
+	require.NotEqual(t, [16]byte{}, id)
+
+	id3, err := kem.ConnectionID(alice, carol)
+	require.NoError(t, err)
+	require.NotEqual(t, id, id3)
+
+	id4, err := kem.ConnectionID(alice, alice)
+	require.NoError(t, err)
+	require.NotEqual(t, id, id4)
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import "context"
+
+// ContextKeyGenerator is implemented by schemes whose key generation
+// can be cancelled, such as composite schemes with slow components like
+// Classic McEliece or CTIDH.
+type ContextKeyGenerator interface {
+	// GenerateKeyPairContext is like GenerateKeyPair, but gives up and
+	// returns ctx.Err() once ctx is done.
+	GenerateKeyPairContext(ctx context.Context) (PublicKey, PrivateKey, error)
+}
+
+// GenerateKeyPairContext generates a key pair with the given scheme,
+// returning ctx.Err() if ctx is done first. Schemes which implement
+// ContextKeyGenerator are asked to stop as soon as they can; for any
+// other scheme ctx is only checked before key generation starts, since
+// it can't be interrupted once under way.
+func GenerateKeyPairContext(ctx context.Context, s Scheme) (PublicKey, PrivateKey, error) {
+	if g, ok := s.(ContextKeyGenerator); ok {
+		return g.GenerateKeyPairContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	return s.GenerateKeyPair()
+}
+
+// ResetPrivateKeys resets those of the given private keys which
+// implement Resetter and skips nil keys, for instance to discard the
+// component keys generated before a composite key generation was
+// cancelled.
+func ResetPrivateKeys(keys ...PrivateKey) {
+	for _, sk := range keys {
+		if r, ok := sk.(Resetter); ok {
+			r.Reset()
+		}
+	}
+}
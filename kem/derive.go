@@ -0,0 +1,33 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"errors"
+
+	"github.com/katzenpost/hpqc/util"
+)
+
+// ErrNotDeriveable is returned by DeriveKeyPair for schemes which don't
+// report CapDeterministicKeygen.
+var ErrNotDeriveable = errors.New("kem: scheme does not support deterministic key derivation")
+
+// DeriveKeyPair derives a key pair from seed with the given scheme like
+// Scheme.DeriveKeyPair, but returns an error instead of panicking when
+// the scheme can't derive keys deterministically, as reported by
+// Capabilities, or when the seed isn't SeedSize bytes long. In the
+// latter case the error is a SizeError wrapping ErrSeedSize. Should the
+// scheme panic nonetheless, the panic is redacted by util.SafePanic.
+func DeriveKeyPair(s Scheme, seed []byte) (pk PublicKey, sk PrivateKey, err error) {
+	if !Capabilities(s).Has(CapDeterministicKeygen) {
+		return nil, nil, ErrNotDeriveable
+	}
+	if len(seed) != s.SeedSize() {
+		return nil, nil, &SizeError{Err: ErrSeedSize, Expected: s.SeedSize(), Got: len(seed)}
+	}
+	util.SafePanic(func() {
+		pk, sk = s.DeriveKeyPair(seed)
+	})
+	return pk, sk, nil
+}
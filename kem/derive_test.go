@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+// randomOnlyScheme stands in for a scheme which can't derive keys
+// deterministically.
+type randomOnlyScheme struct {
+	kem.Scheme
+}
+
+func (s *randomOnlyScheme) Capabilities() kem.Caps {
+	return kem.Capabilities(s.Scheme) &^ kem.CapDeterministicKeygen
+}
+
+func (s *randomOnlyScheme) DeriveKeyPair([]byte) (kem.PublicKey, kem.PrivateKey) {
+	panic("randomOnlyScheme: DeriveKeyPair is not supported")
+}
+
+func TestDeriveKeyPair(t *testing.T) {
+	s := mlkem768.Scheme()
+	seed := make([]byte, s.SeedSize())
+	pk, sk, err := kem.DeriveKeyPair(s, seed)
+	require.NoError(t, err)
+	pk2, _ := s.DeriveKeyPair(seed)
+	require.True(t, pk.Equal(pk2))
+	require.True(t, sk.Public().Equal(pk))
+
+	_, _, err = kem.DeriveKeyPair(s, seed[1:])
+	require.ErrorIs(t, err, kem.ErrSeedSize)
+	var sizeErr *kem.SizeError
+	require.True(t, errors.As(err, &sizeErr))
+	require.Equal(t, s.SeedSize(), sizeErr.Expected)
+
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	randomOnly := &randomOnlyScheme{x25519KEM}
+	_, _, err = kem.DeriveKeyPair(randomOnly, make([]byte, randomOnly.SeedSize()))
+	require.ErrorIs(t, err, kem.ErrNotDeriveable)
+
+	// A combiner can only derive keys if all its components can.
+	c := combiner.New("MLKEM768-X25519", []kem.Scheme{s, x25519KEM})
+	require.True(t, kem.Capabilities(c).Has(kem.CapDeterministicKeygen))
+	c = combiner.New("MLKEM768-RandomOnly", []kem.Scheme{s, randomOnly})
+	require.False(t, kem.Capabilities(c).Has(kem.CapDeterministicKeygen))
+	_, _, err = kem.DeriveKeyPair(c, make([]byte, c.SeedSize()))
+	require.ErrorIs(t, err, kem.ErrNotDeriveable)
+}
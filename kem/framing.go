@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WritePublicKey writes the public key to w as a big endian uint32
+// length followed by its binary encoding, for exchanging keys over a
+// stream such as a net.Conn. Keys of 4 GiB or more can't be framed and
+// are rejected.
+func WritePublicKey(w io.Writer, pk PublicKey) error {
+	blob, err := pk.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if uint64(len(blob)) > math.MaxUint32 {
+		return fmt.Errorf("kem: public key of %d bytes is too large to frame", len(blob))
+	}
+	buf := make([]byte, 4, 4+len(blob))
+	binary.BigEndian.PutUint32(buf, uint32(len(blob)))
+	buf = append(buf, blob...)
+	_, err = w.Write(buf)
+	return err
+}
+
+// ReadPublicKey reads a public key of the given scheme written by
+// WritePublicKey. Since all the keys of a scheme have the same size,
+// a length other than the scheme's PublicKeySize is rejected with a
+// SizeError before anything more is read, so a peer can't make us
+// allocate or read more than one key's worth of data. A stream which
+// ends early yields io.ErrUnexpectedEOF, or io.EOF if it ends before
+// the length.
+func ReadPublicKey(r io.Reader, sch Scheme) (PublicKey, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	size := uint64(binary.BigEndian.Uint32(l[:]))
+	if size != uint64(sch.PublicKeySize()) {
+		// The length can exceed an int on 32 bit platforms, where the
+		// size reported is capped instead.
+		got := math.MaxInt
+		if size < math.MaxInt {
+			got = int(size)
+		}
+		return nil, &SizeError{Err: ErrPubKeySize, Expected: sch.PublicKeySize(), Got: got}
+	}
+	blob := make([]byte, size)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return nil, err
+	}
+	return sch.UnmarshalBinaryPublicKey(blob)
+}
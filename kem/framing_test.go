@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+)
+
+func TestReadWritePublicKey(t *testing.T) {
+	s := mlkem768.Scheme()
+	pk1, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	pk2, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, kem.WritePublicKey(buf, pk1))
+	require.NoError(t, kem.WritePublicKey(buf, pk2))
+	require.Equal(t, 2*(4+s.PublicKeySize()), buf.Len())
+	wire := bytes.Clone(buf.Bytes())
+
+	got, err := kem.ReadPublicKey(buf, s)
+	require.NoError(t, err)
+	require.True(t, pk1.Equal(got))
+	got, err = kem.ReadPublicKey(buf, s)
+	require.NoError(t, err)
+	require.True(t, pk2.Equal(got))
+	_, err = kem.ReadPublicKey(buf, s)
+	require.ErrorIs(t, err, io.EOF)
+
+	_, err = kem.ReadPublicKey(bytes.NewReader(wire[:100]), s)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// A huge claimed length is rejected without reading further.
+	_, err = kem.ReadPublicKey(bytes.NewReader([]byte{0x7f, 0xff, 0xff, 0xff}), s)
+	require.ErrorIs(t, err, kem.ErrPubKeySize)
+	var sizeErr *kem.SizeError
+	require.ErrorAs(t, err, &sizeErr)
+	require.Equal(t, 0x7fffffff, sizeErr.Got)
+
+	// The largest length fits in Got, or is capped where int is 32 bits.
+	_, err = kem.ReadPublicKey(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), s)
+	require.ErrorAs(t, err, &sizeErr)
+	require.Equal(t, uint64(min(0xffffffff, math.MaxInt)), uint64(sizeErr.Got))
+}
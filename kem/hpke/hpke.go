@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package hpke provides a lightweight public key encryption construction
+// in the spirit of HPKE, built out of any of our KEMs, HKDF-SHA256 and
+// XChaCha20-Poly1305. It is not compatible with RFC 9180.
+//
+// Seal encapsulates to the recipient's public key and derives a fresh
+// AEAD key and nonce from the shared secret with HKDF, using
+//
+//	info = "hpqc kem/hpke v1" || len(name) || name || kem_ct
+//
+// where name is the KEM scheme name and its length is a big endian
+// uint16, so that the key is bound to the scheme and the KEM ciphertext.
+// The output is
+//
+//	len(kem_ct) || kem_ct || aead_ct
+//
+// with the length encoded as a big endian uint32. Because every message
+// uses a new KEM encapsulation, the derived nonce is never reused.
+package hpke
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+var (
+	// ErrMalformed is returned by Open if the ciphertext can't be parsed.
+	ErrMalformed = errors.New("hpke: malformed ciphertext")
+
+	// ErrOpen is returned by Open if the ciphertext or associated data
+	// fails authentication.
+	ErrOpen = errors.New("hpke: message authentication failed")
+)
+
+var infoLabel = []byte("hpqc kem/hpke v1")
+
+// Seal encrypts and authenticates plaintext, and authenticates aad, to
+// the holder of the private key corresponding to pk.
+func Seal(sch kem.Scheme, pk kem.PublicKey, plaintext, aad []byte) ([]byte, error) {
+	kemCt, ss, err := sch.Encapsulate(pk)
+	if err != nil {
+		return nil, err
+	}
+	key, nonce, err := deriveKeyAndNonce(sch, ss, kemCt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 4, 4+len(kemCt)+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint32(out, uint32(len(kemCt)))
+	out = append(out, kemCt...)
+	return aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+// Open decrypts and authenticates a ciphertext created by Seal with the
+// given private key and associated data.
+func Open(sch kem.Scheme, sk kem.PrivateKey, ct, aad []byte) ([]byte, error) {
+	if len(ct) < 4 {
+		return nil, ErrMalformed
+	}
+	n := binary.BigEndian.Uint32(ct)
+	ct = ct[4:]
+	if uint64(n) != uint64(sch.CiphertextSize()) || len(ct) < int(n)+chacha20poly1305.Overhead {
+		return nil, ErrMalformed
+	}
+	kemCt, aeadCt := ct[:n], ct[n:]
+
+	ss, err := sch.Decapsulate(sk, kemCt)
+	if err != nil {
+		return nil, err
+	}
+	key, nonce, err := deriveKeyAndNonce(sch, ss, kemCt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, aeadCt, aad)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+func deriveKeyAndNonce(sch kem.Scheme, ss, kemCt []byte) (key, nonce []byte, err error) {
+	name := sch.Name()
+	info := make([]byte, 0, len(infoLabel)+2+len(name)+len(kemCt))
+	info = append(info, infoLabel...)
+	info = binary.BigEndian.AppendUint16(info, uint16(len(name)))
+	info = append(info, name...)
+	info = append(info, kemCt...)
+
+	okm := make([]byte, chacha20poly1305.KeySize+chacha20poly1305.NonceSizeX)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, ss, nil, info), okm); err != nil {
+		return nil, nil, err
+	}
+	return okm[:chacha20poly1305.KeySize], okm[chacha20poly1305.KeySize:], nil
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package hpke
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestSealOpen(t *testing.T) {
+	s := combiner.New("MLKEM768-X25519", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		mlkem768.Scheme(),
+	})
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	msg := []byte("attack at dawn")
+	aad := []byte("header")
+	ct, err := Seal(s, pubKey, msg, aad)
+	require.NoError(t, err)
+
+	pt, err := Open(s, privKey, ct, aad)
+	require.NoError(t, err)
+	require.Equal(t, msg, pt)
+
+	// each message gets a fresh encapsulation
+	ct2, err := Seal(s, pubKey, msg, aad)
+	require.NoError(t, err)
+	require.NotEqual(t, ct, ct2)
+
+	_, err = Open(s, privKey, ct, []byte("other header"))
+	require.ErrorIs(t, err, ErrOpen)
+
+	ct[len(ct)-1] ^= 1
+	_, err = Open(s, privKey, ct, aad)
+	require.ErrorIs(t, err, ErrOpen)
+
+	_, err = Open(s, privKey, ct[:10], aad)
+	require.ErrorIs(t, err, ErrMalformed)
+
+	_, otherKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	_, err = Open(s, otherKey, ct2, aad)
+	require.ErrorIs(t, err, ErrOpen)
+}
@@ -5,30 +5,42 @@
 //
 //	https://eprint.iacr.org/2018/024.pdf
 //
-// For deriving a KEM keypair deterministically and encapsulating
-// deterministically, we expand a single seed to both using Blake2b hash and then XOF,
-// so that a non-uniform seed (such as a shared secret generated by a hybrid
-// KEM where one of the KEMs is weak) doesn't impact just one of the KEMs.
+// For deriving a KEM keypair deterministically with DeriveKeyPairExpanded and
+// encapsulating deterministically with EncapsulateFromSeed, we expand a
+// single seed to both using Blake2b hash and then XOF, so that a non-uniform
+// seed (such as a shared secret generated by a hybrid KEM where one of the
+// KEMs is weak) doesn't impact just one of the KEMs. DeriveKeyPair and
+// EncapsulateDeterministically instead split their seed linearly.
 
 package hybrid
 
 import (
+	"context"
+	"crypto/hmac"
 	"errors"
 	"fmt"
 
 	"github.com/katzenpost/hpqc/kem"
 	"github.com/katzenpost/hpqc/kem/pem"
 	"github.com/katzenpost/hpqc/kem/util"
+	hpqcutil "github.com/katzenpost/hpqc/util"
 	"golang.org/x/crypto/blake2b"
 )
 
 var (
 	ErrUninitialized = errors.New("public or private key not initialized")
+
+	// ErrComponentMismatch indicates that a component key doesn't belong
+	// to the corresponding component scheme of the hybrid KEM.
+	ErrComponentMismatch = errors.New("hybrid: component key is for the wrong scheme")
 )
 
 var _ kem.PrivateKey = (*PrivateKey)(nil)
 var _ kem.PublicKey = (*PublicKey)(nil)
+var _ kem.MarshalerTo = (*PublicKey)(nil)
 var _ kem.Scheme = (*Scheme)(nil)
+var _ kem.Composite = (*Scheme)(nil)
+var _ kem.ContextKeyGenerator = (*Scheme)(nil)
 
 // Public key of a hybrid KEM.
 type PublicKey struct {
@@ -60,7 +72,29 @@ func New(name string, first kem.Scheme, second kem.Scheme) *Scheme {
 	}
 }
 
+// AssemblePublicKey builds a hybrid public key out of its component
+// public keys, for example when they are stored separately. Each key
+// must belong to the scheme returned for its position by Components,
+// for instance by unmarshaling it with that scheme.
+func AssemblePublicKey(sch *Scheme, first, second kem.PublicKey) (*PublicKey, error) {
+	if first == nil || second == nil {
+		return nil, ErrUninitialized
+	}
+	if first.Scheme() != sch.first {
+		return nil, fmt.Errorf("%w: first key is for %s, not %s", ErrComponentMismatch, first.Scheme().Name(), sch.first.Name())
+	}
+	if second.Scheme() != sch.second {
+		return nil, fmt.Errorf("%w: second key is for %s, not %s", ErrComponentMismatch, second.Scheme().Name(), sch.second.Name())
+	}
+	return &PublicKey{sch, first, second}, nil
+}
+
 func (sch *Scheme) Name() string { return sch.name }
+
+// Components returns the first and second component KEMs.
+func (sch *Scheme) Components() []kem.Scheme {
+	return []kem.Scheme{sch.first, sch.second}
+}
 func (sch *Scheme) PublicKeySize() int {
 	return sch.first.PublicKeySize() + sch.second.PublicKeySize()
 }
@@ -111,6 +145,21 @@ func (sk *PrivateKey) Equal(other kem.PrivateKey) bool {
 	return sk.first.Equal(oth.first) && sk.second.Equal(oth.second)
 }
 
+// Reset zeroizes both component private keys, provided they implement
+// kem.Resetter, see Resettable.
+func (sk *PrivateKey) Reset() {
+	for _, k := range []kem.PrivateKey{sk.first, sk.second} {
+		if r, ok := k.(kem.Resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// Resettable returns true if Reset zeroizes both component private keys.
+func (sk *PrivateKey) Resettable() bool {
+	return kem.Resettable(sk.first) && kem.Resettable(sk.second)
+}
+
 func (sk *PrivateKey) Public() kem.PublicKey {
 	return &PublicKey{sk.scheme, sk.first.Public(), sk.second.Public()}
 }
@@ -138,6 +187,29 @@ func (pk *PublicKey) MarshalBinary() ([]byte, error) {
 	return append(first, second...), nil
 }
 
+// MarshalBinaryTo writes the binary encoding of the public key into dst,
+// which must be at least PublicKeySize() bytes long, and returns the
+// number of bytes written. It doesn't allocate as long as both
+// components implement kem.MarshalerTo, as the NIKE adapter, mlkem768,
+// sntrup and xwing keys do.
+func (pk *PublicKey) MarshalBinaryTo(dst []byte) (int, error) {
+	if pk.first == nil || pk.second == nil {
+		return 0, ErrUninitialized
+	}
+	if len(dst) < pk.scheme.PublicKeySize() {
+		return 0, kem.ErrPubKeySize
+	}
+	n, err := kem.MarshalPublicKeyTo(dst, pk.first)
+	if err != nil {
+		return 0, err
+	}
+	m, err := kem.MarshalPublicKeyTo(dst[n:], pk.second)
+	if err != nil {
+		return 0, err
+	}
+	return n + m, nil
+}
+
 func (sch *Scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
 	pk1, sk1, err := sch.first.GenerateKeyPair()
 	if err != nil {
@@ -151,18 +223,127 @@ func (sch *Scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
 	return &PublicKey{sch, pk1, pk2}, &PrivateKey{sch, sk1, sk2}, nil
 }
 
+// GenerateKeyPairContext generates a key pair like GenerateKeyPair, but
+// checks ctx before each of the two component key generations and
+// passes it on to components which implement kem.ContextKeyGenerator.
+// If ctx is done it resets the first private key if one was generated
+// and returns ctx.Err().
+func (sch *Scheme) GenerateKeyPairContext(ctx context.Context) (kem.PublicKey, kem.PrivateKey, error) {
+	pk1, sk1, err := kem.GenerateKeyPairContext(ctx, sch.first)
+	if err != nil {
+		return nil, nil, err
+	}
+	pk2, sk2, err := kem.GenerateKeyPairContext(ctx, sch.second)
+	if err != nil {
+		kem.ResetPrivateKeys(sk1)
+		return nil, nil, err
+	}
+
+	return &PublicKey{sch, pk1, pk2}, &PrivateKey{sch, sk1, sk2}, nil
+}
+
+// DeriveKeyPair derives a key pair by splitting seed between the two
+// components. Panics of the components are redacted by the top level
+// util.SafePanic.
 func (sch *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
 	if len(seed) != sch.first.SeedSize()+sch.second.SeedSize() {
 		panic(fmt.Sprintf("seed size must be %d", sch.first.SeedSize()+sch.second.SeedSize()))
 	}
 
-	pk1, sk1 := sch.first.DeriveKeyPair(seed[:sch.first.SeedSize()])
-	pk2, sk2 := sch.second.DeriveKeyPair(seed[sch.first.SeedSize():])
+	var pk1, pk2 kem.PublicKey
+	var sk1, sk2 kem.PrivateKey
+	hpqcutil.SafePanic(func() {
+		pk1, sk1 = sch.first.DeriveKeyPair(seed[:sch.first.SeedSize()])
+		pk2, sk2 = sch.second.DeriveKeyPair(seed[sch.first.SeedSize():])
+	})
 
 	return &PublicKey{sch, pk1, pk2}, &PrivateKey{sch, sk1, sk2}
 }
 
+// DeriveKeyPairExpanded derives a key pair from a single seed of at least
+// util.MinExpandSeedSize bytes, which is expanded with Blake2b and XOF into
+// independent seeds for the two components as described in the package
+// documentation. Unlike DeriveKeyPair, which splits its SeedSize() long seed
+// linearly and is kept for keys which were already derived that way, a seed
+// with little entropy in one half can't weaken only one of the components.
+// Panics if the seed is too short. Panics of the components are
+// redacted by the top level util.SafePanic, which also zeroizes the
+// expanded seeds.
+func (sch *Scheme) DeriveKeyPairExpanded(seed []byte) (kem.PublicKey, kem.PrivateKey) {
+	if len(seed) < util.MinExpandSeedSize {
+		panic(fmt.Sprintf("seed size must be at least %d", util.MinExpandSeedSize))
+	}
+	seeds := util.ExpandSeeds(seed, []int{sch.first.SeedSize(), sch.second.SeedSize()})
+	defer func() {
+		for _, s := range seeds {
+			hpqcutil.ExplicitBzero(s)
+		}
+	}()
+
+	var pk1, pk2 kem.PublicKey
+	var sk1, sk2 kem.PrivateKey
+	hpqcutil.SafePanic(func() {
+		pk1, sk1 = sch.first.DeriveKeyPair(seeds[0])
+		pk2, sk2 = sch.second.DeriveKeyPair(seeds[1])
+	}, seeds...)
+
+	return &PublicKey{sch, pk1, pk2}, &PrivateKey{sch, sk1, sk2}
+}
+
+// VerifyConsistency returns true if both halves of pk are those derived
+// from seed, detecting hybrid keys spliced together from components of
+// different origins. The keys are re-derived with DeriveKeyPair if seed
+// is SeedSize bytes long and with DeriveKeyPairExpanded if it is long
+// enough for it, and pk is consistent if either derivation matches. It
+// returns false rather than panicking for seeds which fit neither.
+func VerifyConsistency(pk *PublicKey, seed []byte) bool {
+	if pk == nil || pk.scheme == nil || pk.first == nil || pk.second == nil {
+		return false
+	}
+	sch := pk.scheme
+	if len(seed) == sch.SeedSize() {
+		derived, sk := sch.DeriveKeyPair(seed)
+		kem.ResetPrivateKeys(sk.(*PrivateKey).first, sk.(*PrivateKey).second)
+		if pk.sameHalves(derived.(*PublicKey)) {
+			return true
+		}
+	}
+	if len(seed) >= util.MinExpandSeedSize {
+		derived, sk := sch.DeriveKeyPairExpanded(seed)
+		kem.ResetPrivateKeys(sk.(*PrivateKey).first, sk.(*PrivateKey).second)
+		if pk.sameHalves(derived.(*PublicKey)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameHalves compares both halves by their encoding, which unlike the
+// component Equal methods never panics on keys of another type.
+func (p *PublicKey) sameHalves(other *PublicKey) bool {
+	for _, pair := range [][2]kem.PublicKey{{p.first, other.first}, {p.second, other.second}} {
+		a, err := pair[0].MarshalBinary()
+		if err != nil {
+			return false
+		}
+		b, err := pair[1].MarshalBinary()
+		if err != nil || !hmac.Equal(a, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encapsulate creates a shared secret and ciphertext given a public key.
+// Panics of the component schemes are redacted by util.SafePanic.
 func (sch *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	hpqcutil.SafePanic(func() {
+		ct, ss, err = sch.encapsulate(pk)
+	})
+	return ct, ss, err
+}
+
+func (sch *Scheme) encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
 	pub, ok := pk.(*PublicKey)
 	if !ok {
 		return nil, nil, kem.ErrTypeMismatch
@@ -181,13 +362,85 @@ func (sch *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
 	return append(ct1, ct2...), util.PairSplitPRF(ss1, ss2, ct1, ct2), nil
 }
 
+// EncapsulateDeterministically splits the seed linearly between the two
+// component KEMs, both of which must implement kem.DeterministicEncapsulator.
 func (sch *Scheme) EncapsulateDeterministically(publicKey kem.PublicKey, seed []byte) (ct, ss []byte, err error) {
-	panic("not implemented")
+	first, second, err := sch.deterministicEncapsulators()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(seed) != sch.EncapsulationSeedSize() {
+		return nil, nil, kem.ErrSeedSize
+	}
+	pub, ok := publicKey.(*PublicKey)
+	if !ok {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+
+	firstSize := first.EncapsulationSeedSize()
+	ct1, ss1, err := first.EncapsulateDeterministically(pub.first, seed[:firstSize])
+	if err != nil {
+		return nil, nil, err
+	}
+	ct2, ss2, err := second.EncapsulateDeterministically(pub.second, seed[firstSize:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return append(ct1, ct2...), util.PairSplitPRF(ss1, ss2, ct1, ct2), nil
 }
 
-func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+// EncapsulateFromSeed deterministically encapsulates using a single short
+// seed of at least util.MinExpandSeedSize bytes, which is expanded into
+// independent encapsulation seeds for the two components as described in
+// the package documentation.
+func (sch *Scheme) EncapsulateFromSeed(publicKey kem.PublicKey, seed []byte) (ct, ss []byte, err error) {
+	first, second, err := sch.deterministicEncapsulators()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(seed) < util.MinExpandSeedSize {
+		return nil, nil, kem.ErrSeedSize
+	}
+	seeds := util.ExpandSeeds(seed, []int{first.EncapsulationSeedSize(), second.EncapsulationSeedSize()})
+	return sch.EncapsulateDeterministically(publicKey, append(seeds[0], seeds[1]...))
+}
+
+// EncapsulationSeedSize returns the size of the seed used by
+// EncapsulateDeterministically, or 0 if either component doesn't
+// support deterministic encapsulation.
+func (sch *Scheme) EncapsulationSeedSize() int {
+	first, second, err := sch.deterministicEncapsulators()
+	if err != nil {
+		return 0
+	}
+	return first.EncapsulationSeedSize() + second.EncapsulationSeedSize()
+}
+
+func (sch *Scheme) deterministicEncapsulators() (kem.DeterministicEncapsulator, kem.DeterministicEncapsulator, error) {
+	first, ok := sch.first.(kem.DeterministicEncapsulator)
+	if !ok {
+		return nil, nil, fmt.Errorf("hybrid: %s does not support deterministic encapsulation", sch.first.Name())
+	}
+	second, ok := sch.second.(kem.DeterministicEncapsulator)
+	if !ok {
+		return nil, nil, fmt.Errorf("hybrid: %s does not support deterministic encapsulation", sch.second.Name())
+	}
+	return first, second, nil
+}
+
+// Decapsulate decrypts a given KEM ciphertext using the given private key.
+// Panics of the component schemes are redacted by util.SafePanic.
+func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) (ss []byte, err error) {
+	hpqcutil.SafePanic(func() {
+		ss, err = sch.decapsulate(sk, ct)
+	})
+	return ss, err
+}
+
+func (sch *Scheme) decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
 	if len(ct) != sch.CiphertextSize() {
-		return nil, kem.ErrCiphertextSize
+		return nil, kem.CiphertextSizeError(sch.CiphertextSize(), len(ct))
 	}
 
 	priv, ok := sk.(*PrivateKey)
@@ -208,34 +461,90 @@ func (sch *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
 	return util.PairSplitPRF(ss1, ss2, ct[:firstSize], ct[firstSize:]), nil
 }
 
+// DecapsulateComponent decapsulates only the first (index 0) or second
+// (index 1) component ciphertext and returns its shared secret before
+// it is combined. It is a debugging aid, the result is NOT the shared
+// secret returned by Decapsulate.
+func (sch *Scheme) DecapsulateComponent(sk kem.PrivateKey, ct []byte, index int) ([]byte, error) {
+	if index != 0 && index != 1 {
+		return nil, fmt.Errorf("hybrid: component index %d out of range [0, 2)", index)
+	}
+	if len(ct) != sch.CiphertextSize() {
+		return nil, kem.CiphertextSizeError(sch.CiphertextSize(), len(ct))
+	}
+	priv, ok := sk.(*PrivateKey)
+	if !ok {
+		return nil, kem.ErrTypeMismatch
+	}
+
+	firstSize := sch.first.CiphertextSize()
+	if index == 0 {
+		return sch.first.Decapsulate(priv.first, ct[:firstSize])
+	}
+	return sch.second.Decapsulate(priv.second, ct[firstSize:])
+}
+
+// ImplicitRejection returns true only if both component KEMs use
+// implicit rejection. Decapsulate returns the first error returned
+// by either component, so if one of them can fail explicitly then so
+// can the hybrid. When it returns true an invalid ciphertext yields a
+// shared secret which doesn't match the sender's, because the
+// rejection secret of the failing component is fed into the combiner.
+func (sch *Scheme) ImplicitRejection() bool {
+	return kem.ImplicitRejection(sch.first) && kem.ImplicitRejection(sch.second)
+}
+
+// Capabilities returns CapHybrid, plus CapPostQuantum if either
+// component reports it, CapDeterministicKeygen if both components
+// report it and CapImplicitReject if ImplicitRejection returns true.
+func (sch *Scheme) Capabilities() kem.Caps {
+	first, second := kem.Capabilities(sch.first), kem.Capabilities(sch.second)
+	caps := kem.CapHybrid
+	caps |= (first | second) & kem.CapPostQuantum
+	caps |= first & second & kem.CapDeterministicKeygen
+	if sch.ImplicitRejection() {
+		caps |= kem.CapImplicitReject
+	}
+	return caps
+}
+
 func (sch *Scheme) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
 	if len(buf) != sch.PublicKeySize() {
-		return nil, kem.ErrPubKeySize
+		return nil, kem.PubKeySizeError(sch.PublicKeySize(), len(buf))
 	}
 	firstSize := sch.first.PublicKeySize()
 	pk1, err := sch.first.UnmarshalBinaryPublicKey(buf[:firstSize])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("hybrid: %s: %w", sch.first.Name(), err)
 	}
 	pk2, err := sch.second.UnmarshalBinaryPublicKey(buf[firstSize:])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("hybrid: %s: %w", sch.second.Name(), err)
 	}
 	return &PublicKey{sch, pk1, pk2}, nil
 }
 
-func (sch *Scheme) UnmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
+// UnmarshalBinaryPrivateKey unmarshals a binary blob representing a private key.
+// Panics of the component schemes are redacted by util.SafePanic.
+func (sch *Scheme) UnmarshalBinaryPrivateKey(buf []byte) (sk kem.PrivateKey, err error) {
+	hpqcutil.SafePanic(func() {
+		sk, err = sch.unmarshalBinaryPrivateKey(buf)
+	})
+	return sk, err
+}
+
+func (sch *Scheme) unmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
 	if len(buf) != sch.PrivateKeySize() {
-		return nil, kem.ErrPrivKeySize
+		return nil, kem.PrivKeySizeError(sch.PrivateKeySize(), len(buf))
 	}
 	firstSize := sch.first.PrivateKeySize()
 	sk1, err := sch.first.UnmarshalBinaryPrivateKey(buf[:firstSize])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("hybrid: %s: %w", sch.first.Name(), err)
 	}
 	sk2, err := sch.second.UnmarshalBinaryPrivateKey(buf[firstSize:])
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("hybrid: %s: %w", sch.second.Name(), err)
 	}
 	return &PrivateKey{sch, sk1, sk2}, nil
 }
@@ -0,0 +1,185 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package hybrid
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/util"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func testScheme() *Scheme {
+	return New(
+		"X25519-X448",
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		adapter.FromNIKE(x448.Scheme(rand.Reader)),
+	)
+}
+
+func TestMarshalBinaryTo(t *testing.T) {
+	s := testScheme()
+	pubKey, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	blob, err := pubKey.MarshalBinary()
+	require.NoError(t, err)
+
+	dst := make([]byte, s.PublicKeySize())
+	n, err := pubKey.(*PublicKey).MarshalBinaryTo(dst)
+	require.NoError(t, err)
+	require.Equal(t, s.PublicKeySize(), n)
+	require.Equal(t, blob, dst)
+
+	_, err = pubKey.(*PublicKey).MarshalBinaryTo(dst[:n-1])
+	require.ErrorIs(t, err, kem.ErrPubKeySize)
+}
+
+func BenchmarkPublicKeyMarshalBinary(b *testing.B) {
+	s := testScheme()
+	pubKey, _, err := s.GenerateKeyPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pubKey.MarshalBinary(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPublicKeyMarshalBinaryTo(b *testing.B) {
+	s := testScheme()
+	pubKey, _, err := s.GenerateKeyPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+	dst := make([]byte, s.PublicKeySize())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pubKey.(*PublicKey).MarshalBinaryTo(dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDeriveKeyPairExpanded(t *testing.T) {
+	s := testScheme()
+	seed := make([]byte, 32)
+	_, err := rand.Reader.Read(seed)
+	require.NoError(t, err)
+
+	pubKey1, privKey1 := s.DeriveKeyPairExpanded(seed)
+	pubKey2, privKey2 := s.DeriveKeyPairExpanded(seed)
+	require.True(t, pubKey1.Equal(pubKey2))
+	require.True(t, privKey1.Equal(privKey2))
+
+	// the expanded derivation is distinct from the linear one
+	pubKey3, _ := s.DeriveKeyPair(append(seed, seed...))
+	require.False(t, pubKey1.Equal(pubKey3))
+
+	ct, ss1, err := s.Encapsulate(pubKey1)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(privKey1, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss2)
+
+	require.Panics(t, func() {
+		s.DeriveKeyPairExpanded(seed[:16])
+	})
+}
+
+func TestAssemblePublicKey(t *testing.T) {
+	s := testScheme()
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	blob, err := pubKey.MarshalBinary()
+	require.NoError(t, err)
+
+	components := s.Components()
+	firstSize := components[0].PublicKeySize()
+	first, err := components[0].UnmarshalBinaryPublicKey(blob[:firstSize])
+	require.NoError(t, err)
+	second, err := components[1].UnmarshalBinaryPublicKey(blob[firstSize:])
+	require.NoError(t, err)
+
+	assembled, err := AssemblePublicKey(s, first, second)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(assembled))
+
+	ct, ss1, err := s.Encapsulate(assembled)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(privKey, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss2)
+
+	_, err = AssemblePublicKey(s, second, first)
+	require.ErrorIs(t, err, ErrComponentMismatch)
+
+	// keys of an equivalent but distinct scheme instance are rejected
+	other := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	otherFirst, err := other.UnmarshalBinaryPublicKey(blob[:firstSize])
+	require.NoError(t, err)
+	_, err = AssemblePublicKey(s, otherFirst, second)
+	require.ErrorIs(t, err, ErrComponentMismatch)
+
+	_, err = AssemblePublicKey(s, nil, second)
+	require.ErrorIs(t, err, ErrUninitialized)
+}
+
+func TestVerifyConsistency(t *testing.T) {
+	s := testScheme()
+	seed := make([]byte, s.SeedSize())
+	_, err := rand.Reader.Read(seed)
+	require.NoError(t, err)
+	pubKey, _ := s.DeriveKeyPair(seed)
+	require.True(t, VerifyConsistency(pubKey.(*PublicKey), seed))
+
+	expandedSeed := seed[:util.MinExpandSeedSize]
+	expanded, _ := s.DeriveKeyPairExpanded(expandedSeed)
+	require.True(t, VerifyConsistency(expanded.(*PublicKey), expandedSeed))
+	require.False(t, VerifyConsistency(expanded.(*PublicKey), seed))
+
+	// A key spliced together from the halves of two seeds.
+	otherSeed := make([]byte, s.SeedSize())
+	_, err = rand.Reader.Read(otherSeed)
+	require.NoError(t, err)
+	other, _ := s.DeriveKeyPair(otherSeed)
+	spliced, err := AssemblePublicKey(s, pubKey.(*PublicKey).first, other.(*PublicKey).second)
+	require.NoError(t, err)
+	require.False(t, VerifyConsistency(spliced, seed))
+	require.False(t, VerifyConsistency(spliced, otherSeed))
+
+	require.False(t, VerifyConsistency(pubKey.(*PublicKey), seed[:1]))
+	require.False(t, VerifyConsistency(nil, seed))
+}
+
+func TestGenerateKeyPairContext(t *testing.T) {
+	s := testScheme()
+
+	pk, sk, err := kem.GenerateKeyPairContext(context.Background(), s)
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pk, sk, err = s.GenerateKeyPairContext(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, pk)
+	require.Nil(t, sk)
+}
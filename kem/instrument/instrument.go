@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package instrument provides a KEM scheme wrapper which reports the
+// latency and outcome of every operation to caller supplied hooks,
+// for example to export metrics.
+package instrument
+
+import (
+	"time"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// Hooks are called after each operation of a wrapped scheme completes
+// with the time it took and the error it returned, if any. Nil hooks are
+// skipped. Hooks are called synchronously and may be called
+// concurrently, so they should be fast and safe for concurrent use.
+type Hooks struct {
+	OnGenerateKeyPair func(dur time.Duration, err error)
+	OnDeriveKeyPair   func(dur time.Duration)
+	OnEncapsulate     func(dur time.Duration, err error)
+	OnDecapsulate     func(dur time.Duration, err error)
+}
+
+// Scheme is a kem.Scheme which wraps another scheme and calls Hooks
+// around its operations. Sizes, names and marshaling are those of the
+// wrapped scheme, and keys returned by it belong to the wrapped scheme.
+type Scheme struct {
+	scheme kem.Scheme
+	hooks  Hooks
+}
+
+var _ kem.Scheme = (*Scheme)(nil)
+
+// DeterministicScheme is a Scheme wrapping a scheme which implements
+// kem.DeterministicEncapsulator, and implements it too.
+type DeterministicScheme struct {
+	*Scheme
+	d kem.DeterministicEncapsulator
+}
+
+var _ kem.DeterministicEncapsulator = (*DeterministicScheme)(nil)
+
+// Wrap returns a scheme which behaves exactly like sch and additionally
+// reports each operation to the given hooks. If sch implements
+// kem.DeterministicEncapsulator the returned scheme is a
+// *DeterministicScheme, otherwise it is a *Scheme.
+func Wrap(sch kem.Scheme, hooks Hooks) kem.Scheme {
+	if sch == nil {
+		panic("instrument: KEM scheme cannot be nil")
+	}
+	s := &Scheme{
+		scheme: sch,
+		hooks:  hooks,
+	}
+	if d, ok := sch.(kem.DeterministicEncapsulator); ok {
+		return &DeterministicScheme{
+			Scheme: s,
+			d:      d,
+		}
+	}
+	return s
+}
+
+// Unwrap returns the wrapped scheme.
+func (s *Scheme) Unwrap() kem.Scheme {
+	return s.scheme
+}
+
+// Name returns the name of the wrapped scheme.
+func (s *Scheme) Name() string {
+	return s.scheme.Name()
+}
+
+// GenerateKeyPair creates a new key pair.
+func (s *Scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	start := time.Now()
+	pubKey, privKey, err := s.scheme.GenerateKeyPair()
+	if s.hooks.OnGenerateKeyPair != nil {
+		s.hooks.OnGenerateKeyPair(time.Since(start), err)
+	}
+	return pubKey, privKey, err
+}
+
+// DeriveKeyPair deterministically derives a pair of keys from a seed.
+func (s *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
+	start := time.Now()
+	pubKey, privKey := s.scheme.DeriveKeyPair(seed)
+	if s.hooks.OnDeriveKeyPair != nil {
+		s.hooks.OnDeriveKeyPair(time.Since(start))
+	}
+	return pubKey, privKey
+}
+
+// Encapsulate generates a shared key ss for the public key and
+// encapsulates it into a ciphertext ct.
+func (s *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	start := time.Now()
+	ct, ss, err = s.scheme.Encapsulate(pk)
+	if s.hooks.OnEncapsulate != nil {
+		s.hooks.OnEncapsulate(time.Since(start), err)
+	}
+	return ct, ss, err
+}
+
+// EncapsulateDeterministically forwards to the wrapped scheme and
+// reports to the OnEncapsulate hook.
+func (s *DeterministicScheme) EncapsulateDeterministically(pk kem.PublicKey, seed []byte) (ct, ss []byte, err error) {
+	start := time.Now()
+	ct, ss, err = s.d.EncapsulateDeterministically(pk, seed)
+	if s.hooks.OnEncapsulate != nil {
+		s.hooks.OnEncapsulate(time.Since(start), err)
+	}
+	return ct, ss, err
+}
+
+// EncapsulationSeedSize returns the wrapped scheme's encapsulation seed
+// size.
+func (s *DeterministicScheme) EncapsulationSeedSize() int {
+	return s.d.EncapsulationSeedSize()
+}
+
+// Decapsulate returns the shared key encapsulated in ciphertext ct for
+// the private key sk.
+func (s *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+	start := time.Now()
+	ss, err := s.scheme.Decapsulate(sk, ct)
+	if s.hooks.OnDecapsulate != nil {
+		s.hooks.OnDecapsulate(time.Since(start), err)
+	}
+	return ss, err
+}
+
+// ImplicitRejection reports whether the wrapped scheme uses implicit
+// rejection.
+func (s *Scheme) ImplicitRejection() bool {
+	return kem.ImplicitRejection(s.scheme)
+}
+
+// Circl returns the circl scheme underneath the wrapped scheme, if any,
+// as described for kem.Circl. It is unstable and meant for advanced use.
+func (s *Scheme) Circl() interface{} {
+	return kem.Circl(s.scheme)
+}
+
+// Capabilities returns the capability flags of the wrapped scheme.
+func (s *Scheme) Capabilities() kem.Caps {
+	return kem.Capabilities(s.scheme)
+}
+
+// UnmarshalBinaryPublicKey unmarshals a PublicKey from the provided buffer.
+func (s *Scheme) UnmarshalBinaryPublicKey(b []byte) (kem.PublicKey, error) {
+	return s.scheme.UnmarshalBinaryPublicKey(b)
+}
+
+// UnmarshalBinaryPrivateKey unmarshals a PrivateKey from the provided buffer.
+func (s *Scheme) UnmarshalBinaryPrivateKey(b []byte) (kem.PrivateKey, error) {
+	return s.scheme.UnmarshalBinaryPrivateKey(b)
+}
+
+// UnmarshalTextPublicKey unmarshals a PublicKey from the provided text.
+func (s *Scheme) UnmarshalTextPublicKey(text []byte) (kem.PublicKey, error) {
+	return s.scheme.UnmarshalTextPublicKey(text)
+}
+
+// UnmarshalTextPrivateKey unmarshals a PrivateKey from the provided text.
+func (s *Scheme) UnmarshalTextPrivateKey(text []byte) (kem.PrivateKey, error) {
+	return s.scheme.UnmarshalTextPrivateKey(text)
+}
+
+// CiphertextSize returns the wrapped scheme's ciphertext size.
+func (s *Scheme) CiphertextSize() int {
+	return s.scheme.CiphertextSize()
+}
+
+// SharedKeySize returns the wrapped scheme's shared key size.
+func (s *Scheme) SharedKeySize() int {
+	return s.scheme.SharedKeySize()
+}
+
+// PrivateKeySize returns the wrapped scheme's private key size.
+func (s *Scheme) PrivateKeySize() int {
+	return s.scheme.PrivateKeySize()
+}
+
+// PublicKeySize returns the wrapped scheme's public key size.
+func (s *Scheme) PublicKeySize() int {
+	return s.scheme.PublicKeySize()
+}
+
+// SeedSize returns the wrapped scheme's key derivation seed size.
+func (s *Scheme) SeedSize() int {
+	return s.scheme.SeedSize()
+}
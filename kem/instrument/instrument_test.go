@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package instrument
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestWrap(t *testing.T) {
+	inner := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+
+	counts := make(map[string]int)
+	var decapErr error
+	s := Wrap(inner, Hooks{
+		OnGenerateKeyPair: func(dur time.Duration, err error) { counts["keygen"]++ },
+		OnEncapsulate:     func(dur time.Duration, err error) { counts["encap"]++ },
+		OnDecapsulate: func(dur time.Duration, err error) {
+			counts["decap"]++
+			decapErr = err
+		},
+	})
+
+	require.Equal(t, inner.Name(), s.Name())
+	require.Equal(t, inner.PublicKeySize(), s.PublicKeySize())
+	require.Equal(t, inner.PrivateKeySize(), s.PrivateKeySize())
+	require.Equal(t, inner.CiphertextSize(), s.CiphertextSize())
+	require.Equal(t, inner.SharedKeySize(), s.SharedKeySize())
+	require.Equal(t, inner.SeedSize(), s.SeedSize())
+	require.Equal(t, kem.ImplicitRejection(inner), kem.ImplicitRejection(s))
+
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	ct, ss1, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(privKey, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss2)
+
+	_, err = s.Decapsulate(privKey, ct[1:])
+	require.Error(t, err)
+	require.ErrorIs(t, decapErr, kem.ErrCiphertextSize)
+
+	require.Equal(t, map[string]int{"keygen": 1, "encap": 1, "decap": 2}, counts)
+
+	// marshaling goes through the wrapped scheme unchanged
+	blob, err := pubKey.MarshalText()
+	require.NoError(t, err)
+	pubKey2, err := s.UnmarshalTextPublicKey(blob)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+}
+
+func TestWrapDeterministic(t *testing.T) {
+	encaps := 0
+	hooks := Hooks{
+		OnEncapsulate: func(dur time.Duration, err error) { encaps++ },
+	}
+
+	inner := adapter.FromNIKE(x25519.Scheme(rand.Reader)).(kem.DeterministicEncapsulator)
+	s := Wrap(inner.(kem.Scheme), hooks)
+	d, ok := s.(kem.DeterministicEncapsulator)
+	require.True(t, ok)
+	require.Equal(t, inner.EncapsulationSeedSize(), d.EncapsulationSeedSize())
+
+	pubKey, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	seed := make([]byte, d.EncapsulationSeedSize())
+	ct1, ss1, err := d.EncapsulateDeterministically(pubKey, seed)
+	require.NoError(t, err)
+	ct2, ss2, err := inner.EncapsulateDeterministically(pubKey, seed)
+	require.NoError(t, err)
+	require.Equal(t, ct2, ct1)
+	require.Equal(t, ss2, ss1)
+	require.Equal(t, 1, encaps)
+
+	s = Wrap(mlkem768.Scheme(), hooks)
+	_, ok = s.(kem.DeterministicEncapsulator)
+	require.False(t, ok)
+}
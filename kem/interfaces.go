@@ -68,7 +68,8 @@ package kem
 
 import (
 	"encoding"
-	"errors"
+
+	circlkem "github.com/katzenpost/circl/kem"
 )
 
 // A KEM public key
@@ -97,6 +98,14 @@ type PrivateKey interface {
 }
 
 // A Scheme represents a specific instance of a KEM.
+//
+// The schemes in this module, including those returned by kem/schemes
+// and those built by the adapter, combiner and hybrid packages, hold no
+// mutable state and are safe for concurrent use by multiple goroutines,
+// as are their keys, provided that any entropy source given to a NIKE
+// scheme wrapped by the adapter is itself safe for concurrent use, as
+// rand.Reader is. A key must not be Reset while other goroutines are
+// using it.
 type Scheme interface {
 	// Name of the scheme
 	Name() string
@@ -145,30 +154,107 @@ type Scheme interface {
 	SeedSize() int
 }
 
+// Resetter is implemented by private keys which can overwrite their
+// secret key material with zeros.
+type Resetter interface {
+	// Reset zeroizes the key material. The key must not be used
+	// afterwards.
+	Reset()
+}
+
+// MarshalerTo is implemented by keys which can serialize themselves into
+// a caller provided buffer, avoiding the allocation done by MarshalBinary.
+type MarshalerTo interface {
+	// MarshalBinaryTo writes the binary encoding of the key into dst and
+	// returns the number of bytes written. It returns an error if dst is
+	// too small to hold the key.
+	MarshalBinaryTo(dst []byte) (int, error)
+}
+
+// MarshalPublicKeyTo writes the binary encoding of pk into dst and
+// returns the number of bytes written. Keys implementing MarshalerTo are
+// written without allocating, others are marshaled and copied.
+func MarshalPublicKeyTo(dst []byte, pk PublicKey) (int, error) {
+	if m, ok := pk.(MarshalerTo); ok {
+		return m.MarshalBinaryTo(dst)
+	}
+	blob, err := pk.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	if len(dst) < len(blob) {
+		return 0, ErrPubKeySize
+	}
+	return copy(dst, blob), nil
+}
+
+// DeterministicEncapsulator is implemented by schemes which can
+// encapsulate deterministically given a seed.
+type DeterministicEncapsulator interface {
+	// EncapsulateDeterministically generates a shared key ss for the
+	// public key deterministically from the given seed and encapsulates
+	// it into a ciphertext ct. Returns ErrSeedSize if the length of seed
+	// is not equal to the value returned by EncapsulationSeedSize.
+	EncapsulateDeterministically(pk PublicKey, seed []byte) (ct, ss []byte, err error)
+
+	// EncapsulationSeedSize returns the size of the seed used by
+	// EncapsulateDeterministically.
+	EncapsulationSeedSize() int
+}
+
+// ImplicitRejecter is implemented by schemes which can report how
+// Decapsulate behaves when given a correctly sized but invalid
+// ciphertext.
+type ImplicitRejecter interface {
+	// ImplicitRejection returns true if Decapsulate never returns an
+	// error for a ciphertext of the correct size, and instead returns
+	// a pseudorandom shared secret which won't match the sender's.
+	// It returns false if such a ciphertext may cause Decapsulate to
+	// return an error.
+	ImplicitRejection() bool
+}
+
+// ImplicitRejection reports whether the given scheme's Decapsulate
+// uses implicit rejection. Schemes which don't implement
+// ImplicitRejecter, such as those used directly from circl, are
+// conservatively reported as not using implicit rejection.
+func ImplicitRejection(s Scheme) bool {
+	r, ok := s.(ImplicitRejecter)
+	if !ok {
+		return false
+	}
+	return r.ImplicitRejection()
+}
+
+// The errors below are shared with the circl KEM package, so that
+// schemes taken directly from circl return the same values as our own.
+// They may be returned wrapped with additional context, for instance
+// naming the failing component of a hybrid KEM, so use errors.Is to test
+// for them.
 var (
 	// ErrTypeMismatch is the error used if types of, for instance, private
 	// and public keys don't match
-	ErrTypeMismatch = errors.New("types mismatch")
+	ErrTypeMismatch = circlkem.ErrTypeMismatch
 
 	// ErrSeedSize is the error used if the provided seed is of the wrong
 	// size.
-	ErrSeedSize = errors.New("wrong seed size")
+	ErrSeedSize = circlkem.ErrSeedSize
 
 	// ErrPubKeySize is the error used if the provided public key is of
 	// the wrong size.
-	ErrPubKeySize = errors.New("wrong size for public key")
+	ErrPubKeySize = circlkem.ErrPubKeySize
 
 	// ErrCiphertextSize is the error used if the provided ciphertext
 	// is of the wrong size.
-	ErrCiphertextSize = errors.New("wrong size for ciphertext")
+	ErrCiphertextSize = circlkem.ErrCiphertextSize
 
 	// ErrPrivKeySize is the error used if the provided private key is of
 	// the wrong size.
-	ErrPrivKeySize = errors.New("wrong size for private key")
+	ErrPrivKeySize = circlkem.ErrPrivKeySize
 
 	// ErrPubKey is the error used if the provided public key is invalid.
-	ErrPubKey = errors.New("invalid public key")
+	ErrPubKey = circlkem.ErrPubKey
 
 	// ErrCipherText is the error used if the provided ciphertext is invalid.
-	ErrCipherText = errors.New("invalid ciphertext")
+	ErrCipherText = circlkem.ErrCipherText
 )
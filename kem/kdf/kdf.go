@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package kdf derives symmetric keys from KEM shared secrets.
+package kdf
+
+import (
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/hkdf"
+)
+
+// MaxLength is the longest key Derive can produce, the HKDF limit of
+// 255 blocks of BLAKE2b-256 output.
+const MaxLength = 255 * blake2b.Size256
+
+func newHash() hash.Hash {
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Derive returns a length byte key for the given purpose, such as
+// "client to server encryption" or "handshake mac", derived from a KEM
+// shared secret. It is HKDF (RFC 5869) instantiated with BLAKE2b-256
+// as the hash, an empty salt, secret as the input keying material and
+// purpose as the info string.
+//
+// Keys for different purposes are independent, so a single shared
+// secret can safely key several ciphers and MACs. However, keys of
+// different lengths for the same purpose are prefixes of one another,
+// so a purpose should only ever be used with one length. Derive panics
+// if purpose is empty or length is not between 1 and MaxLength.
+func Derive(secret []byte, purpose string, length int) []byte {
+	if purpose == "" {
+		panic("kdf: empty purpose")
+	}
+	if length <= 0 || length > MaxLength {
+		panic(fmt.Sprintf("kdf: invalid key length %d", length))
+	}
+	r := hkdf.New(newHash, secret, nil, []byte(purpose))
+	key := make([]byte, length)
+	if _, err := r.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kdf
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestDerive(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x42}, 32)
+
+	key := Derive(secret, "encryption", 32)
+	require.Len(t, key, 32)
+	require.Equal(t, key, Derive(secret, "encryption", 32))
+
+	// HKDF-BLAKE2b-256 with the purpose as the info string.
+	want := make([]byte, 32)
+	_, err := io.ReadFull(hkdf.New(newHash, secret, nil, []byte("encryption")), want)
+	require.NoError(t, err)
+	require.Equal(t, want, key)
+
+	require.NotEqual(t, key, Derive(secret, "mac", 32))
+	require.NotEqual(t, key, Derive(secret, "encryption ", 32))
+	require.NotEqual(t, key, Derive(bytes.Repeat([]byte{0x43}, 32), "encryption", 32))
+
+	require.Len(t, Derive(secret, "long", MaxLength), MaxLength)
+	require.Panics(t, func() { Derive(secret, "", 32) })
+	require.Panics(t, func() { Derive(secret, "encryption", 0) })
+	require.Panics(t, func() { Derive(secret, "encryption", MaxLength+1) })
+}
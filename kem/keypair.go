@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const keypairChecksumSize = blake2b.Size256
+
+var (
+	// ErrKeypairEncoding is returned by UnmarshalKeypair if the blob is
+	// truncated or otherwise malformed.
+	ErrKeypairEncoding = errors.New("kem: malformed keypair encoding")
+
+	// ErrKeypairChecksum is returned by UnmarshalKeypair if the
+	// checksum doesn't match, which usually means the blob was
+	// corrupted.
+	ErrKeypairChecksum = errors.New("kem: keypair checksum mismatch")
+
+	// ErrKeypairScheme is returned by UnmarshalKeypair if the blob
+	// holds keys for a different scheme.
+	ErrKeypairScheme = errors.New("kem: keypair is for a different scheme")
+
+	// ErrKeypairMismatch is returned if the public key doesn't belong
+	// to the private key.
+	ErrKeypairMismatch = errors.New("kem: public key does not match private key")
+)
+
+// MarshalKeypair serializes a public and private key into a single blob
+// suitable for backups. The encoding is
+//
+//	len(name) as uint16 || name || len(pk) as uint32 || pk ||
+//	len(sk) as uint32 || sk || BLAKE2b-256 of everything before
+//
+// with lengths in big endian, where name is the scheme name, so that
+// corruption of either key or the name is detected by UnmarshalKeypair.
+// Note that the private key is not encrypted.
+func MarshalKeypair(pk PublicKey, sk PrivateKey) ([]byte, error) {
+	if pk.Scheme() != sk.Scheme() {
+		return nil, ErrTypeMismatch
+	}
+	if !sk.Public().Equal(pk) {
+		return nil, ErrKeypairMismatch
+	}
+	name := pk.Scheme().Name()
+	if len(name) > 0xffff {
+		return nil, ErrKeypairEncoding
+	}
+	pkBlob, err := pk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	skBlob, err := sk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 2+len(name)+4+len(pkBlob)+4+len(skBlob)+keypairChecksumSize)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(name)))
+	out = append(out, name...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(pkBlob)))
+	out = append(out, pkBlob...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(skBlob)))
+	out = append(out, skBlob...)
+	sum := blake2b.Sum256(out)
+	return append(out, sum[:]...), nil
+}
+
+// UnmarshalKeypair deserializes a blob created by MarshalKeypair,
+// verifying the checksum, that the blob is for the given scheme and that
+// the keys belong together.
+func UnmarshalKeypair(s Scheme, b []byte) (PublicKey, PrivateKey, error) {
+	if len(b) < 2+4+4+keypairChecksumSize {
+		return nil, nil, ErrKeypairEncoding
+	}
+	body := b[:len(b)-keypairChecksumSize]
+	sum := blake2b.Sum256(body)
+	if !hmac.Equal(sum[:], b[len(body):]) {
+		return nil, nil, ErrKeypairChecksum
+	}
+
+	nameLen := int(binary.BigEndian.Uint16(body))
+	body = body[2:]
+	if len(body) < nameLen {
+		return nil, nil, ErrKeypairEncoding
+	}
+	name := string(body[:nameLen])
+	body = body[nameLen:]
+	if name != s.Name() {
+		return nil, nil, fmt.Errorf("%w: %q != %q", ErrKeypairScheme, name, s.Name())
+	}
+
+	pkBlob, body, ok := readBlob(body)
+	if !ok {
+		return nil, nil, ErrKeypairEncoding
+	}
+	skBlob, body, ok := readBlob(body)
+	if !ok || len(body) != 0 {
+		return nil, nil, ErrKeypairEncoding
+	}
+
+	pk, err := s.UnmarshalBinaryPublicKey(pkBlob)
+	if err != nil {
+		return nil, nil, err
+	}
+	sk, err := s.UnmarshalBinaryPrivateKey(skBlob)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sk.Public().Equal(pk) {
+		return nil, nil, ErrKeypairMismatch
+	}
+	return pk, sk, nil
+}
+
+// readBlob splits a uint32 length prefixed blob off the front of b.
+func readBlob(b []byte) (blob, rest []byte, ok bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	if uint64(len(b)) < uint64(n) {
+		return nil, nil, false
+	}
+	return b[:n], b[n:], true
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestMarshalKeypair(t *testing.T) {
+	s := combiner.New("X25519-X448", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		adapter.FromNIKE(x448.Scheme(rand.Reader)),
+	})
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	blob, err := kem.MarshalKeypair(pubKey, privKey)
+	require.NoError(t, err)
+
+	pubKey2, privKey2, err := kem.UnmarshalKeypair(s, blob)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+	require.True(t, privKey.Equal(privKey2))
+
+	// flipping any bit, including in the name, is detected
+	for _, i := range []int{0, 5, len(blob) / 2, len(blob) - 1} {
+		corrupt := append([]byte{}, blob...)
+		corrupt[i] ^= 0x01
+		_, _, err = kem.UnmarshalKeypair(s, corrupt)
+		require.ErrorIs(t, err, kem.ErrKeypairChecksum)
+	}
+
+	_, _, err = kem.UnmarshalKeypair(s, blob[:10])
+	require.ErrorIs(t, err, kem.ErrKeypairEncoding)
+
+	other := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	_, _, err = kem.UnmarshalKeypair(other, blob)
+	require.ErrorIs(t, err, kem.ErrKeypairScheme)
+
+	pubKey3, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	_, err = kem.MarshalKeypair(pubKey3, privKey)
+	require.ErrorIs(t, err, kem.ErrKeypairMismatch)
+}
@@ -0,0 +1,292 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package keytransfer splits large KEM public keys, such as those of
+// Classic McEliece, into individually verified chunks, so that a
+// transfer over an unreliable link can be resumed by fetching only the
+// chunks which are still missing.
+//
+// Every chunk carries the hash of the whole key, which the receiver is
+// expected to have learned from a trusted source, and its own hash
+// binding its data to that key and to its position. A corrupted chunk is
+// rejected on arrival, and the reassembled key is checked against the
+// whole key hash before it is used. The chunk hashes only detect
+// corruption; authenticity comes from the whole key hash.
+package keytransfer
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+const (
+	// HashSize is the size of the key and chunk hashes.
+	HashSize = blake2b.Size256
+
+	// headerSize is the size of a marshaled chunk without its data.
+	headerSize = 4 + 4 + HashSize + HashSize
+
+	keyHashLabel   = "hpqc keytransfer key v1"
+	chunkHashLabel = "hpqc keytransfer chunk v1"
+)
+
+var (
+	// ErrChunkSize is returned by Chunker for chunk sizes which aren't
+	// positive.
+	ErrChunkSize = errors.New("keytransfer: invalid chunk size")
+
+	// ErrInvalidChunk is returned for chunks which are malformed or
+	// don't belong to the key being reassembled.
+	ErrInvalidChunk = errors.New("keytransfer: invalid chunk")
+
+	// ErrChunkHash is returned for chunks whose data doesn't match
+	// their hash.
+	ErrChunkHash = errors.New("keytransfer: chunk hash mismatch")
+
+	// ErrKeyHash is returned if the reassembled key doesn't match the
+	// whole key hash.
+	ErrKeyHash = errors.New("keytransfer: key hash mismatch")
+
+	// ErrIncomplete is returned by Reassembler.PublicKey while chunks
+	// are still missing.
+	ErrIncomplete = errors.New("keytransfer: chunks missing")
+)
+
+// Chunk is one piece of a public key in transit.
+type Chunk struct {
+	// Index is the position of the chunk, from 0 to Total-1.
+	Index uint32
+
+	// Total is the number of chunks the key was split into.
+	Total uint32
+
+	// KeyHash is the hash of the whole key, as returned by KeyHash.
+	KeyHash [HashSize]byte
+
+	// Hash is the hash of the chunk's data bound to KeyHash, Index and
+	// Total.
+	Hash [HashSize]byte
+
+	// Data is the chunk's part of the marshaled key.
+	Data []byte
+}
+
+// MarshalBinary encodes the chunk as
+//
+//	Index || Total || KeyHash || Hash || Data
+//
+// with the integers as big endian uint32.
+func (c *Chunk) MarshalBinary() ([]byte, error) {
+	b := make([]byte, headerSize, headerSize+len(c.Data))
+	binary.BigEndian.PutUint32(b[0:4], c.Index)
+	binary.BigEndian.PutUint32(b[4:8], c.Total)
+	copy(b[8:8+HashSize], c.KeyHash[:])
+	copy(b[8+HashSize:headerSize], c.Hash[:])
+	return append(b, c.Data...), nil
+}
+
+// UnmarshalBinary decodes a chunk encoded by MarshalBinary. It doesn't
+// verify the chunk, which Reassembler.Add does.
+func (c *Chunk) UnmarshalBinary(b []byte) error {
+	if len(b) <= headerSize {
+		return ErrInvalidChunk
+	}
+	c.Index = binary.BigEndian.Uint32(b[0:4])
+	c.Total = binary.BigEndian.Uint32(b[4:8])
+	copy(c.KeyHash[:], b[8:8+HashSize])
+	copy(c.Hash[:], b[8+HashSize:headerSize])
+	c.Data = append([]byte{}, b[headerSize:]...)
+	return nil
+}
+
+// KeyHash returns the hash identifying the given public key, which
+// is the BLAKE2b-256 hash of
+//
+//	label || uint32(len(name)) || name || pk
+//
+// where name is the scheme name, the length is big endian and pk is the
+// marshaled key.
+func KeyHash(pk kem.PublicKey) ([HashSize]byte, error) {
+	blob, err := pk.MarshalBinary()
+	if err != nil {
+		return [HashSize]byte{}, err
+	}
+	return keyHash(pk.Scheme().Name(), blob), nil
+}
+
+func keyHash(name string, blob []byte) [HashSize]byte {
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		panic(err)
+	}
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(name)))
+	h.Write([]byte(keyHashLabel))
+	h.Write(l[:])
+	h.Write([]byte(name))
+	h.Write(blob)
+	var sum [HashSize]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func chunkHash(keyHash *[HashSize]byte, index, total uint32, data []byte) [HashSize]byte {
+	h, err := blake2b.New256(nil)
+	if err != nil {
+		panic(err)
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[0:4], index)
+	binary.BigEndian.PutUint32(b[4:8], total)
+	h.Write([]byte(chunkHashLabel))
+	h.Write(keyHash[:])
+	h.Write(b[:])
+	h.Write(data)
+	var sum [HashSize]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Chunker splits the marshaled public key into chunks of chunkSize
+// bytes, the last of which may be shorter. The chunks can be sent in
+// any order, and sent again, and are put back together by a
+// Reassembler.
+func Chunker(pk kem.PublicKey, chunkSize int) ([]Chunk, error) {
+	if chunkSize <= 0 {
+		return nil, ErrChunkSize
+	}
+	blob, err := pk.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) == 0 {
+		return nil, ErrInvalidChunk
+	}
+	n := (len(blob) + chunkSize - 1) / chunkSize
+	if uint64(n) > 0xffffffff {
+		return nil, ErrChunkSize
+	}
+	total := uint32(n)
+	kh := keyHash(pk.Scheme().Name(), blob)
+	chunks := make([]Chunk, n)
+	for i := range chunks {
+		end := (i + 1) * chunkSize
+		if end > len(blob) {
+			end = len(blob)
+		}
+		data := append([]byte{}, blob[i*chunkSize:end]...)
+		chunks[i] = Chunk{
+			Index:   uint32(i),
+			Total:   total,
+			KeyHash: kh,
+			Hash:    chunkHash(&kh, uint32(i), total, data),
+			Data:    data,
+		}
+	}
+	return chunks, nil
+}
+
+// Reassembler collects the chunks of one public key, as produced by
+// Chunker, verifying each one as it arrives. It isn't safe for
+// concurrent use.
+type Reassembler struct {
+	scheme  kem.Scheme
+	keyHash [HashSize]byte
+	total   uint32
+	chunks  [][]byte
+	have    int
+	size    int
+}
+
+// NewReassembler returns a Reassembler for the public key of the given
+// scheme whose KeyHash is keyHash.
+func NewReassembler(scheme kem.Scheme, keyHash [HashSize]byte) *Reassembler {
+	if scheme == nil {
+		panic("keytransfer: KEM scheme cannot be nil")
+	}
+	return &Reassembler{
+		scheme:  scheme,
+		keyHash: keyHash,
+	}
+}
+
+// Add verifies the chunk and keeps it. Chunks already received are
+// ignored, so it is safe to add the same chunk again after a resumed
+// transfer. It returns ErrInvalidChunk if the chunk isn't for the
+// expected key or is inconsistent with the chunks received so far, and
+// ErrChunkHash if its data is corrupted.
+func (r *Reassembler) Add(c *Chunk) error {
+	if !hmac.Equal(c.KeyHash[:], r.keyHash[:]) {
+		return fmt.Errorf("%w: chunk is for another key", ErrInvalidChunk)
+	}
+	if c.Total == 0 || c.Index >= c.Total || uint64(c.Total) > uint64(r.scheme.PublicKeySize()) {
+		return fmt.Errorf("%w: index %d of %d", ErrInvalidChunk, c.Index, c.Total)
+	}
+	if r.chunks != nil && c.Total != r.total {
+		return fmt.Errorf("%w: expected %d chunks got %d", ErrInvalidChunk, r.total, c.Total)
+	}
+	sum := chunkHash(&r.keyHash, c.Index, c.Total, c.Data)
+	if !hmac.Equal(sum[:], c.Hash[:]) {
+		return fmt.Errorf("%w: chunk %d", ErrChunkHash, c.Index)
+	}
+	if r.chunks != nil && r.chunks[c.Index] != nil {
+		return nil
+	}
+	if len(c.Data) == 0 || r.size+len(c.Data) > r.scheme.PublicKeySize() {
+		return fmt.Errorf("%w: chunks don't fit the key size", ErrInvalidChunk)
+	}
+	if r.chunks == nil {
+		r.total = c.Total
+		r.chunks = make([][]byte, c.Total)
+	}
+	r.chunks[c.Index] = append([]byte{}, c.Data...)
+	r.have++
+	r.size += len(c.Data)
+	return nil
+}
+
+// Missing returns the indexes of the chunks which haven't been received
+// yet, which is what a resumed transfer needs to fetch. It returns nil
+// before the first chunk is added, since the number of chunks isn't
+// known until then.
+func (r *Reassembler) Missing() []uint32 {
+	if r.chunks == nil {
+		return nil
+	}
+	missing := []uint32{}
+	for i, data := range r.chunks {
+		if data == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+	return missing
+}
+
+// Done returns true once every chunk has been received.
+func (r *Reassembler) Done() bool {
+	return r.chunks != nil && r.have == len(r.chunks)
+}
+
+// PublicKey joins the chunks, checks the result against the whole key
+// hash and unmarshals it. It returns ErrIncomplete if chunks are still
+// missing and ErrKeyHash if the key doesn't match the hash.
+func (r *Reassembler) PublicKey() (kem.PublicKey, error) {
+	if !r.Done() {
+		return nil, ErrIncomplete
+	}
+	blob := make([]byte, 0, r.size)
+	for _, data := range r.chunks {
+		blob = append(blob, data...)
+	}
+	sum := keyHash(r.scheme.Name(), blob)
+	if !hmac.Equal(sum[:], r.keyHash[:]) {
+		return nil, ErrKeyHash
+	}
+	return r.scheme.UnmarshalBinaryPublicKey(blob)
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package keytransfer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+)
+
+func testKey(t *testing.T) (kem.PublicKey, [HashSize]byte) {
+	pk, _, err := mlkem768.Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	kh, err := KeyHash(pk)
+	require.NoError(t, err)
+	return pk, kh
+}
+
+func TestChunkerReassembler(t *testing.T) {
+	pk, kh := testKey(t)
+	chunks, err := Chunker(pk, 500)
+	require.NoError(t, err)
+	require.Len(t, chunks, (mlkem768.PublicKeySize+499)/500)
+
+	r := NewReassembler(mlkem768.Scheme(), kh)
+	require.Nil(t, r.Missing())
+	require.False(t, r.Done())
+
+	// An interrupted transfer which delivered the last chunk and then
+	// resumed with the missing ones.
+	last := chunks[len(chunks)-1]
+	require.NoError(t, r.Add(&last))
+	_, err = r.PublicKey()
+	require.ErrorIs(t, err, ErrIncomplete)
+	missing := r.Missing()
+	require.Len(t, missing, len(chunks)-1)
+	for _, i := range missing {
+		blob, err := chunks[i].MarshalBinary()
+		require.NoError(t, err)
+		c := new(Chunk)
+		require.NoError(t, c.UnmarshalBinary(blob))
+		require.NoError(t, r.Add(c))
+	}
+	require.NoError(t, r.Add(&chunks[0]))
+	require.True(t, r.Done())
+	require.Empty(t, r.Missing())
+
+	pk2, err := r.PublicKey()
+	require.NoError(t, err)
+	require.True(t, pk.Equal(pk2))
+}
+
+func TestReassemblerRejects(t *testing.T) {
+	pk, kh := testKey(t)
+	chunks, err := Chunker(pk, 256)
+	require.NoError(t, err)
+
+	r := NewReassembler(mlkem768.Scheme(), kh)
+	corrupted := chunks[1]
+	corrupted.Data = append([]byte{}, corrupted.Data...)
+	corrupted.Data[0] ^= 1
+	require.ErrorIs(t, r.Add(&corrupted), ErrChunkHash)
+
+	other, otherHash := testKey(t)
+	otherChunks, err := Chunker(other, 256)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.Add(&otherChunks[0]), ErrInvalidChunk)
+
+	outOfRange := chunks[0]
+	outOfRange.Index = outOfRange.Total
+	require.ErrorIs(t, r.Add(&outOfRange), ErrInvalidChunk)
+
+	// Chunks of the right key but a different chunk size.
+	require.NoError(t, r.Add(&chunks[0]))
+	resized, err := Chunker(pk, 100)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.Add(&resized[1]), ErrInvalidChunk)
+
+	// The whole key hash catches a reassembled key which was never
+	// the expected one.
+	r = NewReassembler(mlkem768.Scheme(), otherHash)
+	for i := range chunks {
+		c := chunks[i]
+		c.KeyHash = otherHash
+		c.Hash = chunkHash(&otherHash, c.Index, c.Total, c.Data)
+		require.NoError(t, r.Add(&c))
+	}
+	_, err = r.PublicKey()
+	require.ErrorIs(t, err, ErrKeyHash)
+
+	_, err = Chunker(pk, 0)
+	require.ErrorIs(t, err, ErrChunkSize)
+	require.ErrorIs(t, new(Chunk).UnmarshalBinary(make([]byte, headerSize)), ErrInvalidChunk)
+}
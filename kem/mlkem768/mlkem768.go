@@ -6,12 +6,12 @@ package mlkem768
 
 import (
 	"crypto/hmac"
-	"errors"
 
 	"filippo.io/mlkem768"
 
 	"github.com/katzenpost/hpqc/kem"
 	"github.com/katzenpost/hpqc/kem/pem"
+	"github.com/katzenpost/hpqc/util"
 )
 
 const (
@@ -25,6 +25,7 @@ const (
 // tell the type checker that we obey these interfaces
 var _ kem.Scheme = (*scheme)(nil)
 var _ kem.PublicKey = (*PublicKey)(nil)
+var _ kem.MarshalerTo = (*PublicKey)(nil)
 var _ kem.PrivateKey = (*PrivateKey)(nil)
 
 var sch kem.Scheme = &scheme{}
@@ -49,6 +50,15 @@ func (p *PublicKey) MarshalBinary() ([]byte, error) {
 	return p.encapKey, nil
 }
 
+// MarshalBinaryTo copies the public key into dst and returns the number
+// of bytes written.
+func (p *PublicKey) MarshalBinaryTo(dst []byte) (int, error) {
+	if len(dst) < len(p.encapKey) {
+		return 0, kem.ErrPubKeySize
+	}
+	return copy(dst, p.encapKey), nil
+}
+
 func (p *PublicKey) Equal(pubkey kem.PublicKey) bool {
 	if pubkey.(*PublicKey).scheme != p.scheme {
 		return false
@@ -77,6 +87,11 @@ func (p *PrivateKey) Equal(privkey kem.PrivateKey) bool {
 	return hmac.Equal(privkey.(*PrivateKey).decapKey, p.decapKey)
 }
 
+// Reset zeroizes the decapsulation key.
+func (p *PrivateKey) Reset() {
+	util.ExplicitBzero(p.decapKey)
+}
+
 func (p *PrivateKey) Public() kem.PublicKey {
 	return &PublicKey{
 		encapKey: p.encapKey,
@@ -107,16 +122,38 @@ func (a *scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
 }
 
 func (s *scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
-	return mlkem768.Encapsulate(pk.(*PublicKey).encapKey)
+	pub, ok := pk.(*PublicKey)
+	if !ok {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+	return mlkem768.Encapsulate(pub.encapKey)
 }
 
 func (s *scheme) Decapsulate(myPrivkey kem.PrivateKey, ct []byte) ([]byte, error) {
-	return mlkem768.Decapsulate(myPrivkey.(*PrivateKey).decapKey, ct)
+	if len(ct) != CiphertextSize {
+		return nil, kem.CiphertextSizeError(CiphertextSize, len(ct))
+	}
+	priv, ok := myPrivkey.(*PrivateKey)
+	if !ok {
+		return nil, kem.ErrTypeMismatch
+	}
+	return mlkem768.Decapsulate(priv.decapKey, ct)
+}
+
+// ImplicitRejection returns true because ML-KEM decapsulation
+// never fails on a correctly sized ciphertext.
+func (s *scheme) ImplicitRejection() bool {
+	return true
+}
+
+// Capabilities returns the capability flags of ML-KEM-768.
+func (s *scheme) Capabilities() kem.Caps {
+	return kem.CapPostQuantum | kem.CapImplicitReject | kem.CapDeterministicKeygen
 }
 
 func (s *scheme) UnmarshalBinaryPublicKey(b []byte) (kem.PublicKey, error) {
 	if len(b) != PublicKeySize {
-		return nil, errors.New("wrong key size")
+		return nil, kem.PubKeySizeError(PublicKeySize, len(b))
 	}
 	return &PublicKey{
 		scheme:   s,
@@ -126,12 +163,15 @@ func (s *scheme) UnmarshalBinaryPublicKey(b []byte) (kem.PublicKey, error) {
 
 func (s *scheme) UnmarshalBinaryPrivateKey(b []byte) (kem.PrivateKey, error) {
 	if len(b) != PrivateKeySize {
-		return nil, errors.New("wrong key size")
+		return nil, kem.PrivKeySizeError(PrivateKeySize, len(b))
 	}
+	// copy so that callers may zeroize b
+	key := make([]byte, PrivateKeySize)
+	copy(key, b)
 	return &PrivateKey{
 		scheme:   s,
-		decapKey: b[:mlkem768.DecapsulationKeySize],
-		encapKey: b[mlkem768.DecapsulationKeySize:],
+		decapKey: key[:mlkem768.DecapsulationKeySize:mlkem768.DecapsulationKeySize],
+		encapKey: key[mlkem768.DecapsulationKeySize:],
 	}, nil
 }
 
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package mlkem768
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testdata/vectors.json holds the test vectors shipped with
+// filippo.io/mlkem768, which implements the August 2023 FIPS 203 draft.
+// They comprise the NIST intermediate values, the pq-crystals and
+// post-quantum-cryptography/KAT vectors and the C2SP CCTV edge cases,
+// each group citing its source. The final FIPS 203 changed key
+// generation, so its KATs won't match until the dependency is updated,
+// at which point these vectors must be replaced too.
+
+// loadVectors returns the named groups of hex encoded test vectors.
+func loadVectors(t *testing.T) map[string]map[string]string {
+	blob, err := os.ReadFile("testdata/vectors.json")
+	require.NoError(t, err)
+	vectors := make(map[string]map[string]string)
+	require.NoError(t, json.Unmarshal(blob, &vectors))
+	return vectors
+}
+
+func vector(t *testing.T, group map[string]string, name string) []byte {
+	h, ok := group[name]
+	require.True(t, ok, "missing vector %s", name)
+	v, err := hex.DecodeString(h)
+	require.NoError(t, err)
+	return v
+}
+
+// privateKeyFromDK builds our private key encoding, dk || ek, from a
+// FIPS 203 decapsulation key, which contains ek after the K-PKE key.
+func privateKeyFromDK(t *testing.T, dk []byte) []byte {
+	const pkeKeySize = 384 * 3
+	require.Len(t, dk, 2400)
+	return append(append([]byte{}, dk...), dk[pkeKeySize:pkeKeySize+PublicKeySize]...)
+}
+
+func TestKeyGenVectors(t *testing.T) {
+	vectors := loadVectors(t)
+	for _, name := range []string{"NIST Key Generation", "post-quantum-cryptography/KAT", "unlucky"} {
+		group := vectors[name]
+		ekName, dkName := "pk", "sk"
+		if name == "NIST Key Generation" {
+			ekName, dkName = "ek", "dk"
+		}
+		seed := append(vector(t, group, "d"), vector(t, group, "z")...)
+
+		pk, sk := Scheme().DeriveKeyPair(seed)
+		pkBytes, err := pk.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, vector(t, group, ekName), pkBytes, name)
+		skBytes, err := sk.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, privateKeyFromDK(t, vector(t, group, dkName)), skBytes, name)
+	}
+
+	// The pq-crystals vector gives the key generation coins as d || z.
+	group := vectors["PQCrystals"]
+	pk, _ := Scheme().DeriveKeyPair(vector(t, group, "Coins")[:SeedSize])
+	pkBytes, err := pk.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, vector(t, group, "Public Key"), pkBytes)
+}
+
+func TestDecapsulationVectors(t *testing.T) {
+	vectors := loadVectors(t)
+	cases := []struct {
+		group, sk, ct, ss string
+	}{
+		{"NIST Decapsulation", "dk", "ct", "k"},
+		{"PQCrystals", "Secret Key", "Ciphertext", "Shared Secret"},
+		{"post-quantum-cryptography/KAT", "sk", "ct", "ss"},
+		// Implicit rejection of invalid ciphertexts.
+		{"PQCrystals", "Secret Key", "Pseudorandom Ciphertext", "Pseudorandom Shared Secret"},
+		{"post-quantum-cryptography/KAT", "sk", "ct_n", "ss_n"},
+		{"unlucky", "sk", "ct", "ss"},
+		{"strcmp", "sk", "ct", "ss"},
+	}
+	for _, c := range cases {
+		group := vectors[c.group]
+		sk, err := Scheme().UnmarshalBinaryPrivateKey(privateKeyFromDK(t, vector(t, group, c.sk)))
+		require.NoError(t, err)
+		ss, err := Scheme().Decapsulate(sk, vector(t, group, c.ct))
+		require.NoError(t, err)
+		require.Equal(t, vector(t, group, c.ss), ss, "%s %s", c.group, c.ct)
+	}
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const nonceLabel = "hpqc kem nonce v1"
+
+// DeriveNonce derives a length byte AEAD nonce from the KEM ciphertext,
+// so that one-shot encryption under the encapsulated key needs no nonce
+// state. The nonce is the BLAKE2Xb output, configured for length bytes,
+// of
+//
+//	label || uint32(len(name)) || name || ct
+//
+// where name is the scheme name and the length is big endian.
+//
+// A fresh encapsulation yields a fresh ciphertext and shared secret, so
+// the nonce only repeats under the same key if the same ciphertext is
+// used to encrypt twice, which must be avoided: the nonce is unique per
+// ciphertext, not per message. Nonces from different ciphertexts collide
+// by chance after about 2^(4*length) ciphertexts, but such a collision
+// only matters if the keys collide too, so the usual 12 or 24 byte AEAD
+// nonces are ample. It panics if length isn't positive or is too large
+// for BLAKE2Xb.
+func DeriveNonce(sch Scheme, ct []byte, length int) []byte {
+	if length <= 0 || int64(length) >= 1<<32-1 {
+		panic(fmt.Sprintf("kem: invalid nonce length %d", length))
+	}
+	h, err := blake2b.NewXOF(uint32(length), nil)
+	if err != nil {
+		panic(err)
+	}
+	name := sch.Name()
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(name)))
+	h.Write([]byte(nonceLabel))
+	h.Write(l[:])
+	h.Write([]byte(name))
+	h.Write(ct)
+	nonce := make([]byte, length)
+	if _, err = h.Read(nonce); err != nil {
+		panic(err)
+	}
+	return nonce
+}
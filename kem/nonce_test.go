@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestDeriveNonce(t *testing.T) {
+	s := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	pk, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, _, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+
+	nonce := kem.DeriveNonce(s, ct, 24)
+	require.Len(t, nonce, 24)
+	require.Equal(t, nonce, kem.DeriveNonce(s, ct, 24))
+	require.Len(t, kem.DeriveNonce(s, ct, 100), 100)
+
+	ct2, _, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	require.NotEqual(t, nonce, kem.DeriveNonce(s, ct2, 24))
+
+	// The scheme name is bound into the nonce.
+	other := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	require.NotEqual(t, nonce, kem.DeriveNonce(other, ct, 24))
+
+	require.Panics(t, func() { kem.DeriveNonce(s, ct, 0) })
+}
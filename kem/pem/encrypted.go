@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: Copyright (c) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pem
+
+import (
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/util"
+)
+
+const (
+	encryptedSaltSize = 32
+
+	// minSaltSize is the shortest salt accepted when decrypting.
+	minSaltSize = 16
+
+	// The largest scrypt parameters accepted, so that a crafted PEM
+	// file can't make decryption take unbounded time or memory. scrypt
+	// needs 128*N*R bytes, which the limit on N*R keeps to 1 GiB.
+	maxScryptN  = 1 << 22
+	maxScryptR  = 32
+	maxScryptP  = 16
+	maxScryptNR = 1 << 23
+
+	headerKDF    = "KDF"
+	headerCipher = "Cipher"
+	headerN      = "Scrypt-N"
+	headerR      = "Scrypt-R"
+	headerP      = "Scrypt-P"
+	headerSalt   = "Salt"
+	headerNonce  = "Nonce"
+
+	kdfScrypt        = "scrypt"
+	cipherXChaCha20  = "XChaCha20-Poly1305"
+	encryptedKeyType = "ENCRYPTED %s PRIVATE KEY"
+)
+
+var (
+	// ErrWrongPassphrase is returned when an encrypted private key
+	// fails to authenticate, which almost always means that the wrong
+	// passphrase was given.
+	ErrWrongPassphrase = errors.New("pem: wrong passphrase or corrupted private key")
+
+	// ErrNotEncrypted is returned when the given PEM block is not an
+	// encrypted private key.
+	ErrNotEncrypted = errors.New("pem: private key is not encrypted")
+)
+
+// ScryptParams are the scrypt cost parameters used to derive the key
+// encryption key from a passphrase. N may be at most 1<<22, R at most 32
+// and P at most 16, and the memory scrypt needs, 128*N*R bytes, may be
+// at most 1 GiB.
+type ScryptParams struct {
+	N int
+	R int
+	P int
+}
+
+// DefaultScryptParams are the scrypt parameters recommended for
+// interactive logins, which take roughly 100ms on current hardware.
+var DefaultScryptParams = ScryptParams{
+	N: 1 << 15,
+	R: 8,
+	P: 1,
+}
+
+// ToEncryptedPrivatePEMBytes encrypts the private key with the given
+// passphrase using DefaultScryptParams and XChaCha20-Poly1305 and
+// returns it PEM encoded.
+func ToEncryptedPrivatePEMBytes(key kem.PrivateKey, passphrase []byte) ([]byte, error) {
+	return ToEncryptedPrivatePEMBytesWithParams(key, passphrase, DefaultScryptParams)
+}
+
+// ToEncryptedPrivatePEMBytesWithParams is like ToEncryptedPrivatePEMBytes
+// but uses the given scrypt parameters. The parameters are stored in the
+// PEM headers so they needn't be known when decrypting.
+func ToEncryptedPrivatePEMBytesWithParams(key kem.PrivateKey, passphrase []byte, params ScryptParams) ([]byte, error) {
+	keyType := fmt.Sprintf(encryptedKeyType, strings.ToUpper(key.Scheme().Name()))
+	blob, err := key.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	defer util.ExplicitBzero(blob)
+	if util.CtIsZero(blob) {
+		return nil, fmt.Errorf("ToEncryptedPrivatePEMBytes/%s: attempted to serialize scrubbed key", keyType)
+	}
+
+	salt := make([]byte, encryptedSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := newEncryptedKeyAEAD(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	blk := &pem.Block{
+		Type: keyType,
+		Headers: map[string]string{
+			headerKDF:    kdfScrypt,
+			headerCipher: cipherXChaCha20,
+			headerN:      strconv.Itoa(params.N),
+			headerR:      strconv.Itoa(params.R),
+			headerP:      strconv.Itoa(params.P),
+			headerSalt:   hex.EncodeToString(salt),
+			headerNonce:  hex.EncodeToString(nonce),
+		},
+		Bytes: aead.Seal(nil, nonce, blob, []byte(keyType)),
+	}
+	return pem.EncodeToMemory(blk), nil
+}
+
+// FromEncryptedPrivatePEMBytes decrypts a private key which was encrypted
+// by ToEncryptedPrivatePEMBytes. It returns ErrWrongPassphrase if the key
+// fails to decrypt.
+func FromEncryptedPrivatePEMBytes(b []byte, passphrase []byte, scheme kem.Scheme) (kem.PrivateKey, error) {
+	keyType := fmt.Sprintf(encryptedKeyType, strings.ToUpper(scheme.Name()))
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, fmt.Errorf("failed to decode PEM data from %s PEM", keyType)
+	}
+	if strings.ToUpper(blk.Type) == fmt.Sprintf("%s PRIVATE KEY", strings.ToUpper(scheme.Name())) {
+		return nil, ErrNotEncrypted
+	}
+	if strings.ToUpper(blk.Type) != keyType {
+		return nil, fmt.Errorf("attempted to decode PEM file with wrong key type %v != %v", blk.Type, keyType)
+	}
+	if blk.Headers[headerKDF] != kdfScrypt || blk.Headers[headerCipher] != cipherXChaCha20 {
+		return nil, fmt.Errorf("pem: unsupported private key encryption %s/%s", blk.Headers[headerKDF], blk.Headers[headerCipher])
+	}
+
+	params := ScryptParams{}
+	for _, v := range []struct {
+		name string
+		dst  *int
+	}{
+		{headerN, &params.N},
+		{headerR, &params.R},
+		{headerP, &params.P},
+	} {
+		n, err := strconv.Atoi(blk.Headers[v.name])
+		if err != nil {
+			return nil, fmt.Errorf("pem: invalid %s header: %s", v.name, err)
+		}
+		*v.dst = n
+	}
+	salt, err := hex.DecodeString(blk.Headers[headerSalt])
+	if err != nil {
+		return nil, fmt.Errorf("pem: invalid %s header: %s", headerSalt, err)
+	}
+	if len(salt) < minSaltSize {
+		return nil, fmt.Errorf("pem: invalid %s header length %d", headerSalt, len(salt))
+	}
+	nonce, err := hex.DecodeString(blk.Headers[headerNonce])
+	if err != nil {
+		return nil, fmt.Errorf("pem: invalid %s header: %s", headerNonce, err)
+	}
+	if len(nonce) != chacha20poly1305.NonceSizeX {
+		return nil, fmt.Errorf("pem: invalid %s header length %d", headerNonce, len(nonce))
+	}
+
+	aead, err := newEncryptedKeyAEAD(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	blob, err := aead.Open(nil, nonce, blk.Bytes, []byte(keyType))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	defer util.ExplicitBzero(blob)
+	return scheme.UnmarshalBinaryPrivateKey(blob)
+}
+
+func newEncryptedKeyAEAD(passphrase, salt []byte, params ScryptParams) (cipher.AEAD, error) {
+	if params.N > maxScryptN || params.R > maxScryptR || params.P > maxScryptP ||
+		params.R > 0 && params.N > maxScryptNR/params.R {
+		return nil, fmt.Errorf("pem: scrypt parameters N=%d r=%d p=%d exceed the limits", params.N, params.R, params.P)
+	}
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("pem: scrypt: %s", err)
+	}
+	defer util.ExplicitBzero(key)
+	return chacha20poly1305.NewX(key)
+}
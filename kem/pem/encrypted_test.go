@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: Copyright (c) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pem_test
+
+import (
+	stdpem "encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/kem/pem"
+	"github.com/katzenpost/hpqc/kem/sntrup"
+	"github.com/katzenpost/hpqc/kem/xwing"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestEncryptedPrivatePEM(t *testing.T) {
+	s := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	_, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	params := pem.ScryptParams{N: 1 << 10, R: 8, P: 1}
+	passphrase := []byte("correct horse battery staple")
+
+	blob, err := pem.ToEncryptedPrivatePEMBytesWithParams(privKey, passphrase, params)
+	require.NoError(t, err)
+	require.Contains(t, string(blob), "ENCRYPTED X25519 PRIVATE KEY")
+
+	privKey2, err := pem.FromEncryptedPrivatePEMBytes(blob, passphrase, s)
+	require.NoError(t, err)
+	require.True(t, privKey.Equal(privKey2))
+
+	_, err = pem.FromEncryptedPrivatePEMBytes(blob, []byte("wrong"), s)
+	require.ErrorIs(t, err, pem.ErrWrongPassphrase)
+
+	_, err = pem.FromEncryptedPrivatePEMBytes(pem.ToPrivatePEMBytes(privKey), passphrase, s)
+	require.ErrorIs(t, err, pem.ErrNotEncrypted)
+}
+
+func TestEncryptedPrivatePEMSchemes(t *testing.T) {
+	params := pem.ScryptParams{N: 1 << 10, R: 8, P: 1}
+	passphrase := []byte("correct horse battery staple")
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+
+	// the decrypted key is zeroized once unmarshalled, which must not
+	// affect the returned key
+	for _, s := range []kem.Scheme{
+		mlkem768.Scheme(),
+		xwing.Scheme(),
+		sntrup.Scheme(),
+		combiner.New("MLKEM768-X25519", []kem.Scheme{mlkem768.Scheme(), x25519KEM}),
+	} {
+		_, privKey, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		blob, err := pem.ToEncryptedPrivatePEMBytesWithParams(privKey, passphrase, params)
+		require.NoError(t, err)
+		privKey2, err := pem.FromEncryptedPrivatePEMBytes(blob, passphrase, s)
+		require.NoError(t, err)
+		require.True(t, privKey.Equal(privKey2), s.Name())
+	}
+}
+
+func TestEncryptedPrivatePEMLimits(t *testing.T) {
+	s := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	_, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	passphrase := []byte("correct horse battery staple")
+
+	for _, params := range []pem.ScryptParams{
+		{N: 1 << 23, R: 1, P: 1},
+		{N: 1 << 10, R: 64, P: 1},
+		{N: 1 << 10, R: 8, P: 64},
+		{N: 1 << 22, R: 8, P: 1},
+	} {
+		_, err = pem.ToEncryptedPrivatePEMBytesWithParams(privKey, passphrase, params)
+		require.Error(t, err, "%+v", params)
+	}
+
+	blob, err := pem.ToEncryptedPrivatePEMBytesWithParams(privKey, passphrase, pem.ScryptParams{N: 1 << 10, R: 8, P: 1})
+	require.NoError(t, err)
+	tamper := func(name, value string) []byte {
+		blk, _ := stdpem.Decode(blob)
+		require.NotNil(t, blk)
+		blk.Headers[name] = value
+		return stdpem.EncodeToMemory(blk)
+	}
+	_, err = pem.FromEncryptedPrivatePEMBytes(tamper("Scrypt-N", "1073741824"), passphrase, s)
+	require.ErrorContains(t, err, "exceed the limits")
+	_, err = pem.FromEncryptedPrivatePEMBytes(tamper("Scrypt-P", "1000000"), passphrase, s)
+	require.ErrorContains(t, err, "exceed the limits")
+	_, err = pem.FromEncryptedPrivatePEMBytes(tamper("Salt", strings.Repeat("00", 8)), passphrase, s)
+	require.ErrorContains(t, err, "Salt")
+}
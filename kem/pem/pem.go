@@ -14,11 +14,45 @@ import (
 	"github.com/katzenpost/hpqc/util"
 )
 
+// headerScheme is the optional PEM header holding the name of the key's
+// scheme, which keeps its original case unlike the block type. Only the
+// WithScheme functions write it, so that the blocks written by the
+// others stay the same as those of earlier versions.
+const headerScheme = "Scheme"
+
+// ErrSchemeMismatch is returned when a PEM block's Scheme header names a
+// different scheme than the one the key is being loaded for.
+var ErrSchemeMismatch = errors.New("pem: key scheme header mismatch")
+
+// checkSchemeHeader returns ErrSchemeMismatch if the block has a Scheme
+// header which doesn't match the scheme name, compared case
+// insensitively. Blocks without the header are accepted.
+func checkSchemeHeader(blk *pem.Block, scheme kem.Scheme) error {
+	name, ok := blk.Headers[headerScheme]
+	if ok && !strings.EqualFold(name, scheme.Name()) {
+		return fmt.Errorf("%w: %q != %q", ErrSchemeMismatch, name, scheme.Name())
+	}
+	return nil
+}
+
 func ToPublicPEMString(key kem.PublicKey) string {
 	return string(ToPublicPEMBytes(key))
 }
 
 func ToPublicPEMBytes(key kem.PublicKey) []byte {
+	return pem.EncodeToMemory(publicPEMBlock(key))
+}
+
+// ToPublicPEMBytesWithScheme is like ToPublicPEMBytes, but also writes a
+// Scheme header with the name of the key's scheme in its original case.
+// The From functions check the header if it's present.
+func ToPublicPEMBytesWithScheme(key kem.PublicKey) []byte {
+	blk := publicPEMBlock(key)
+	blk.Headers = map[string]string{headerScheme: key.Scheme().Name()}
+	return pem.EncodeToMemory(blk)
+}
+
+func publicPEMBlock(key kem.PublicKey) *pem.Block {
 	keyType := fmt.Sprintf("%s PUBLIC KEY", strings.ToUpper(key.Scheme().Name()))
 	blob, err := key.MarshalBinary()
 	if err != nil {
@@ -27,11 +61,10 @@ func ToPublicPEMBytes(key kem.PublicKey) []byte {
 	if util.CtIsZero(blob) {
 		panic(fmt.Sprintf("ToPEMString/%s: attempted to serialize scrubbed key", keyType))
 	}
-	blk := &pem.Block{
+	return &pem.Block{
 		Type:  keyType,
 		Bytes: blob,
 	}
-	return pem.EncodeToMemory(blk)
 }
 
 func PublicKeyToFile(f string, key kem.PublicKey) error {
@@ -67,6 +100,9 @@ func FromPublicPEMBytes(b []byte, scheme kem.Scheme) (kem.PublicKey, error) {
 	if strings.ToUpper(blk.Type) != keyType {
 		return nil, fmt.Errorf("attempted to decode PEM file with wrong key type %v != %v", blk.Type, keyType)
 	}
+	if err := checkSchemeHeader(blk, scheme); err != nil {
+		return nil, err
+	}
 	return scheme.UnmarshalBinaryPublicKey(blk.Bytes)
 }
 
@@ -79,6 +115,9 @@ func FromPublicPEMToBytes(b []byte, scheme kem.Scheme) ([]byte, error) {
 	if strings.ToUpper(blk.Type) != keyType {
 		return nil, fmt.Errorf("attempted to decode PEM file with wrong key type %v != %v", blk.Type, keyType)
 	}
+	if err := checkSchemeHeader(blk, scheme); err != nil {
+		return nil, err
+	}
 	return blk.Bytes, nil
 }
 
@@ -97,6 +136,18 @@ func ToPrivatePEMString(key kem.PrivateKey) string {
 }
 
 func ToPrivatePEMBytes(key kem.PrivateKey) []byte {
+	return pem.EncodeToMemory(privatePEMBlock(key))
+}
+
+// ToPrivatePEMBytesWithScheme is like ToPrivatePEMBytes, but also writes
+// a Scheme header as ToPublicPEMBytesWithScheme does.
+func ToPrivatePEMBytesWithScheme(key kem.PrivateKey) []byte {
+	blk := privatePEMBlock(key)
+	blk.Headers = map[string]string{headerScheme: key.Scheme().Name()}
+	return pem.EncodeToMemory(blk)
+}
+
+func privatePEMBlock(key kem.PrivateKey) *pem.Block {
 	keyType := fmt.Sprintf("%s PRIVATE KEY", strings.ToUpper(key.Scheme().Name()))
 	blob, err := key.MarshalBinary()
 	if err != nil {
@@ -105,11 +156,10 @@ func ToPrivatePEMBytes(key kem.PrivateKey) []byte {
 	if util.CtIsZero(blob) {
 		panic(fmt.Sprintf("ToPEMString/%s: attempted to serialize scrubbed key", keyType))
 	}
-	blk := &pem.Block{
+	return &pem.Block{
 		Type:  keyType,
 		Bytes: blob,
 	}
-	return pem.EncodeToMemory(blk)
 }
 
 func PrivateKeyToFile(f string, key kem.PrivateKey) error {
@@ -145,6 +195,9 @@ func FromPrivatePEMBytes(b []byte, scheme kem.Scheme) (kem.PrivateKey, error) {
 	if strings.ToUpper(blk.Type) != keyType {
 		return nil, fmt.Errorf("attempted to decode PEM file with wrong key type %v != %v", blk.Type, keyType)
 	}
+	if err := checkSchemeHeader(blk, scheme); err != nil {
+		return nil, err
+	}
 	return scheme.UnmarshalBinaryPrivateKey(blk.Bytes)
 }
 
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: Copyright (c) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package pem_test
+
+import (
+	stdpem "encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/pem"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestSchemeHeader(t *testing.T) {
+	s := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	// The plain functions don't write the header.
+	require.NotContains(t, string(pem.ToPublicPEMBytes(pubKey)), "Scheme:")
+	require.NotContains(t, string(pem.ToPrivatePEMBytes(privKey)), "Scheme:")
+
+	blob := pem.ToPublicPEMBytesWithScheme(pubKey)
+	require.Contains(t, string(blob), "Scheme: x25519")
+	pubKey2, err := pem.FromPublicPEMBytes(blob, s)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+
+	blob = pem.ToPrivatePEMBytesWithScheme(privKey)
+	require.Contains(t, string(blob), "Scheme: x25519")
+	privKey2, err := pem.FromPrivatePEMBytes(blob, s)
+	require.NoError(t, err)
+	require.True(t, privKey.Equal(privKey2))
+
+	// A block whose type matches but whose header doesn't is rejected.
+	blk, _ := stdpem.Decode(pem.ToPublicPEMBytesWithScheme(pubKey))
+	blk.Headers["Scheme"] = "x448"
+	_, err = pem.FromPublicPEMBytes(stdpem.EncodeToMemory(blk), s)
+	require.ErrorIs(t, err, pem.ErrSchemeMismatch)
+	_, err = pem.FromPublicPEMToBytes(stdpem.EncodeToMemory(blk), s)
+	require.ErrorIs(t, err, pem.ErrSchemeMismatch)
+
+	// Blocks without the header are still accepted.
+	pubKey2, err = pem.FromPublicPEMBytes(pem.ToPublicPEMBytes(pubKey), s)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+	privKey2, err = pem.FromPrivatePEMBytes(pem.ToPrivatePEMBytes(privKey), s)
+	require.NoError(t, err)
+	require.True(t, privKey.Equal(privKey2))
+}
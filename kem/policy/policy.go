@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package policy provides a KEM scheme wrapper which can only be built
+// for schemes meeting a minimum post quantum security level, so that
+// policy is enforced once where schemes are configured rather than at
+// every call site.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/schemes"
+)
+
+var (
+	// ErrNilScheme is returned by Enforce when the scheme is nil.
+	ErrNilScheme = errors.New("policy: KEM scheme cannot be nil")
+
+	// ErrInvalidCategory is returned by Enforce when the minimum category
+	// isn't between 0 and 5.
+	ErrInvalidCategory = errors.New("policy: invalid NIST security category")
+
+	// ErrUnknownScheme is returned by Enforce for schemes we have no
+	// security estimate for.
+	ErrUnknownScheme = errors.New("policy: no security estimate for scheme")
+
+	// ErrPolicyViolation is returned by Enforce for schemes below the
+	// minimum category.
+	ErrPolicyViolation = errors.New("policy: scheme does not meet the minimum security category")
+)
+
+// Scheme is a kem.Scheme which wraps a scheme known to meet a minimum
+// post quantum security category. Sizes, names and marshaling are those
+// of the wrapped scheme, and keys returned by it belong to the wrapped
+// scheme.
+type Scheme struct {
+	scheme   kem.Scheme
+	category int
+}
+
+var _ kem.Scheme = (*Scheme)(nil)
+
+// Category returns the post quantum NIST security category of sch as
+// used by Enforce: the estimate from schemes.SecurityLevel for schemes
+// with a post quantum component, and 0 for classical only schemes. Note
+// that hybrids are rated by their strongest component, which for the
+// registered schemes is always the post quantum one. It returns
+// ErrUnknownScheme if there is no estimate for the scheme.
+func Category(sch kem.Scheme) (int, error) {
+	level, ok := schemes.SecurityLevel(sch)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownScheme, sch.Name())
+	}
+	if !schemes.Capabilities(sch).Has(kem.CapPostQuantum) {
+		return 0, nil
+	}
+	return level, nil
+}
+
+// Enforce returns a scheme which behaves like sch if sch is at least of
+// post quantum NIST security category minPQCategory, as computed by
+// Category, and an error otherwise. A minPQCategory of 0 admits
+// classical schemes, provided we have an estimate for them. It returns
+// ErrNilScheme if sch is nil.
+//
+// The policy is only checked here, when the wrapper is built. Its
+// Encapsulate and Decapsulate never refuse an operation on policy
+// grounds; they only refuse keys of other schemes with
+// kem.ErrTypeMismatch, so that keys of a weaker scheme can't slip past
+// the wrapper.
+func Enforce(sch kem.Scheme, minPQCategory int) (kem.Scheme, error) {
+	if sch == nil {
+		return nil, ErrNilScheme
+	}
+	if minPQCategory < 0 || minPQCategory > 5 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCategory, minPQCategory)
+	}
+	category, err := Category(sch)
+	if err != nil {
+		return nil, err
+	}
+	if category < minPQCategory {
+		return nil, fmt.Errorf("%w: %s is category %d, need %d", ErrPolicyViolation, sch.Name(), category, minPQCategory)
+	}
+	return &Scheme{
+		scheme:   sch,
+		category: category,
+	}, nil
+}
+
+// Unwrap returns the wrapped scheme.
+func (s *Scheme) Unwrap() kem.Scheme {
+	return s.scheme
+}
+
+// Category returns the post quantum NIST security category of the
+// wrapped scheme.
+func (s *Scheme) Category() int {
+	return s.category
+}
+
+// Name returns the name of the wrapped scheme.
+func (s *Scheme) Name() string {
+	return s.scheme.Name()
+}
+
+// GenerateKeyPair creates a new key pair.
+func (s *Scheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	return s.scheme.GenerateKeyPair()
+}
+
+// DeriveKeyPair deterministically derives a pair of keys from a seed.
+func (s *Scheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
+	return s.scheme.DeriveKeyPair(seed)
+}
+
+// Encapsulate generates a shared key ss for the public key and
+// encapsulates it into a ciphertext ct. It returns kem.ErrTypeMismatch
+// if the key isn't of the wrapped scheme.
+func (s *Scheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	if pk == nil || !s.owns(pk.Scheme()) {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+	return s.scheme.Encapsulate(pk)
+}
+
+// Decapsulate returns the shared key encapsulated in ciphertext ct for
+// the private key sk. It returns kem.ErrTypeMismatch if the key isn't
+// of the wrapped scheme.
+func (s *Scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+	if sk == nil || !s.owns(sk.Scheme()) {
+		return nil, kem.ErrTypeMismatch
+	}
+	return s.scheme.Decapsulate(sk, ct)
+}
+
+// ImplicitRejection reports whether the wrapped scheme uses implicit
+// rejection.
+func (s *Scheme) ImplicitRejection() bool {
+	return kem.ImplicitRejection(s.scheme)
+}
+
+// Circl returns the circl scheme underneath the wrapped scheme, if any,
+// as described for kem.Circl. It is unstable and meant for advanced use.
+func (s *Scheme) Circl() interface{} {
+	return kem.Circl(s.scheme)
+}
+
+// Capabilities returns the capability flags of the wrapped scheme.
+func (s *Scheme) Capabilities() kem.Caps {
+	return kem.Capabilities(s.scheme)
+}
+
+// UnmarshalBinaryPublicKey unmarshals a PublicKey from the provided buffer.
+func (s *Scheme) UnmarshalBinaryPublicKey(b []byte) (kem.PublicKey, error) {
+	return s.scheme.UnmarshalBinaryPublicKey(b)
+}
+
+// UnmarshalBinaryPrivateKey unmarshals a PrivateKey from the provided buffer.
+func (s *Scheme) UnmarshalBinaryPrivateKey(b []byte) (kem.PrivateKey, error) {
+	return s.scheme.UnmarshalBinaryPrivateKey(b)
+}
+
+// UnmarshalTextPublicKey unmarshals a PublicKey from the provided text.
+func (s *Scheme) UnmarshalTextPublicKey(text []byte) (kem.PublicKey, error) {
+	return s.scheme.UnmarshalTextPublicKey(text)
+}
+
+// UnmarshalTextPrivateKey unmarshals a PrivateKey from the provided text.
+func (s *Scheme) UnmarshalTextPrivateKey(text []byte) (kem.PrivateKey, error) {
+	return s.scheme.UnmarshalTextPrivateKey(text)
+}
+
+// CiphertextSize returns the wrapped scheme's ciphertext size.
+func (s *Scheme) CiphertextSize() int {
+	return s.scheme.CiphertextSize()
+}
+
+// SharedKeySize returns the wrapped scheme's shared key size.
+func (s *Scheme) SharedKeySize() int {
+	return s.scheme.SharedKeySize()
+}
+
+// PrivateKeySize returns the wrapped scheme's private key size.
+func (s *Scheme) PrivateKeySize() int {
+	return s.scheme.PrivateKeySize()
+}
+
+// PublicKeySize returns the wrapped scheme's public key size.
+func (s *Scheme) PublicKeySize() int {
+	return s.scheme.PublicKeySize()
+}
+
+// SeedSize returns the wrapped scheme's key derivation seed size.
+func (s *Scheme) SeedSize() int {
+	return s.scheme.SeedSize()
+}
+
+// owns returns true if keys of sch belong to the wrapped scheme. Names
+// are compared since wrappers such as this one hand out the keys of the
+// scheme they wrap.
+func (s *Scheme) owns(sch kem.Scheme) bool {
+	return sch != nil && strings.EqualFold(sch.Name(), s.scheme.Name())
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/schemes"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestEnforce(t *testing.T) {
+	inner := schemes.ByName("MLKEM768-X25519")
+	s, err := Enforce(inner, 3)
+	require.NoError(t, err)
+	require.Equal(t, 3, s.(*Scheme).Category())
+	require.Equal(t, inner.Name(), s.Name())
+	require.Equal(t, inner.CiphertextSize(), s.CiphertextSize())
+
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(privKey, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	// Keys of another scheme are refused.
+	weak := schemes.ByName("x25519")
+	weakPubKey, weakPrivKey, err := weak.GenerateKeyPair()
+	require.NoError(t, err)
+	_, _, err = s.Encapsulate(weakPubKey)
+	require.ErrorIs(t, err, kem.ErrTypeMismatch)
+	_, err = s.Decapsulate(weakPrivKey, ct)
+	require.ErrorIs(t, err, kem.ErrTypeMismatch)
+}
+
+func TestEnforceRejects(t *testing.T) {
+	_, err := Enforce(schemes.ByName("MLKEM768-X25519"), 5)
+	require.ErrorIs(t, err, ErrPolicyViolation)
+
+	// Classical schemes are category 0 whatever their classical strength.
+	_, err = Enforce(schemes.ByName("x448"), 1)
+	require.ErrorIs(t, err, ErrPolicyViolation)
+	_, err = Enforce(schemes.ByName("x448"), 0)
+	require.NoError(t, err)
+
+	_, err = Enforce(schemes.ByName("mceliece6688128-X25519"), 5)
+	require.NoError(t, err)
+
+	unknown := combiner.New("X25519-X448", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		adapter.FromNIKE(x448.Scheme(rand.Reader)),
+	})
+	_, err = Enforce(unknown, 0)
+	require.ErrorIs(t, err, ErrUnknownScheme)
+
+	_, err = Enforce(schemes.ByName("MLKEM768"), 6)
+	require.ErrorIs(t, err, ErrInvalidCategory)
+	_, err = Enforce(schemes.ByName("MLKEM768"), -1)
+	require.ErrorIs(t, err, ErrInvalidCategory)
+
+	_, err = Enforce(nil, 0)
+	require.ErrorIs(t, err, ErrNilScheme)
+}
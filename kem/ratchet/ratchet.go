@@ -0,0 +1,211 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package ratchet provides a one way KEM ratchet which gives a stream of
+// messages forward secrecy on top of any KEM which derives key pairs
+// deterministically.
+//
+// Each message encapsulates to the receiver's current public key. The
+// shared secret then seeds DeriveKeyPair for the key pair of the next
+// message, and keys the message key and the message's MAC. Once a
+// message has been received, the private key which decapsulated it is
+// erased, so compromising the receiver later reveals none of the earlier
+// message keys. Erasing relies on kem.Resetter, so the ratchet refuses
+// private keys which can't be fully reset, such as circl's Classic
+// McEliece, Kyber and FrodoKEM keys and composite keys containing them.
+//
+// The sender learns every private key the receiver will use, so the
+// ratchet protects nothing against a compromised sender. And since each
+// key pair follows from the previous shared secret, an attacker who
+// learns the receiver's state can follow every later message: the
+// ratchet has no post-compromise security. Messages must be received in
+// the order they were sent, and none may be lost.
+package ratchet
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/kdf"
+	"github.com/katzenpost/hpqc/util"
+)
+
+const (
+	// KeySize is the size of the message keys.
+	KeySize = 32
+
+	// TagSize is the size of the MAC which follows the ciphertext in
+	// each message.
+	TagSize = blake2b.Size256
+
+	seedPurpose = "hpqc kem ratchet v1 seed"
+	keyPurpose  = "hpqc kem ratchet v1 message key"
+	macPurpose  = "hpqc kem ratchet v1 mac"
+)
+
+// ErrInvalidMessage is returned by Receiver.Receive for messages which
+// weren't sent by the matching Sender at the receiver's current step.
+var ErrInvalidMessage = errors.New("ratchet: invalid message")
+
+// Sender sends messages to a Receiver.
+type Sender struct {
+	scheme kem.Scheme
+	pub    kem.PublicKey
+	step   uint64
+}
+
+// NewSender returns a Sender which sends its first message to the given
+// public key, whose private key the Receiver starts from. It returns
+// kem.ErrNotDeriveable if the scheme can't derive key pairs
+// deterministically, and kem.ErrTypeMismatch if the key isn't of the
+// scheme. Whether the scheme's private keys can be reset is checked by
+// NewReceiver only, as the Sender never holds the Receiver's current
+// private key.
+func NewSender(sch kem.Scheme, initialPub kem.PublicKey) (*Sender, error) {
+	if err := checkScheme(sch); err != nil {
+		return nil, err
+	}
+	if initialPub == nil || initialPub.Scheme().Name() != sch.Name() {
+		return nil, kem.ErrTypeMismatch
+	}
+	return &Sender{
+		scheme: sch,
+		pub:    initialPub,
+	}, nil
+}
+
+// Send advances the ratchet and returns the next message, to be passed
+// to the Receiver's Receive, together with its KeySize byte message key.
+func (s *Sender) Send() (msg, key []byte, err error) {
+	ct, ss, err := s.scheme.Encapsulate(s.pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer util.ExplicitBzero(ss)
+	pub, priv, err := nextKeyPair(s.scheme, ss)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the receiver needs the next private key.
+	kem.ResetPrivateKeys(priv)
+
+	s.pub = pub
+	s.step++
+	return append(ct, tag(ss, ct)...), kdf.Derive(ss, keyPurpose, KeySize), nil
+}
+
+// Step returns the number of messages sent so far.
+func (s *Sender) Step() uint64 {
+	return s.step
+}
+
+// MessageSize returns the size of the messages returned by Send.
+func (s *Sender) MessageSize() int {
+	return s.scheme.CiphertextSize() + TagSize
+}
+
+// Receiver receives messages from a Sender.
+type Receiver struct {
+	scheme kem.Scheme
+	priv   kem.PrivateKey
+	step   uint64
+}
+
+// NewReceiver returns a Receiver which decapsulates the first message
+// with the given private key. The Receiver takes ownership of the key
+// and erases it once the first message has been received. It returns
+// kem.ErrNotDeriveable if the scheme can't derive key pairs
+// deterministically, kem.ErrTypeMismatch if the key isn't of the scheme
+// and kem.ErrNotResettable if the key can't be fully erased, which would
+// defeat the forward secrecy of the ratchet.
+func NewReceiver(sch kem.Scheme, initialPriv kem.PrivateKey) (*Receiver, error) {
+	if err := checkScheme(sch); err != nil {
+		return nil, err
+	}
+	if initialPriv == nil || initialPriv.Scheme().Name() != sch.Name() {
+		return nil, kem.ErrTypeMismatch
+	}
+	if !kem.Resettable(initialPriv) {
+		return nil, kem.ErrNotResettable
+	}
+	return &Receiver{
+		scheme: sch,
+		priv:   initialPriv,
+	}, nil
+}
+
+// Receive advances the ratchet with the next message from the Sender
+// and returns its message key. A message which doesn't authenticate
+// under the current private key, because it was corrupted, replayed or
+// delivered out of order, gives an error wrapping ErrInvalidMessage and
+// leaves the Receiver unchanged, so it can still receive the expected
+// message.
+func (r *Receiver) Receive(msg []byte) ([]byte, error) {
+	ctLen := r.scheme.CiphertextSize()
+	if len(msg) != ctLen+TagSize {
+		return nil, fmt.Errorf("%w: got %d bytes, expected %d", ErrInvalidMessage, len(msg), ctLen+TagSize)
+	}
+	ct, msgTag := msg[:ctLen], msg[ctLen:]
+	ss, err := r.scheme.Decapsulate(r.priv, ct)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMessage, err)
+	}
+	defer util.ExplicitBzero(ss)
+	if !hmac.Equal(tag(ss, ct), msgTag) {
+		return nil, ErrInvalidMessage
+	}
+	_, priv, err := nextKeyPair(r.scheme, ss)
+	if err != nil {
+		return nil, err
+	}
+
+	kem.ResetPrivateKeys(r.priv)
+	r.priv = priv
+	r.step++
+	return kdf.Derive(ss, keyPurpose, KeySize), nil
+}
+
+// Step returns the number of messages received so far.
+func (r *Receiver) Step() uint64 {
+	return r.step
+}
+
+// Reset erases the current private key. The Receiver must not be used
+// afterwards.
+func (r *Receiver) Reset() {
+	kem.ResetPrivateKeys(r.priv)
+}
+
+func checkScheme(sch kem.Scheme) error {
+	if sch == nil {
+		return errors.New("ratchet: KEM scheme cannot be nil")
+	}
+	if !kem.Capabilities(sch).Has(kem.CapDeterministicKeygen) {
+		return kem.ErrNotDeriveable
+	}
+	return nil
+}
+
+// nextKeyPair derives the key pair of the next message from the shared
+// secret of the current one.
+func nextKeyPair(sch kem.Scheme, ss []byte) (kem.PublicKey, kem.PrivateKey, error) {
+	seed := kdf.Derive(ss, seedPurpose, sch.SeedSize())
+	defer util.ExplicitBzero(seed)
+	return kem.DeriveKeyPair(sch, seed)
+}
+
+// tag returns the MAC of a message's ciphertext under its shared secret.
+func tag(ss, ct []byte) []byte {
+	macKey := kdf.Derive(ss, macPurpose, blake2b.Size256)
+	defer util.ExplicitBzero(macKey)
+	h, err := blake2b.New256(macKey)
+	if err != nil {
+		panic(err)
+	}
+	h.Write(ct)
+	return h.Sum(nil)
+}
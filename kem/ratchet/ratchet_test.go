@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ratchet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/kem/schemes"
+)
+
+func testRatchet(t *testing.T, sch kem.Scheme) (*Sender, *Receiver) {
+	pk, sk, err := sch.GenerateKeyPair()
+	require.NoError(t, err)
+	s, err := NewSender(sch, pk)
+	require.NoError(t, err)
+	r, err := NewReceiver(sch, sk)
+	require.NoError(t, err)
+	return s, r
+}
+
+func TestRatchet(t *testing.T) {
+	for _, name := range []string{"MLKEM768", "x25519", "Xwing", "MLKEM768-X25519"} {
+		sch := schemes.ByName(name)
+		require.NotNil(t, sch, name)
+		s, r := testRatchet(t, sch)
+
+		seen := make(map[string]bool)
+		for i := 0; i < 100; i++ {
+			msg, key, err := s.Send()
+			require.NoError(t, err)
+			require.Len(t, msg, s.MessageSize())
+			require.Len(t, key, KeySize)
+
+			key2, err := r.Receive(msg)
+			require.NoError(t, err, "%s message %d", name, i)
+			require.Equal(t, key, key2)
+			require.False(t, seen[string(key)])
+			seen[string(key)] = true
+		}
+		require.Equal(t, uint64(100), s.Step())
+		require.Equal(t, uint64(100), r.Step())
+	}
+}
+
+func TestRatchetInvalidMessage(t *testing.T) {
+	s, r := testRatchet(t, mlkem768.Scheme())
+	msg1, key1, err := s.Send()
+	require.NoError(t, err)
+	msg2, key2, err := s.Send()
+	require.NoError(t, err)
+
+	// Corrupted, truncated and out of order messages are rejected
+	// without disturbing the receiver.
+	bad := append([]byte{}, msg1...)
+	bad[0] ^= 1
+	_, err = r.Receive(bad)
+	require.ErrorIs(t, err, ErrInvalidMessage)
+	bad = append([]byte{}, msg1...)
+	bad[len(bad)-1] ^= 1
+	_, err = r.Receive(bad)
+	require.ErrorIs(t, err, ErrInvalidMessage)
+	_, err = r.Receive(msg1[1:])
+	require.ErrorIs(t, err, ErrInvalidMessage)
+	_, err = r.Receive(msg2)
+	require.ErrorIs(t, err, ErrInvalidMessage)
+	require.Equal(t, uint64(0), r.Step())
+
+	key, err := r.Receive(msg1)
+	require.NoError(t, err)
+	require.Equal(t, key1, key)
+
+	// Replays are rejected too.
+	_, err = r.Receive(msg1)
+	require.ErrorIs(t, err, ErrInvalidMessage)
+	key, err = r.Receive(msg2)
+	require.NoError(t, err)
+	require.Equal(t, key2, key)
+}
+
+func TestRatchetErasesKeys(t *testing.T) {
+	pk, sk, err := mlkem768.Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	s, err := NewSender(mlkem768.Scheme(), pk)
+	require.NoError(t, err)
+	r, err := NewReceiver(mlkem768.Scheme(), sk)
+	require.NoError(t, err)
+
+	msg, _, err := s.Send()
+	require.NoError(t, err)
+	_, err = r.Receive(msg)
+	require.NoError(t, err)
+
+	// The initial private key can no longer decapsulate the message.
+	b, err := sk.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, make([]byte, mlkem768.PrivateKeySize-mlkem768.PublicKeySize), b[:mlkem768.PrivateKeySize-mlkem768.PublicKeySize])
+}
+
+func TestNewRatchetErrors(t *testing.T) {
+	pk, sk, err := mlkem768.Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	x25519 := schemes.ByName("x25519")
+	_, err = NewSender(x25519, pk)
+	require.ErrorIs(t, err, kem.ErrTypeMismatch)
+	_, err = NewReceiver(x25519, sk)
+	require.ErrorIs(t, err, kem.ErrTypeMismatch)
+	_, err = NewSender(nil, pk)
+	require.Error(t, err)
+
+	randomOnly := &randomOnlyScheme{mlkem768.Scheme()}
+	_, err = NewSender(randomOnly, pk)
+	require.ErrorIs(t, err, kem.ErrNotDeriveable)
+	_, err = NewReceiver(randomOnly, sk)
+	require.ErrorIs(t, err, kem.ErrNotDeriveable)
+}
+
+// randomOnlyScheme stands in for a scheme which can't derive keys
+// deterministically.
+type randomOnlyScheme struct {
+	kem.Scheme
+}
+
+func (s *randomOnlyScheme) Capabilities() kem.Caps {
+	return kem.Capabilities(s.Scheme) &^ kem.CapDeterministicKeygen
+}
+
+func TestNewReceiverNotResettable(t *testing.T) {
+	for _, name := range []string{"Kyber768-X25519", "FrodoKEM-640-SHAKE"} {
+		sch := schemes.ByName(name)
+		require.NotNil(t, sch, name)
+		_, sk, err := sch.GenerateKeyPair()
+		require.NoError(t, err)
+		_, err = NewReceiver(sch, sk)
+		require.ErrorIs(t, err, kem.ErrNotResettable, name)
+	}
+
+	_, sk, err := mlkem768.Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	_, err = NewReceiver(mlkem768.Scheme(), sk)
+	require.NoError(t, err)
+}
@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import "github.com/katzenpost/hpqc/kem"
+
+// SecurityLevel returns our estimate of the security of the given
+// scheme as a NIST PQC security category from 1 to 5, as described for
+// SchemeInfo. The boolean is false if we have no estimate for the
+// scheme, as for combiners built by callers.
+func SecurityLevel(s kem.Scheme) (int, bool) {
+	info, _ := Info(s.Name())
+	return info.SecurityLevel, info.SecurityLevel != 0
+}
+
+// BestUnderSize returns the strongest available scheme whose ciphertexts
+// are at most maxCiphertext bytes long, for instance so that they fit in
+// a single datagram. If requirePQ is true, classical only schemes are
+// excluded. Schemes are ranked by their estimated NIST security
+// category, then hybrids are preferred over single schemes, then the
+// smaller ciphertext wins. Schemes without a security estimate are never
+// returned. The boolean is false if no scheme fits.
+func BestUnderSize(maxCiphertext int, requirePQ bool) (kem.Scheme, bool) {
+	var best kem.Scheme
+	bestLevel := 0
+	for _, s := range All() {
+		level, ok := SecurityLevel(s)
+		if !ok || s.CiphertextSize() > maxCiphertext {
+			continue
+		}
+		if requirePQ && Family(s) == FamilyClassical {
+			continue
+		}
+		if best == nil || betterThan(s, level, best, bestLevel) {
+			best, bestLevel = s, level
+		}
+	}
+	return best, best != nil
+}
+
+func betterThan(a kem.Scheme, aLevel int, b kem.Scheme, bLevel int) bool {
+	if aLevel != bLevel {
+		return aLevel > bLevel
+	}
+	aHybrid, bHybrid := Family(a) == FamilyHybrid, Family(b) == FamilyHybrid
+	if aHybrid != bHybrid {
+		return aHybrid
+	}
+	return a.CiphertextSize() < b.CiphertextSize()
+}
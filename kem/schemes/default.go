@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import "github.com/katzenpost/hpqc/kem"
+
+// defaultName is the name of the scheme returned by Default.
+const defaultName = "MLKEM768-X25519"
+
+// DefaultName returns the name of the scheme returned by Default.
+func DefaultName() string {
+	return defaultName
+}
+
+// Default returns the KEM we recommend when there is no reason to pick
+// a specific one. It is chosen to be a hybrid, so that it stays secure
+// if either its classical or post quantum component is broken, built
+// with our security preserving combiner out of standardized components
+// (ML-KEM-768 and X25519), to have small enough keys and ciphertexts for
+// network protocols, and to be available in every build. The default
+// may change in future releases, so store the scheme name alongside
+// keys rather than assuming it.
+func Default() kem.Scheme {
+	return ByName(defaultName)
+}
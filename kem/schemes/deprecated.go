@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/hybrid"
+)
+
+const (
+	kyberNote  = "Kyber is deprecated in favour of ML-KEM (FIPS 203), whose keys and ciphertexts are not compatible with round 3 Kyber"
+	hybridNote = "schemes built with hybrid.New are deprecated in favour of combiner.New"
+)
+
+// warnedSchemes holds the lower case names of the schemes which
+// WarnIfDeprecated has already logged.
+var warnedSchemes sync.Map
+
+// DeprecationNote returns why the given scheme is deprecated and what
+// to use instead, or the empty string if it isn't deprecated. Besides
+// the registered schemes whose SchemeInfo has a DeprecationNote, this
+// covers schemes built with hybrid.New and schemes with a Kyber
+// component.
+func DeprecationNote(s kem.Scheme) string {
+	if info, _ := Info(s.Name()); info.DeprecationNote != "" {
+		return info.DeprecationNote
+	}
+	for {
+		u, ok := s.(interface{ Unwrap() kem.Scheme })
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	if _, ok := s.(*hybrid.Scheme); ok {
+		return hybridNote
+	}
+	if hasKyber(s) {
+		return kyberNote
+	}
+	return ""
+}
+
+// IsDeprecated returns true if the given scheme is deprecated, in which
+// case DeprecationNote explains why.
+func IsDeprecated(s kem.Scheme) bool {
+	return DeprecationNote(s) != ""
+}
+
+// WarnIfDeprecated logs the deprecation note of the given scheme with
+// the standard logger, at most once per scheme name for the life of the
+// process. Call it wherever a scheme is chosen from configuration.
+func WarnIfDeprecated(s kem.Scheme) {
+	note := DeprecationNote(s)
+	if note == "" {
+		return
+	}
+	if _, warned := warnedSchemes.LoadOrStore(strings.ToLower(s.Name()), true); warned {
+		return
+	}
+	log.Printf("hpqc: KEM %s is deprecated: %s", s.Name(), note)
+}
+
+// hasKyber returns true if the scheme, or any of its components, is in
+// FamilyKyber.
+func hasKyber(s kem.Scheme) bool {
+	c, ok := s.(kem.Composite)
+	if !ok {
+		return Family(s) == FamilyKyber
+	}
+	for _, component := range c.Components() {
+		if hasKyber(component) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// Describe returns a one line summary of the scheme for logs, such as
+//
+//	hybrid(x25519 + MLKEM768) [PQ, level 3, ct=1120B]
+//
+// Composite schemes are shown as their component names, and the summary
+// includes whether the scheme is post-quantum, its estimated NIST
+// security category if known, and its ciphertext size.
+func Describe(sch kem.Scheme) string {
+	b := new(strings.Builder)
+	if c, ok := sch.(kem.Composite); ok {
+		names := []string{}
+		for _, component := range c.Components() {
+			names = append(names, component.Name())
+		}
+		fmt.Fprintf(b, "hybrid(%s)", strings.Join(names, " + "))
+	} else {
+		b.WriteString(sch.Name())
+	}
+
+	if isPostQuantum(sch) {
+		b.WriteString(" [PQ")
+	} else {
+		b.WriteString(" [classical")
+	}
+	if level, ok := SecurityLevel(sch); ok {
+		fmt.Fprintf(b, ", level %d", level)
+	}
+	fmt.Fprintf(b, ", ct=%dB]", sch.CiphertextSize())
+	return b.String()
+}
+
+// isPostQuantum returns true if the scheme, or any of its components,
+// isn't classical.
+func isPostQuantum(sch kem.Scheme) bool {
+	c, ok := sch.(kem.Composite)
+	if !ok {
+		return Family(sch) != FamilyClassical
+	}
+	for _, component := range c.Components() {
+		if isPostQuantum(component) {
+			return true
+		}
+	}
+	return false
+}
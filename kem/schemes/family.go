@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// Scheme families as returned by Family and used as the keys of ByFamily.
+const (
+	FamilyClassical = "classical"
+	FamilyMLKEM     = "ML-KEM"
+	FamilyKyber     = "Kyber"
+	FamilyMcEliece  = "McEliece"
+	FamilyNTRU      = "NTRU"
+	FamilyFrodo     = "Frodo"
+	FamilyCTIDH     = "CTIDH"
+	FamilyHybrid    = "hybrid"
+	FamilyOther     = "other"
+)
+
+// familyPrefixes maps lower case name prefixes of the non hybrid
+// schemes to their family.
+var familyPrefixes = []struct {
+	prefix string
+	family string
+}{
+	{"x25519", FamilyClassical},
+	{"x448", FamilyClassical},
+	{"mlkem", FamilyMLKEM},
+	{"kyber", FamilyKyber},
+	{"mceliece", FamilyMcEliece},
+	{"sntrup", FamilyNTRU},
+	{"frodo", FamilyFrodo},
+	{"ctidh", FamilyCTIDH},
+}
+
+// Family returns the family of the given scheme. Schemes built from
+// other schemes, such as the combiner and hybrid KEMs and X-Wing, are
+// in FamilyHybrid regardless of their components, and schemes which
+// aren't recognized are in FamilyOther.
+func Family(s kem.Scheme) string {
+	if _, ok := s.(kem.Composite); ok {
+		return FamilyHybrid
+	}
+	name := strings.ToLower(s.Name())
+	if name == "xwing" {
+		return FamilyHybrid
+	}
+	for _, p := range familyPrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.family
+		}
+	}
+	return FamilyOther
+}
+
+// ByFamily returns all the supported schemes grouped by Family. Within
+// each family the schemes are in the same order as returned by All.
+func ByFamily() map[string][]kem.Scheme {
+	families := make(map[string][]kem.Scheme)
+	for _, s := range All() {
+		f := Family(s)
+		families[f] = append(families[f], s)
+	}
+	return families
+}
+
+// Capabilities returns kem.Capabilities for the given scheme, with
+// kem.CapPostQuantum also set if the scheme or any of its components is
+// in a family other than FamilyClassical. This covers the schemes used
+// directly from circl and the NIKE based KEMs, which don't report their
+// own capabilities.
+func Capabilities(s kem.Scheme) kem.Caps {
+	caps := kem.Capabilities(s)
+	if isPostQuantum(s) {
+		caps |= kem.CapPostQuantum
+	}
+	return caps
+}
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/hybrid"
+)
+
+// Node types of the scheme JSON documents.
+const (
+	nodeScheme   = "scheme"
+	nodeHybrid   = "hybrid"
+	nodeCombiner = "combiner"
+)
+
+// maxSchemeDepth bounds the nesting of scheme JSON documents.
+const maxSchemeDepth = 16
+
+// ErrSchemeJSON is returned by MarshalSchemeJSON for schemes which
+// can't be described, and by UnmarshalSchemeJSON for malformed
+// documents.
+var ErrSchemeJSON = errors.New("schemes: invalid scheme JSON")
+
+// schemeNode is one scheme in a scheme JSON document.
+type schemeNode struct {
+	Type     string        `json:"type"`
+	Name     string        `json:"name"`
+	Spec     string        `json:"spec,omitempty"`
+	Label    string        `json:"label,omitempty"`
+	Children []*schemeNode `json:"children,omitempty"`
+}
+
+// MarshalSchemeJSON describes the given scheme as a JSON document which
+// UnmarshalSchemeJSON turns back into an equivalent scheme, so that
+// bespoke hybrids can be defined in configuration files. For example
+//
+//	{"type":"combiner","name":"MyHybrid",
+//	 "spec":"hpqc-combiner-v1?component=x25519&component=MLKEM768&name=MyHybrid",
+//	 "children":[{"type":"scheme","name":"x25519"},{"type":"scheme","name":"MLKEM768"}]}
+//
+// Registered schemes, and schemes which aren't composite, are recorded
+// by name as "scheme" nodes and looked up with ByName. A scheme which
+// isn't composite must be the one ByName returns, or else a NIKE
+// adapter over the same NIKE, whose label, if it has one, is recorded
+// hex encoded. Other schemes built by the hybrid and combiner packages
+// are recorded as "hybrid" and "combiner" nodes with their components
+// as children; combiner nodes also hold the combiner's Spec, which
+// carries its options. The document is canonical: equivalent schemes
+// give the same bytes. It returns an error wrapping ErrSchemeJSON for
+// schemes which can't be described, such as combiners with a custom
+// KDF or wrapped schemes.
+func MarshalSchemeJSON(sch kem.Scheme) ([]byte, error) {
+	node, err := describeScheme(sch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(node)
+}
+
+// UnmarshalSchemeJSON builds the scheme described by a document from
+// MarshalSchemeJSON. It returns an error wrapping ErrSchemeJSON if the
+// document is malformed or names an unknown scheme.
+func UnmarshalSchemeJSON(b []byte) (kem.Scheme, error) {
+	node := new(schemeNode)
+	if err := json.Unmarshal(b, node); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSchemeJSON, err)
+	}
+	return buildScheme(node, 0)
+}
+
+func describeScheme(sch kem.Scheme) (*schemeNode, error) {
+	if sch == nil {
+		return nil, fmt.Errorf("%w: nil scheme", ErrSchemeJSON)
+	}
+	if _, ok := sch.(kem.Composite); !ok || ByName(sch.Name()) == sch {
+		registered := ByName(sch.Name())
+		if registered == nil {
+			return nil, fmt.Errorf("%w: unknown scheme %q", ErrSchemeJSON, sch.Name())
+		}
+		node := &schemeNode{Type: nodeScheme, Name: sch.Name()}
+		if registered == sch {
+			return node, nil
+		}
+		a, ok := sch.(*adapter.Scheme)
+		r, rok := registered.(*adapter.Scheme)
+		if !ok || !rok || a.NIKE().Name() != r.NIKE().Name() {
+			return nil, fmt.Errorf("%w: %q isn't the registered scheme of that name", ErrSchemeJSON, sch.Name())
+		}
+		node.Label = hex.EncodeToString(a.Label())
+		return node, nil
+	}
+
+	node := &schemeNode{Name: sch.Name()}
+	switch s := sch.(type) {
+	case *hybrid.Scheme:
+		node.Type = nodeHybrid
+	case *combiner.Scheme:
+		node.Type = nodeCombiner
+		node.Spec = s.Spec()
+		if strings.Contains(node.Spec, "kdf=custom") {
+			return nil, fmt.Errorf("%w: %s has a custom KDF", ErrSchemeJSON, sch.Name())
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported composite scheme %q", ErrSchemeJSON, sch.Name())
+	}
+	for _, component := range sch.(kem.Composite).Components() {
+		child, err := describeScheme(component)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return node, nil
+}
+
+func buildScheme(node *schemeNode, depth int) (kem.Scheme, error) {
+	if node == nil {
+		return nil, fmt.Errorf("%w: missing scheme", ErrSchemeJSON)
+	}
+	if depth > maxSchemeDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", ErrSchemeJSON)
+	}
+	if node.Type == nodeScheme {
+		if len(node.Children) != 0 || node.Spec != "" {
+			return nil, fmt.Errorf("%w: scheme %q can't have children or a spec", ErrSchemeJSON, node.Name)
+		}
+		sch := ByName(node.Name)
+		if sch == nil {
+			return nil, fmt.Errorf("%w: unknown scheme %q", ErrSchemeJSON, node.Name)
+		}
+		if node.Label == "" {
+			return sch, nil
+		}
+		label, err := hex.DecodeString(node.Label)
+		a, ok := sch.(*adapter.Scheme)
+		if err != nil || len(label) == 0 || !ok {
+			return nil, fmt.Errorf("%w: invalid label for scheme %q", ErrSchemeJSON, node.Name)
+		}
+		return adapter.FromNIKEWithLabel(a.NIKE(), label), nil
+	}
+
+	children := make([]kem.Scheme, len(node.Children))
+	for i, child := range node.Children {
+		var err error
+		children[i], err = buildScheme(child, depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if node.Label != "" {
+		return nil, fmt.Errorf("%w: %s %q can't have a label", ErrSchemeJSON, node.Type, node.Name)
+	}
+	switch node.Type {
+	case nodeHybrid:
+		if len(children) != 2 || node.Spec != "" {
+			return nil, fmt.Errorf("%w: hybrid %q needs two children and no spec", ErrSchemeJSON, node.Name)
+		}
+		return hybrid.New(node.Name, children[0], children[1]), nil
+	case nodeCombiner:
+		sch, err := combiner.FromSpecWithComponents(node.Spec, children)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrSchemeJSON, err)
+		}
+		if sch.Name() != node.Name {
+			return nil, fmt.Errorf("%w: combiner %q has spec for %q", ErrSchemeJSON, node.Name, sch.Name())
+		}
+		return sch, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown node type %q", ErrSchemeJSON, node.Type)
+	}
+}
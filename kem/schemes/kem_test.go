@@ -4,15 +4,31 @@
 package schemes
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
 
 	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/hybrid"
+	"github.com/katzenpost/hpqc/kem/instrument"
 	"github.com/katzenpost/hpqc/kem/pem"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
 	"github.com/katzenpost/hpqc/rand"
 )
 
@@ -107,3 +123,872 @@ func TestKEMEncapDecap(t *testing.T) {
 		t.Log("OK")
 	}
 }
+
+func TestAllOrder(t *testing.T) {
+	var want []string
+	for _, name := range []string{
+		"x25519", "X448",
+		"MLKEM768", "sntrup4591761", "FrodoKEM-640-SHAKE",
+		"mceliece348864", "mceliece348864f", "mceliece460896", "mceliece460896f",
+		"mceliece6688128", "mceliece6688128f", "mceliece6960119", "mceliece6960119f",
+		"mceliece8192128", "mceliece8192128f",
+		"XWING", "Kyber768-X25519", "MLKEM768-X25519", "MLKEM768-X448",
+		"mceliece348864-X25519", "mceliece348864f-X25519",
+		"mceliece460896-X25519", "mceliece460896f-X25519",
+		"mceliece6688128-X25519", "mceliece6688128f-X25519",
+		"mceliece6960119-X25519", "mceliece6960119f-X25519",
+		"mceliece8192128-X25519", "mceliece8192128f-X25519",
+		"CTIDH511", "CTIDH512", "CTIDH1024", "CTIDH2048",
+		"CTIDH512-X25519", "CTIDH1024-X448",
+	} {
+		if BuiltWith(name) {
+			want = append(want, strings.ToLower(name))
+		}
+	}
+	var got []string
+	for _, s := range All() {
+		got = append(got, strings.ToLower(s.Name()))
+	}
+	require.Equal(t, want, got)
+}
+
+func TestInfo(t *testing.T) {
+	codes := make(map[uint64]string)
+	for _, p := range registry {
+		info, ok := Info(p.name)
+		require.True(t, ok, p.name)
+		require.Equal(t, p.info, info)
+		require.GreaterOrEqual(t, info.SecurityLevel, 1, p.name)
+		require.LessOrEqual(t, info.SecurityLevel, 5, p.name)
+		require.NotZero(t, info.Multicodec, p.name)
+		other, dup := codes[info.Multicodec]
+		require.False(t, dup, "%s and %s share a multicodec", p.name, other)
+		codes[info.Multicodec] = p.name
+	}
+
+	info, ok := Info("MCELIECE8192128F")
+	require.True(t, ok)
+	require.Equal(t, uint64(5<<20), info.KeygenMemory)
+	_, ok = Info("bogus")
+	require.False(t, ok)
+}
+
+func TestBestUnderSize(t *testing.T) {
+	for _, s := range All() {
+		_, ok := SecurityLevel(s)
+		require.True(t, ok, s.Name())
+	}
+
+	s, ok := BestUnderSize(32, false)
+	require.True(t, ok)
+	require.Equal(t, "x25519", s.Name())
+
+	_, ok = BestUnderSize(56, true)
+	require.False(t, ok)
+
+	// sizes of datagram sized ciphertexts
+	s, ok = BestUnderSize(1200, true)
+	require.True(t, ok)
+	require.LessOrEqual(t, s.CiphertextSize(), 1200)
+	require.Equal(t, FamilyHybrid, Family(s))
+
+	s, ok = BestUnderSize(1<<30, true)
+	require.True(t, ok)
+	level, _ := SecurityLevel(s)
+	require.Equal(t, 5, level)
+	require.Equal(t, FamilyHybrid, Family(s))
+}
+
+func TestDefault(t *testing.T) {
+	s := Default()
+	require.NotNil(t, s)
+	require.Equal(t, DefaultName(), s.Name())
+	require.Equal(t, FamilyHybrid, Family(s))
+	_, ok := UnavailableReason(DefaultName())
+	require.False(t, ok)
+}
+
+func TestBuiltWith(t *testing.T) {
+	require.True(t, BuiltWith("mlkem768"))
+	require.False(t, BuiltWith("bogus"))
+
+	unavailable := make(map[string]bool)
+	for _, name := range UnavailableNames() {
+		unavailable[name] = true
+		require.False(t, BuiltWith(name))
+		reason, ok := UnavailableReason(name)
+		require.True(t, ok)
+		require.NotEmpty(t, reason)
+	}
+	for _, p := range registry {
+		require.Equal(t, !unavailable[p.name], BuiltWith(p.name))
+		if p.scheme != nil {
+			require.Equal(t, p.name, p.scheme.Name())
+		}
+	}
+
+	_, ok := UnavailableReason("bogus")
+	require.False(t, ok)
+
+	// CTIDH is built here, so stand in for a scheme which isn't.
+	registerForTest(t, potentialScheme{
+		name:     "Unbuilt768",
+		requires: "the unbuilt build tag",
+		info:     SchemeInfo{SecurityLevel: 3},
+	})
+	require.Contains(t, UnavailableNames(), "Unbuilt768")
+	require.False(t, BuiltWith("unbuilt768"))
+	require.Nil(t, ByName("unbuilt768"))
+	reason, ok := UnavailableReason("UNBUILT768")
+	require.True(t, ok)
+	require.Equal(t, "the unbuilt build tag", reason)
+	info, ok := Info("unbuilt768")
+	require.True(t, ok)
+	require.Equal(t, 3, info.SecurityLevel)
+	for _, s := range All() {
+		require.NotEqual(t, "Unbuilt768", s.Name())
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	nested := combiner.New(
+		"Kyber768-X25519-sntrup",
+		[]kem.Scheme{
+			ByName("Kyber768-X25519"),
+			ByName("sntrup4591761"),
+		},
+	)
+	todo := append(All(), nested)
+
+	for _, s := range todo {
+		_, err := s.UnmarshalBinaryPublicKey(make([]byte, s.PublicKeySize()-1))
+		require.ErrorIs(t, err, kem.ErrPubKeySize, s.Name())
+
+		_, err = s.UnmarshalBinaryPrivateKey(make([]byte, s.PrivateKeySize()+1))
+		require.ErrorIs(t, err, kem.ErrPrivKeySize, s.Name())
+
+		_, privKey, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		_, err = s.Decapsulate(privKey, make([]byte, s.CiphertextSize()-1))
+		require.ErrorIs(t, err, kem.ErrCiphertextSize, s.Name())
+	}
+}
+
+func TestSizeError(t *testing.T) {
+	for _, name := range []string{"x25519", "MLKEM768", "XWING", "sntrup4591761", "MLKEM768-X25519", "Kyber768-X25519"} {
+		s := ByName(name)
+		var sizeErr *kem.SizeError
+
+		_, err := s.UnmarshalBinaryPublicKey(make([]byte, s.PublicKeySize()-1))
+		require.ErrorAs(t, err, &sizeErr, name)
+		require.Equal(t, s.PublicKeySize(), sizeErr.Expected)
+		require.Equal(t, s.PublicKeySize()-1, sizeErr.Got)
+
+		_, err = s.UnmarshalBinaryPrivateKey(make([]byte, s.PrivateKeySize()+1))
+		require.ErrorAs(t, err, &sizeErr, name)
+		require.Equal(t, s.PrivateKeySize()+1, sizeErr.Got)
+
+		_, privKey, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		_, err = s.Decapsulate(privKey, make([]byte, s.CiphertextSize()+2))
+		require.ErrorAs(t, err, &sizeErr, name)
+		require.ErrorIs(t, err, kem.ErrCiphertextSize)
+		require.Equal(t, s.CiphertextSize()+2, sizeErr.Got)
+	}
+
+	x25519KEM := ByName("x25519")
+	x448PubKey, _, err := ByName("x448").GenerateKeyPair()
+	require.NoError(t, err)
+	_, _, err = x25519KEM.Encapsulate(x448PubKey)
+	require.ErrorIs(t, err, kem.ErrTypeMismatch)
+}
+
+func TestMarshalBinaryTo(t *testing.T) {
+	for _, name := range []string{"x25519", "MLKEM768", "XWING", "sntrup4591761", "MLKEM768-X25519", "MLKEM768-X448"} {
+		s := ByName(name)
+		pubKey, _, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		m, ok := pubKey.(kem.MarshalerTo)
+		require.True(t, ok, name)
+
+		blob, err := pubKey.MarshalBinary()
+		require.NoError(t, err)
+		dst := make([]byte, s.PublicKeySize())
+		n, err := m.MarshalBinaryTo(dst)
+		require.NoError(t, err, name)
+		require.Equal(t, s.PublicKeySize(), n, name)
+		require.Equal(t, blob, dst, name)
+
+		_, err = m.MarshalBinaryTo(dst[:n-1])
+		require.ErrorIs(t, err, kem.ErrPubKeySize, name)
+
+		allocs := testing.AllocsPerRun(10, func() {
+			m.MarshalBinaryTo(dst)
+		})
+		require.Zero(t, allocs, name)
+	}
+}
+
+func TestGenerateKeyPairs(t *testing.T) {
+	s := ByName("x25519")
+	require.NotNil(t, s)
+
+	pubKeys, privKeys, err := GenerateKeyPairs(s, 20, 4)
+	require.NoError(t, err)
+	require.Len(t, pubKeys, 20)
+	require.Len(t, privKeys, 20)
+
+	seen := make(map[string]bool)
+	for i := range pubKeys {
+		require.True(t, pubKeys[i].Equal(privKeys[i].Public()))
+		blob, err := pubKeys[i].MarshalBinary()
+		require.NoError(t, err)
+		require.False(t, seen[string(blob)])
+		seen[string(blob)] = true
+	}
+
+	_, _, err = GenerateKeyPairs(s, 1, 0)
+	require.Error(t, err)
+	_, _, err = GenerateKeyPairs(s, -1, 1)
+	require.Error(t, err)
+}
+
+func TestNegotiate(t *testing.T) {
+	local := []string{"X25519", "MLKEM768-X25519", "Xwing", "bogus"}
+	remote := []string{"xwing", "mlkem768-x25519", "bogus"}
+
+	s, ok := Negotiate(local, remote, []string{"Xwing", "MLKEM768-X25519"})
+	require.True(t, ok)
+	require.Equal(t, "XWING", strings.ToUpper(s.Name()))
+
+	// common schemes absent from the preference list fall back to local order
+	s, ok = Negotiate(local, remote, []string{"X448"})
+	require.True(t, ok)
+	require.Equal(t, "MLKEM768-X25519", s.Name())
+
+	_, ok = Negotiate(local, []string{"X448"}, nil)
+	require.False(t, ok)
+}
+
+func TestByFamily(t *testing.T) {
+	families := ByFamily()
+
+	total := 0
+	for family, list := range families {
+		require.NotEqual(t, FamilyOther, family)
+		for _, s := range list {
+			require.Equal(t, family, Family(s))
+		}
+		total += len(list)
+	}
+	require.Equal(t, len(All()), total)
+
+	require.Equal(t, FamilyClassical, Family(ByName("x25519")))
+	require.Equal(t, FamilyMLKEM, Family(ByName("MLKEM768")))
+	require.Equal(t, FamilyNTRU, Family(ByName("sntrup4591761")))
+	require.Equal(t, FamilyHybrid, Family(ByName("Xwing")))
+	require.Equal(t, FamilyHybrid, Family(ByName("Kyber768-X25519")))
+	require.Equal(t, FamilyHybrid, Family(ByName("mceliece348864-X25519")))
+	require.Equal(t, FamilyCTIDH, Family(ByName("ctidh1024")))
+	require.Len(t, families[FamilyMcEliece], 10)
+}
+
+func TestCapabilities(t *testing.T) {
+	for _, s := range All() {
+		caps := Capabilities(s)
+		require.Equal(t, isPostQuantum(s), caps.Has(kem.CapPostQuantum), s.Name())
+		// the schemes report it themselves, including within combiners
+		require.Equal(t, isPostQuantum(s), kem.Capabilities(s).Has(kem.CapPostQuantum), s.Name())
+		require.Equal(t, kem.ImplicitRejection(s), caps.Has(kem.CapImplicitReject), s.Name())
+		require.Equal(t, Family(s) == FamilyHybrid, caps.Has(kem.CapHybrid), s.Name())
+	}
+	require.True(t, Capabilities(ByName("Kyber768-X25519")).Has(kem.CapPostQuantum))
+	require.True(t, Capabilities(ByName("ctidh1024")).Has(kem.CapPostQuantum))
+	require.False(t, Capabilities(ByName("x448")).Has(kem.CapPostQuantum))
+}
+
+func TestDeprecated(t *testing.T) {
+	require.True(t, IsDeprecated(ByName("Kyber768-X25519")))
+	require.Contains(t, DeprecationNote(ByName("Kyber768-X25519")), "MLKEM768-X25519")
+	require.False(t, IsDeprecated(ByName("MLKEM768-X25519")))
+	require.False(t, IsDeprecated(ByName("Xwing")))
+	require.Empty(t, DeprecationNote(ByName("x25519")))
+
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	require.Equal(t, hybridNote, DeprecationNote(hybrid.New("X25519-X448", x25519KEM, x448KEM)))
+
+	kyber := ByName("Kyber768-X25519").(kem.Composite).Components()[1]
+	s := combiner.New("X448-Kyber768", []kem.Scheme{x448KEM, kyber})
+	require.Equal(t, kyberNote, DeprecationNote(s))
+
+	for _, s := range All() {
+		if IsDeprecated(s) {
+			require.Contains(t, []string{"Kyber768-X25519"}, s.Name())
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+	WarnIfDeprecated(ByName("MLKEM768-X25519"))
+	require.Zero(t, buf.Len())
+	WarnIfDeprecated(ByName("Kyber768-X25519"))
+	WarnIfDeprecated(ByName("kyber768-x25519"))
+	require.Equal(t, 1, strings.Count(buf.String(), "deprecated"))
+}
+
+// registerForTest adds p to the registry until the end of the test.
+func registerForTest(t *testing.T, p potentialScheme) {
+	key := strings.ToLower(p.name)
+	_, ok := schemeInfos[key]
+	require.False(t, ok, p.name)
+	n, m := len(registry), len(allSchemes)
+	register(p)
+	t.Cleanup(func() {
+		registry, allSchemes = registry[:n], allSchemes[:m]
+		delete(schemeInfos, key)
+		if p.scheme != nil {
+			delete(allSchemeNames, strings.ToLower(p.scheme.Name()))
+		}
+	})
+}
+
+// randomOnlyScheme stands in for a scheme which can't derive keys
+// deterministically.
+type randomOnlyScheme struct {
+	kem.Scheme
+}
+
+func (s *randomOnlyScheme) Name() string {
+	return "RandomOnly"
+}
+
+func (s *randomOnlyScheme) Capabilities() kem.Caps {
+	return kem.Capabilities(s.Scheme) &^ kem.CapDeterministicKeygen
+}
+
+func TestDeriveable(t *testing.T) {
+	// Every built in scheme can derive keys, which the golden digests
+	// of TestDeriveKeyPairGolden also pin down.
+	require.Equal(t, All(), Deriveable())
+
+	randomOnly := &randomOnlyScheme{ByName("x25519")}
+	registerForTest(t, potentialScheme{name: randomOnly.Name(), scheme: randomOnly})
+	require.Equal(t, randomOnly, ByName("randomonly"))
+	require.Contains(t, All(), kem.Scheme(randomOnly))
+	deriveable := Deriveable()
+	require.NotContains(t, deriveable, kem.Scheme(randomOnly))
+	require.Len(t, deriveable, len(All())-1)
+	_, _, err := kem.DeriveKeyPair(randomOnly, make([]byte, randomOnly.SeedSize()))
+	require.ErrorIs(t, err, kem.ErrNotDeriveable)
+}
+
+func TestCircl(t *testing.T) {
+	mceliece := ByName("mceliece348864")
+	require.Equal(t, mceliece, kem.Circl(mceliece))
+	require.Equal(t, mceliece, kem.Circl(instrument.Wrap(mceliece, instrument.Hooks{})))
+	require.Equal(t, mceliece, WithReader(rand.Reader).ByName("mceliece348864").(kem.CirclProvider).Circl())
+
+	require.Nil(t, kem.Circl(ByName("MLKEM768")))
+	require.Nil(t, kem.Circl(ByName("x25519")))
+	require.Nil(t, kem.Circl(ByName("mceliece348864-X25519")))
+}
+
+func TestCandidatesForCiphertextSize(t *testing.T) {
+	for _, s := range All() {
+		candidates := CandidatesForCiphertextSize(s.CiphertextSize())
+		require.Contains(t, candidates, s)
+		for _, c := range candidates {
+			require.Equal(t, s.CiphertextSize(), c.CiphertextSize())
+		}
+		require.Equal(t, candidates, CandidatesForCiphertextSize(s.CiphertextSize()))
+	}
+
+	// Candidates keep the order of All.
+	candidates := CandidatesForCiphertextSize(ByName("x25519").CiphertextSize())
+	j := 0
+	for _, s := range All() {
+		if j < len(candidates) && s == candidates[j] {
+			j++
+		}
+	}
+	require.Equal(t, len(candidates), j)
+
+	require.Empty(t, CandidatesForCiphertextSize(0))
+	require.Empty(t, CandidatesForCiphertextSize(-1))
+}
+
+func TestKeygenMemory(t *testing.T) {
+	n, ok := KeygenMemory(ByName("mceliece8192128f"))
+	require.True(t, ok)
+	require.Equal(t, uint64(5<<20), n)
+
+	// Hybrids add the keys of their other components.
+	n, ok = KeygenMemory(WithReader(rand.Reader).ByName("mceliece8192128f-X25519"))
+	require.True(t, ok)
+	require.Equal(t, uint64(5<<20+64), n)
+
+	_, ok = KeygenMemory(ByName("MLKEM768-X25519"))
+	require.False(t, ok)
+	require.NoError(t, CheckKeygenMemory(ByName("MLKEM768-X25519"), 1))
+
+	mceliece := ByName("mceliece348864")
+	require.NoError(t, CheckKeygenMemory(mceliece, 1<<20))
+	require.ErrorIs(t, CheckKeygenMemory(mceliece, 1<<20-1), ErrInsufficientMemory)
+
+	// Without a budget the runtime memory limit is used.
+	require.NoError(t, CheckKeygenMemory(mceliece, 0))
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(1 << 20))
+	require.ErrorIs(t, CheckKeygenMemory(mceliece, 0), ErrInsufficientMemory)
+}
+
+func TestSchemeJSON(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	nested := combiner.New("Nested", []kem.Scheme{
+		hybrid.New("X25519-MLKEM768", x25519KEM, ByName("MLKEM768")),
+		combiner.New("X448-sntrup", []kem.Scheme{x448KEM, ByName("sntrup4591761")}, combiner.WithFixedOutput(64)),
+		ByName("MLKEM768-X25519"),
+	}, combiner.WithNameBound())
+
+	for _, s := range []kem.Scheme{ByName("Xwing"), ByName("MLKEM768-X25519"), ByName("Kyber768-X25519"), nested} {
+		b, err := MarshalSchemeJSON(s)
+		require.NoError(t, err, s.Name())
+		s2, err := UnmarshalSchemeJSON(b)
+		require.NoError(t, err, string(b))
+		require.Equal(t, s.Name(), s2.Name())
+		require.Equal(t, s.PublicKeySize(), s2.PublicKeySize())
+		require.Equal(t, s.PrivateKeySize(), s2.PrivateKeySize())
+		require.Equal(t, s.CiphertextSize(), s2.CiphertextSize())
+		require.Equal(t, s.SharedKeySize(), s2.SharedKeySize())
+		require.Equal(t, s.SeedSize(), s2.SeedSize())
+		require.True(t, kem.WireCompatible(s, s2), string(b))
+
+		b2, err := MarshalSchemeJSON(s2)
+		require.NoError(t, err)
+		require.Equal(t, b, b2)
+	}
+
+	// The rebuilt nested scheme derives the same shared secrets.
+	b, err := MarshalSchemeJSON(nested)
+	require.NoError(t, err)
+	s2, err := UnmarshalSchemeJSON(b)
+	require.NoError(t, err)
+	seed := make([]byte, nested.SeedSize())
+	pk, sk := nested.DeriveKeyPair(seed)
+	pk2, _ := s2.DeriveKeyPair(seed)
+	ct, ss, err := s2.Encapsulate(pk2)
+	require.NoError(t, err)
+	ss2, err := nested.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+	require.NotNil(t, pk)
+
+	for _, doc := range []string{
+		`{"type":"scheme","name":"nope"}`,
+		`{"type":"tree","name":"x25519"}`,
+		`{"type":"hybrid","name":"h","children":[{"type":"scheme","name":"x25519"}]}`,
+		`{"type":"combiner","name":"c","spec":"bogus","children":[{"type":"scheme","name":"x25519"},{"type":"scheme","name":"x448"}]}`,
+		`{"type":"combiner","name":"c","spec":"hpqc-combiner-v1?component=x25519&component=x448&name=d",` +
+			`"children":[{"type":"scheme","name":"x25519"},{"type":"scheme","name":"x448"}]}`,
+		`[]`,
+	} {
+		_, err := UnmarshalSchemeJSON([]byte(doc))
+		require.ErrorIs(t, err, ErrSchemeJSON, doc)
+	}
+
+	_, err = MarshalSchemeJSON(combiner.New("KDF", []kem.Scheme{x25519KEM, x448KEM}, combiner.WithKDF(func(inputs ...[]byte) []byte {
+		return make([]byte, 32)
+	})))
+	require.ErrorIs(t, err, ErrSchemeJSON)
+
+	// leaves must be the registered schemes, up to NIKE adapter labels
+	_, err = MarshalSchemeJSON(struct{ kem.Scheme }{ByName("MLKEM768")})
+	require.ErrorIs(t, err, ErrSchemeJSON)
+	for _, doc := range []string{
+		`{"type":"scheme","name":"MLKEM768","label":"00"}`,
+		`{"type":"scheme","name":"x25519","label":"zz"}`,
+		`{"type":"hybrid","name":"h","label":"00","children":[{"type":"scheme","name":"x25519"},{"type":"scheme","name":"x448"}]}`,
+	} {
+		_, err := UnmarshalSchemeJSON([]byte(doc))
+		require.ErrorIs(t, err, ErrSchemeJSON, doc)
+	}
+}
+
+func TestSchemeJSONLabel(t *testing.T) {
+	labeled := adapter.FromNIKEWithLabel(x25519.Scheme(rand.Reader), []byte("hpqc test"))
+	for _, s := range []kem.Scheme{
+		labeled,
+		hybrid.New("X25519-MLKEM768", labeled, ByName("MLKEM768")),
+		combiner.New("X25519-MLKEM768", []kem.Scheme{labeled, ByName("MLKEM768")}),
+	} {
+		b, err := MarshalSchemeJSON(s)
+		require.NoError(t, err, s.Name())
+		require.Contains(t, string(b), fmt.Sprintf(`"label":"%x"`, "hpqc test"))
+		s2, err := UnmarshalSchemeJSON(b)
+		require.NoError(t, err, string(b))
+
+		// the rebuilt scheme derives the same shared secrets
+		pk, sk := s.DeriveKeyPair(make([]byte, s.SeedSize()))
+		ct, ss, err := s.Encapsulate(pk)
+		require.NoError(t, err)
+		ss2, err := s2.Decapsulate(sk, ct)
+		require.NoError(t, err, string(b))
+		require.Equal(t, ss, ss2, string(b))
+	}
+}
+
+func TestCombinerParseName(t *testing.T) {
+	components, err := combiner.ParseName("X25519-mlkem768-x448", ByName)
+	require.NoError(t, err)
+	require.Len(t, components, 3)
+	require.Equal(t, "x25519", components[0].Name())
+	require.Equal(t, "MLKEM768", components[1].Name())
+	require.Equal(t, "x448", components[2].Name())
+
+	s, err := combiner.FromName("sntrup4591761-X25519", ByName)
+	require.NoError(t, err)
+	require.Equal(t, "sntrup4591761-X25519", s.Name())
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss1, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(privKey, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss1, ss2)
+
+	_, err = combiner.ParseName("X25519-nope", ByName)
+	require.ErrorIs(t, err, combiner.ErrUnknownComponent)
+	_, err = combiner.ParseName("X25519", ByName)
+	require.Error(t, err)
+	_, err = combiner.ParseName("X25519-X25519", ByName)
+	require.ErrorIs(t, err, combiner.ErrDuplicateScheme)
+	_, err = combiner.ParseName("x25519-MLKEM768-X25519", ByName)
+	require.ErrorIs(t, err, combiner.ErrDuplicateScheme)
+	require.Contains(t, err.Error(), `"x25519" and "X25519"`)
+	_, err = combiner.FromName("MLKEM768-mlkem768", ByName)
+	require.ErrorIs(t, err, combiner.ErrDuplicateScheme)
+
+	_, err = combiner.ParseName("X25519-MLKEM768", nil)
+	require.ErrorIs(t, err, combiner.ErrNilResolver)
+}
+
+func TestWireCompatible(t *testing.T) {
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+
+	a := combiner.New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})
+	b := hybrid.New("alias", x25519KEM, x448KEM)
+	c := combiner.New("X448-X25519", []kem.Scheme{x448KEM, x25519KEM})
+
+	require.True(t, kem.WireCompatible(a, b))
+	require.False(t, kem.WireCompatible(a, c))
+	require.False(t, kem.WireCompatible(a, x25519KEM))
+	require.True(t, kem.WireCompatible(ByName("mlkem768-x25519"), combiner.New("renamed", []kem.Scheme{x25519KEM, ByName("mlkem768")})))
+
+	// a key marshaled under one unmarshals under the other
+	pubKey, _, err := a.GenerateKeyPair()
+	require.NoError(t, err)
+	blob, err := pubKey.MarshalBinary()
+	require.NoError(t, err)
+	pubKey2, err := b.UnmarshalBinaryPublicKey(blob)
+	require.NoError(t, err)
+	blob2, err := pubKey2.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, blob, blob2)
+}
+
+func TestMultibase(t *testing.T) {
+	require.Equal(t, "2NEpo7TZRRrLZSi2U", base58Encode([]byte("Hello World!")))
+	b, ok := base58Decode("11" + base58Encode([]byte{0xff}))
+	require.True(t, ok)
+	require.Equal(t, []byte{0, 0, 0xff}, b)
+	_, ok = base58Decode("0OIl")
+	require.False(t, ok)
+
+	for _, s := range All() {
+		_, ok := Multicodec(s)
+		require.True(t, ok, s.Name())
+		if s.PublicKeySize() > 10000 {
+			continue
+		}
+
+		pk, _, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		encoded, err := MarshalMultibase(pk)
+		require.NoError(t, err)
+		pk2, err := UnmarshalMultibase(encoded)
+		require.NoError(t, err)
+		require.True(t, pk.Equal(pk2), s.Name())
+	}
+
+	// X25519 keys in DID documents conventionally start with z6LS.
+	pk, _, err := ByName("x25519").GenerateKeyPair()
+	require.NoError(t, err)
+	encoded, err := MarshalMultibase(pk)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(encoded, "z6LS"), encoded)
+
+	_, err = UnmarshalMultibase("m" + encoded[1:])
+	require.ErrorIs(t, err, ErrMultibaseEncoding)
+	_, err = UnmarshalMultibase("z" + base58Encode([]byte{0x7f, 1, 2, 3}))
+	require.ErrorIs(t, err, ErrUnknownMulticodec)
+}
+
+func TestAuditRegisteredCombiners(t *testing.T) {
+	for _, s := range All() {
+		c, ok := s.(*combiner.Scheme)
+		if !ok {
+			continue
+		}
+		require.Empty(t, c.Audit(), s.Name())
+	}
+}
+
+func TestSpecRegisteredCombiners(t *testing.T) {
+	for _, s := range All() {
+		c, ok := s.(*combiner.Scheme)
+		if !ok {
+			continue
+		}
+		c2, err := combiner.FromSpec(c.Spec(), ByName)
+		require.NoError(t, err, s.Name())
+		require.Equal(t, c.Spec(), c2.Spec())
+		require.True(t, kem.WireCompatible(c, c2), s.Name())
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	require.Equal(t, "hybrid(x25519 + MLKEM768) [PQ, level 3, ct=1120B]", Describe(ByName("MLKEM768-X25519")))
+	require.Equal(t, "x25519 [classical, level 1, ct=32B]", Describe(ByName("x25519")))
+	require.Equal(t, "XWING [PQ, level 3, ct=1120B]", Describe(ByName("XWING")))
+
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	x448KEM := adapter.FromNIKE(x448.Scheme(rand.Reader))
+	require.Equal(t, "hybrid(x25519 + x448) [classical, ct=88B]",
+		Describe(combiner.New("X25519-X448", []kem.Scheme{x25519KEM, x448KEM})))
+
+	for _, s := range All() {
+		require.NotEmpty(t, Describe(s))
+	}
+}
+
+// TestConcurrentUse shares each scheme and key pair between goroutines,
+// and is meant to be run with the race detector.
+// hasCTIDH returns true if the scheme, or any of its components, is in
+// FamilyCTIDH.
+func hasCTIDH(s kem.Scheme) bool {
+	if c, ok := s.(kem.Composite); ok {
+		for _, component := range c.Components() {
+			if hasCTIDH(component) {
+				return true
+			}
+		}
+		return false
+	}
+	return Family(s) == FamilyCTIDH
+}
+
+// concurrencySchemes returns one fast scheme of each family, the one
+// with the smallest public key, so that every kind of implementation is
+// run concurrently without the test taking the time of the slowest
+// schemes. CTIDH takes about a second per operation, so outside of its
+// own family hybrids with a CTIDH component are passed over, and the
+// larger CTIDH parameter sets are never picked in short mode.
+func concurrencySchemes() []kem.Scheme {
+	var picked []kem.Scheme
+	for name, family := range ByFamily() {
+		var fastest kem.Scheme
+		for _, s := range family {
+			if name != FamilyCTIDH && hasCTIDH(s) {
+				continue
+			}
+			if testing.Short() && (strings.EqualFold(s.Name(), "ctidh1024") || strings.EqualFold(s.Name(), "ctidh2048")) {
+				continue
+			}
+			if fastest == nil || s.PublicKeySize() < fastest.PublicKeySize() {
+				fastest = s
+			}
+		}
+		if fastest != nil {
+			picked = append(picked, fastest)
+		}
+	}
+	return picked
+}
+
+func TestConcurrentUse(t *testing.T) {
+	const goroutines = 8
+
+	for _, s := range concurrencySchemes() {
+		iterations := 4
+		if hasCTIDH(s) {
+			iterations = 1
+		}
+		pk, sk, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		errCh := make(chan error, goroutines)
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < iterations; j++ {
+					ct, ss, err := s.Encapsulate(pk)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					ss2, err := s.Decapsulate(sk, ct)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					if !bytes.Equal(ss, ss2) {
+						errCh <- fmt.Errorf("%s: shared secret mismatch", s.Name())
+						return
+					}
+					if _, err = pk.MarshalBinary(); err != nil {
+						errCh <- err
+						return
+					}
+					if _, _, err = s.GenerateKeyPair(); err != nil {
+						errCh <- err
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errCh)
+		for err := range errCh {
+			require.NoError(t, err, s.Name())
+		}
+	}
+}
+
+// countingReader returns an endless stream of zero bytes and counts
+// how many were read.
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestWithReader(t *testing.T) {
+	r := new(countingReader)
+	reg := WithReader(r)
+	require.Equal(t, r, reg.Reader())
+	require.Nil(t, reg.ByName("bogus"))
+	require.Len(t, reg.All(), len(All()))
+
+	for _, name := range []string{"x25519", "MLKEM768", "sntrup4591761", "MLKEM768-X25519", "X25519-sntrup4591761"} {
+		s := reg.ByName(name)
+		if s == nil {
+			var err error
+			s, err = combiner.FromName(name, ByName)
+			require.NoError(t, err)
+			s = reg.bind(s)
+		}
+		inner := s.(interface{ Unwrap() kem.Scheme }).Unwrap()
+		require.Equal(t, Family(inner), Family(s), name)
+		require.Equal(t, Capabilities(inner), Capabilities(s), name)
+
+		r.n = 0
+		pk, sk, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		require.Equal(t, s.SeedSize(), r.n, name)
+		pk2, _ := inner.DeriveKeyPair(make([]byte, s.SeedSize()))
+		require.True(t, pk.Equal(pk2), name)
+
+		r.n = 0
+		ct, ss, err := s.Encapsulate(pk)
+		require.NoError(t, err)
+		bound := s.(interface{ EncapsulatesWithReader() bool }).EncapsulatesWithReader()
+		require.Equal(t, bound, r.n > 0, name)
+		ss2, err := s.Decapsulate(sk, ct)
+		require.NoError(t, err)
+		require.Equal(t, ss, ss2)
+	}
+	require.False(t, reg.ByName("MLKEM768").(*ReaderScheme).EncapsulatesWithReader())
+	require.True(t, reg.ByName("sntrup4591761").(*ReaderScheme).EncapsulatesWithReader())
+
+	_, _, err := WithReader(bytes.NewReader(nil)).ByName("x25519").GenerateKeyPair()
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate())
+
+	x25519KEM := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	err := validate(append(All(), x25519KEM))
+	require.ErrorIs(t, err, ErrRegistry)
+	require.ErrorContains(t, err, "differ only in case")
+
+	err = validate([]kem.Scheme{x25519KEM})
+	require.ErrorIs(t, err, ErrRegistry)
+	require.ErrorContains(t, err, "ByName")
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata/derive_golden.json")
+
+// deriveGoldenPath holds, for each scheme, the hex BLAKE2b-256 digest of
+// the binary public key followed by the binary private key derived from
+// the seed 0, 1, 2, ... by DeriveKeyPair.
+const deriveGoldenPath = "testdata/derive_golden.json"
+
+func deriveDigest(s kem.Scheme) string {
+	seed := make([]byte, s.SeedSize())
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	pk, sk := s.DeriveKeyPair(seed)
+	pkBytes, err := pk.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	skBytes, err := sk.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	h := blake2b.Sum256(append(pkBytes, skBytes...))
+	return hex.EncodeToString(h[:])
+}
+
+// TestDeriveKeyPairGolden guards against changes to key derivation,
+// which would break seeds stored by users. Every scheme must have a
+// golden digest; run with -update to record digests for new schemes.
+func TestDeriveKeyPairGolden(t *testing.T) {
+	golden := make(map[string]string)
+	blob, err := os.ReadFile(deriveGoldenPath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(blob, &golden))
+
+	for _, s := range All() {
+		got := deriveDigest(s)
+		if *updateGolden {
+			golden[s.Name()] = got
+			continue
+		}
+		want, ok := golden[s.Name()]
+		if !ok {
+			t.Errorf("no golden digest for %s, run with -update to record it", s.Name())
+			continue
+		}
+		require.Equal(t, want, got, s.Name())
+	}
+
+	if *updateGolden {
+		blob, err = json.MarshalIndent(golden, "", "\t")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(deriveGoldenPath, append(blob, '\n'), 0644))
+	}
+}
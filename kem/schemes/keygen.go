@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// GenerateKeyPairs generates n key pairs for the given scheme using
+// at most the given number of concurrent workers. This is mostly
+// useful for schemes with slow key generation such as Classic McEliece.
+//
+// Every key pair is made by the scheme's own GenerateKeyPair, so the
+// keys are as random as those generated one at a time. It returns an
+// error if generating any of the key pairs failed.
+func GenerateKeyPairs(sch kem.Scheme, n, workers int) ([]kem.PublicKey, []kem.PrivateKey, error) {
+	if sch == nil {
+		return nil, nil, errors.New("schemes: KEM scheme cannot be nil")
+	}
+	if n < 0 {
+		return nil, nil, fmt.Errorf("schemes: invalid key pair count %d", n)
+	}
+	if workers < 1 {
+		return nil, nil, fmt.Errorf("schemes: invalid worker count %d", workers)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	pubKeys := make([]kem.PublicKey, n)
+	privKeys := make([]kem.PrivateKey, n)
+	errs := make([]error, workers)
+
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := range jobs {
+				if errs[w] != nil {
+					continue
+				}
+				pubKeys[i], privKeys[i], errs[w] = sch.GenerateKeyPair()
+			}
+		}(w)
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return pubKeys, privKeys, nil
+}
+
+// Deriveable returns the supported schemes which can derive key pairs
+// deterministically from a seed, as reported by kem.Capabilities, in
+// the same order as All. Every scheme currently built into this package
+// can, but schemes which neither report their capabilities nor come
+// from circl are left out, as are hybrids with such a component. Use
+// kem.DeriveKeyPair to derive keys with an error rather than a panic
+// for other schemes.
+func Deriveable() []kem.Scheme {
+	deriveable := []kem.Scheme{}
+	for _, s := range All() {
+		if kem.Capabilities(s).Has(kem.CapDeterministicKeygen) {
+			deriveable = append(deriveable, s)
+		}
+	}
+	return deriveable
+}
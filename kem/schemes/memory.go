@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// ErrInsufficientMemory is returned by CheckKeygenMemory when key
+// generation would likely exceed the available memory.
+var ErrInsufficientMemory = errors.New("schemes: insufficient memory for key generation")
+
+// KeygenMemory returns an upper bound, in bytes, on the peak heap used
+// while generating a key pair of the given scheme, or false if the
+// scheme has no Classic McEliece component and so needs no more than
+// the size of its keys. Composite schemes are bounded by the sum of
+// their components.
+func KeygenMemory(s kem.Scheme) (uint64, bool) {
+	for {
+		u, ok := s.(interface{ Unwrap() kem.Scheme })
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	if info, _ := Info(s.Name()); info.KeygenMemory != 0 {
+		return info.KeygenMemory, true
+	}
+	c, ok := s.(kem.Composite)
+	if !ok {
+		return 0, false
+	}
+	var total uint64
+	found := false
+	for _, component := range c.Components() {
+		if n, ok := KeygenMemory(component); ok {
+			total += n
+			found = true
+		} else {
+			total += uint64(component.PublicKeySize() + component.PrivateKeySize())
+		}
+	}
+	return total, found
+}
+
+// CheckKeygenMemory returns an error wrapping ErrInsufficientMemory if
+// KeygenMemory of the given scheme exceeds available bytes. If available
+// is 0, the Go runtime's soft memory limit (set with GOMEMLIMIT or
+// debug.SetMemoryLimit) less the heap currently allocated is used, which
+// never fails when no limit is set. Call it before generating keys of
+// large schemes on constrained devices, where running out of memory
+// would otherwise kill the process.
+func CheckKeygenMemory(s kem.Scheme, available uint64) error {
+	need, ok := KeygenMemory(s)
+	if !ok {
+		return nil
+	}
+	if available == 0 {
+		limit := debug.SetMemoryLimit(-1)
+		if limit == math.MaxInt64 {
+			return nil
+		}
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		if uint64(limit) > stats.HeapAlloc {
+			available = uint64(limit) - stats.HeapAlloc
+		}
+	}
+	if need > available {
+		return fmt.Errorf("%w: %s needs about %d bytes, %d available", ErrInsufficientMemory, s.Name(), need, available)
+	}
+	return nil
+}
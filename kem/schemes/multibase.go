@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// multibaseBase58BTC is the multibase prefix of base58btc strings.
+const multibaseBase58BTC = 'z'
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// bigDigits are the digits math/big uses for base 58, which we map
+// to and from base58Alphabet.
+const bigDigits = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUV"
+
+var (
+	// ErrMultibaseEncoding is returned by UnmarshalMultibase if the
+	// string isn't a base58btc multibase encoded multicodec value.
+	ErrMultibaseEncoding = errors.New("schemes: invalid multibase encoding")
+
+	// ErrUnknownMulticodec is returned by MarshalMultibase and
+	// UnmarshalMultibase for schemes without a multicodec.
+	ErrUnknownMulticodec = errors.New("schemes: no multicodec for scheme")
+)
+
+// Multicodec returns the multicodec used for public keys of the given
+// scheme by MarshalMultibase, as given by its SchemeInfo.
+func Multicodec(s kem.Scheme) (uint64, bool) {
+	info, _ := Info(s.Name())
+	return info.Multicodec, info.Multicodec != 0
+}
+
+// MarshalMultibase encodes the public key as used in DID documents: the
+// unsigned varint multicodec of its scheme followed by the key bytes,
+// base58btc encoded with the multibase prefix 'z'.
+func MarshalMultibase(pk kem.PublicKey) (string, error) {
+	code, ok := Multicodec(pk.Scheme())
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownMulticodec, pk.Scheme().Name())
+	}
+	blob, err := pk.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	b := binary.AppendUvarint(make([]byte, 0, binary.MaxVarintLen64+len(blob)), code)
+	b = append(b, blob...)
+	return string(multibaseBase58BTC) + base58Encode(b), nil
+}
+
+// UnmarshalMultibase decodes a public key encoded by MarshalMultibase,
+// picking the scheme by its multicodec.
+func UnmarshalMultibase(s string) (kem.PublicKey, error) {
+	if len(s) < 2 || s[0] != multibaseBase58BTC {
+		return nil, ErrMultibaseEncoding
+	}
+	b, ok := base58Decode(s[1:])
+	if !ok {
+		return nil, ErrMultibaseEncoding
+	}
+	code, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, ErrMultibaseEncoding
+	}
+	for _, scheme := range All() {
+		if c, ok := Multicodec(scheme); ok && c == code {
+			return scheme.UnmarshalBinaryPublicKey(b[n:])
+		}
+	}
+	return nil, fmt.Errorf("%w: %#x", ErrUnknownMulticodec, code)
+}
+
+func base58Encode(b []byte) string {
+	zeros := 0
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	digits := ""
+	if zeros < len(b) {
+		digits = new(big.Int).SetBytes(b[zeros:]).Text(58)
+	}
+	out := make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		out[i] = base58Alphabet[0]
+	}
+	for i := 0; i < len(digits); i++ {
+		out[zeros+i] = base58Alphabet[strings.IndexByte(bigDigits, digits[i])]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, bool) {
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	digits := make([]byte, len(s)-zeros)
+	for i := range digits {
+		j := strings.IndexByte(base58Alphabet, s[zeros+i])
+		if j < 0 {
+			return nil, false
+		}
+		digits[i] = bigDigits[j]
+	}
+	out := make([]byte, zeros)
+	if len(digits) > 0 {
+		n, ok := new(big.Int).SetString(string(digits), 58)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, n.Bytes()...)
+	}
+	return out, true
+}
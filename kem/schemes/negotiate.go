@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// Negotiate selects the scheme to use given the names of the schemes
+// supported locally and by the remote peer. Of the schemes named in
+// both lists, the one appearing earliest in preference is returned.
+// If none of the common schemes are in preference, the first common
+// scheme in local order is returned instead. Names are compared case
+// insensitively and names of unknown schemes are ignored. The boolean
+// is false if there are no common schemes.
+func Negotiate(local, remote []string, preference []string) (kem.Scheme, bool) {
+	remoteSet := make(map[string]bool, len(remote))
+	for _, name := range remote {
+		remoteSet[strings.ToLower(name)] = true
+	}
+	common := make(map[string]bool)
+	commonOrdered := []string{}
+	for _, name := range local {
+		name = strings.ToLower(name)
+		if !remoteSet[name] || common[name] || ByName(name) == nil {
+			continue
+		}
+		common[name] = true
+		commonOrdered = append(commonOrdered, name)
+	}
+	for _, name := range preference {
+		if common[strings.ToLower(name)] {
+			return ByName(name), true
+		}
+	}
+	if len(commonOrdered) > 0 {
+		return ByName(commonOrdered[0]), true
+	}
+	return nil, false
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"io"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/util"
+)
+
+// Registry gives access to the supported schemes with their randomness
+// drawn from a given reader, such as an audited entropy source. See
+// WithReader.
+type Registry struct {
+	r io.Reader
+}
+
+// WithReader returns a Registry whose schemes draw all the randomness
+// for key generation from r, and for encapsulation too where the scheme
+// supports it, instead of the random source they were built with.
+//
+// Key pairs are generated by reading SeedSize bytes from r and passing
+// them to DeriveKeyPair. Encapsulation reads a seed from r and uses
+// EncapsulateDeterministically if the scheme implements
+// kem.DeterministicEncapsulator, which ML-KEM-768, X-Wing and the circl
+// schemes don't, so they and any combiner using them still encapsulate
+// with their own random source; EncapsulatesWithReader reports which is
+// the case. Decapsulation needs no randomness.
+//
+// The returned schemes wrap the registered ones, and keys made or
+// unmarshaled by them report the registered scheme as their Scheme.
+func WithReader(r io.Reader) *Registry {
+	return &Registry{r: r}
+}
+
+// Reader returns the reader the registry's schemes draw from.
+func (reg *Registry) Reader() io.Reader {
+	return reg.r
+}
+
+// ByName returns the named scheme bound to the registry's reader, or
+// nil if there is no such scheme.
+func (reg *Registry) ByName(name string) kem.Scheme {
+	s := ByName(name)
+	if s == nil {
+		return nil
+	}
+	return reg.bind(s)
+}
+
+// All returns all the supported schemes bound to the registry's reader.
+func (reg *Registry) All() []kem.Scheme {
+	all := All()
+	out := make([]kem.Scheme, len(all))
+	for i, s := range all {
+		out[i] = reg.bind(s)
+	}
+	return out
+}
+
+func (reg *Registry) bind(s kem.Scheme) kem.Scheme {
+	b := &ReaderScheme{Scheme: s, r: reg.r}
+	if c, ok := s.(kem.Composite); ok {
+		return &compositeReaderScheme{b, c}
+	}
+	return b
+}
+
+// ReaderScheme is a scheme bound to a reader by a Registry.
+type ReaderScheme struct {
+	kem.Scheme
+	r io.Reader
+}
+
+// compositeReaderScheme keeps composite schemes recognizable as such.
+type compositeReaderScheme struct {
+	*ReaderScheme
+	kem.Composite
+}
+
+// Name resolves the ambiguity between the embedded interfaces.
+func (s *compositeReaderScheme) Name() string {
+	return s.ReaderScheme.Name()
+}
+
+// Reader returns the reader the scheme draws from.
+func (s *ReaderScheme) Reader() io.Reader {
+	return s.r
+}
+
+// Unwrap returns the registered scheme.
+func (s *ReaderScheme) Unwrap() kem.Scheme {
+	return s.Scheme
+}
+
+// Circl returns the circl scheme underneath the registered scheme, if
+// any, as described for kem.Circl. It is unstable and meant for
+// advanced use.
+func (s *ReaderScheme) Circl() interface{} {
+	return kem.Circl(s.Scheme)
+}
+
+// EncapsulatesWithReader returns true if Encapsulate draws its
+// randomness from the reader, and false if the scheme can't encapsulate
+// deterministically and so uses its own random source.
+func (s *ReaderScheme) EncapsulatesWithReader() bool {
+	d, ok := s.Scheme.(kem.DeterministicEncapsulator)
+	return ok && d.EncapsulationSeedSize() > 0
+}
+
+// GenerateKeyPair derives a key pair from a seed read from the reader.
+func (s *ReaderScheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	seed := make([]byte, s.Scheme.SeedSize())
+	defer util.ExplicitBzero(seed)
+	if _, err := io.ReadFull(s.r, seed); err != nil {
+		return nil, nil, err
+	}
+	pk, sk := s.Scheme.DeriveKeyPair(seed)
+	return pk, sk, nil
+}
+
+// Encapsulate encapsulates with a seed read from the reader if
+// EncapsulatesWithReader returns true, otherwise it calls the
+// registered scheme's Encapsulate.
+func (s *ReaderScheme) Encapsulate(pk kem.PublicKey) (ct, ss []byte, err error) {
+	if !s.EncapsulatesWithReader() {
+		return s.Scheme.Encapsulate(pk)
+	}
+	d := s.Scheme.(kem.DeterministicEncapsulator)
+	seed := make([]byte, d.EncapsulationSeedSize())
+	defer util.ExplicitBzero(seed)
+	if _, err := io.ReadFull(s.r, seed); err != nil {
+		return nil, nil, err
+	}
+	return d.EncapsulateDeterministically(pk, seed)
+}
+
+// ImplicitRejection reports whether the registered scheme uses
+// implicit rejection.
+func (s *ReaderScheme) ImplicitRejection() bool {
+	return kem.ImplicitRejection(s.Scheme)
+}
+
+// Capabilities returns the capabilities of the registered scheme.
+func (s *ReaderScheme) Capabilities() kem.Caps {
+	return kem.Capabilities(s.Scheme)
+}
@@ -32,34 +32,92 @@ import (
 	"github.com/katzenpost/hpqc/rand"
 )
 
-var potentialSchemes = [...]kem.Scheme{
+// requiresCTIDH describes the build requirement of the CTIDH schemes.
+const requiresCTIDH = "CTIDH support (cgo and the highctidh library)"
+
+// SchemeInfo holds what this package knows about a registered scheme
+// beyond what the kem.Scheme interface tells. Zero values mean that
+// nothing is known, or that the field doesn't apply to the scheme.
+type SchemeInfo struct {
+	// SecurityLevel is our rough estimate of the security of the
+	// scheme as a NIST PQC security category from 1 to 5. Classical
+	// schemes are rated by their classical security alone, and hybrids
+	// by their strongest component. The CTIDH ratings are lower than
+	// their classical security would suggest, reflecting the ongoing
+	// debate about the quantum cost of attacking CSIDH like schemes.
+	SecurityLevel int
+
+	// Multicodec identifies public keys of the scheme in
+	// MarshalMultibase. Only x25519-pub and mlkem-768-pub are
+	// registered in the multicodec table; every other scheme uses a
+	// code from the private use range starting at 0x300000. These values
+	// are part of the encoding and must never be changed or reused.
+	Multicodec uint64
+
+	// DeprecationNote explains why the scheme is deprecated and what to
+	// use instead.
+	DeprecationNote string
+
+	// KeygenMemory is the peak heap used by one key generation attempt
+	// of the Classic McEliece parameter sets, measured with the garbage
+	// collector disabled and rounded up to the next MiB. Most of it is
+	// the systematic form of the public key matrix, which our circl fork
+	// builds in one piece; it has no streaming construction, so this
+	// can't be lowered short of changing circl. The non "f" parameter
+	// sets retry with fresh randomness when the matrix isn't systematic,
+	// but the garbage of failed attempts is collectable, so the peak is
+	// that of a single attempt. Every other scheme needs little more
+	// than the size of its keys.
+	KeygenMemory uint64
+}
+
+// potentialScheme is an entry of the registry. The scheme is nil if it
+// depends on the build configuration and wasn't built, in which case
+// requires describes what it needs.
+type potentialScheme struct {
+	name     string
+	requires string
+	scheme   kem.Scheme
+	info     SchemeInfo
+}
+
+// builtin returns the registry entry of a scheme which is always built.
+func builtin(s kem.Scheme, info SchemeInfo) potentialScheme {
+	return potentialScheme{name: s.Name(), scheme: s, info: info}
+}
+
+// potentialSchemes are the schemes which depend on the build
+// configuration. Those which are built follow builtinSchemes in All.
+var potentialSchemes = [...]potentialScheme{
 
 	// PQ KEMs
 
-	adapter.FromNIKE(ctidh511.Scheme()),
-	adapter.FromNIKE(ctidh512.Scheme()),
-	adapter.FromNIKE(ctidh1024.Scheme()),
-	adapter.FromNIKE(ctidh2048.Scheme()),
+	{name: "ctidh511", requires: requiresCTIDH, scheme: adapter.FromNIKE(ctidh511.Scheme()), info: SchemeInfo{SecurityLevel: 1, Multicodec: 0x30001c}},
+	{name: "ctidh512", requires: requiresCTIDH, scheme: adapter.FromNIKE(ctidh512.Scheme()), info: SchemeInfo{SecurityLevel: 1, Multicodec: 0x30001d}},
+	{name: "ctidh1024", requires: requiresCTIDH, scheme: adapter.FromNIKE(ctidh1024.Scheme()), info: SchemeInfo{SecurityLevel: 2, Multicodec: 0x30001e}},
+	{name: "ctidh2048", requires: requiresCTIDH, scheme: adapter.FromNIKE(ctidh2048.Scheme()), info: SchemeInfo{SecurityLevel: 3, Multicodec: 0x30001f}},
 
 	// hybrid KEMs
 
-	combiner.New(
+	{name: "CTIDH512-X25519", requires: requiresCTIDH, scheme: combiner.New(
 		"CTIDH512-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(ctidh512.Scheme()),
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 		},
-	),
-	combiner.New(
+	), info: SchemeInfo{SecurityLevel: 1, Multicodec: 0x300020}},
+	{name: "CTIDH1024-X448", requires: requiresCTIDH, scheme: combiner.New(
 		"CTIDH1024-X448",
 		[]kem.Scheme{
 			adapter.FromNIKE(ctidh1024.Scheme()),
 			adapter.FromNIKE(x448.Scheme(rand.Reader)),
 		},
-	),
+	), info: SchemeInfo{SecurityLevel: 2, Multicodec: 0x300021}},
 }
 
-var allSchemes = []kem.Scheme{
+// builtinSchemes are the schemes which are always built, in the order
+// returned by All.
+var builtinSchemes = [...]potentialScheme{
 
 	// classical KEM schemes (converted from NIKE via hashed elgamal construction)
 
@@ -67,141 +125,176 @@ var allSchemes = []kem.Scheme{
 	// https://github.com/katzenpost/hpqc/issues/39
 	//adapter.FromNIKE(diffiehellman.Scheme()),
 
-	adapter.FromNIKE(x25519.Scheme(rand.Reader)),
-	adapter.FromNIKE(x448.Scheme(rand.Reader)),
+	builtin(adapter.FromNIKE(x25519.Scheme(rand.Reader)), SchemeInfo{SecurityLevel: 1, Multicodec: 0xec}),
+	builtin(adapter.FromNIKE(x448.Scheme(rand.Reader)), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300001}),
 
 	// post quantum KEM schemes
 
-	mlkem768.Scheme(),
-	sntrup.Scheme(),
-	frodo640shake.Scheme(),
-	mceliece348864.Scheme(),
-	mceliece348864f.Scheme(),
-	mceliece460896.Scheme(),
-	mceliece460896f.Scheme(),
-	mceliece6688128.Scheme(),
-	mceliece6688128f.Scheme(),
-	mceliece6960119.Scheme(),
-	mceliece6960119f.Scheme(),
-	mceliece8192128.Scheme(),
-	mceliece8192128f.Scheme(),
+	builtin(mlkem768.Scheme(), SchemeInfo{SecurityLevel: 3, Multicodec: 0x120c}),
+	builtin(sntrup.Scheme(), SchemeInfo{SecurityLevel: 2, Multicodec: 0x300002}),
+	// FrodoKEM-976-SHAKE and FrodoKEM-1344-SHAKE can be added here, along
+	// with X25519 combiners, once our circl fork provides them; it
+	// currently only ships the 640 parameter set.
+	builtin(frodo640shake.Scheme(), SchemeInfo{SecurityLevel: 1, Multicodec: 0x300003}),
+	builtin(mceliece348864.Scheme(), SchemeInfo{SecurityLevel: 1, Multicodec: 0x300004, KeygenMemory: 1 << 20}),
+	builtin(mceliece348864f.Scheme(), SchemeInfo{SecurityLevel: 1, Multicodec: 0x300005, KeygenMemory: 1 << 20}),
+	builtin(mceliece460896.Scheme(), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300006, KeygenMemory: 2 << 20}),
+	builtin(mceliece460896f.Scheme(), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300007, KeygenMemory: 2 << 20}),
+	builtin(mceliece6688128.Scheme(), SchemeInfo{SecurityLevel: 5, Multicodec: 0x300008, KeygenMemory: 4 << 20}),
+	builtin(mceliece6688128f.Scheme(), SchemeInfo{SecurityLevel: 5, Multicodec: 0x300009, KeygenMemory: 4 << 20}),
+	builtin(mceliece6960119.Scheme(), SchemeInfo{SecurityLevel: 5, Multicodec: 0x30000a, KeygenMemory: 4 << 20}),
+	builtin(mceliece6960119f.Scheme(), SchemeInfo{SecurityLevel: 5, Multicodec: 0x30000b, KeygenMemory: 4 << 20}),
+	builtin(mceliece8192128.Scheme(), SchemeInfo{SecurityLevel: 5, Multicodec: 0x30000c, KeygenMemory: 5 << 20}),
+	builtin(mceliece8192128f.Scheme(), SchemeInfo{SecurityLevel: 5, Multicodec: 0x30000d, KeygenMemory: 5 << 20}),
 
 	// hybrid KEM schemes
 
-	xwing.Scheme(),
+	builtin(xwing.Scheme(), SchemeInfo{SecurityLevel: 3, Multicodec: 0x30000e}),
 
-	// XXX TODO: must soon deprecate use of "hybrid.New" in favour of "combiner.New".
-	// We'd also like to remove Kyber now that we have mlkem768.
-	hybrid.New(
+	// Deprecated in favour of MLKEM768-X25519. It will be removed along
+	// with hybrid.New in a future release.
+	builtin(hybrid.New(
 		"Kyber768-X25519",
 		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 		kyber768.Scheme(),
-	),
+	), SchemeInfo{
+		SecurityLevel:   3,
+		Multicodec:      0x30000f,
+		DeprecationNote: "Kyber768-X25519 combines round 3 Kyber using hybrid.New; use MLKEM768-X25519 instead",
+	}),
 
 	// If Xwing is not the PQ Hybrid KEM you are looking for then we recommend
 	// using our secure generic KEM combiner:
-	combiner.New(
+	builtin(combiner.New(
 		"MLKEM768-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mlkem768.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300010}),
+	builtin(combiner.New(
 		"MLKEM768-X448",
 		[]kem.Scheme{
 			adapter.FromNIKE(x448.Scheme(rand.Reader)),
 			mlkem768.Scheme(),
 		},
-	),
+	), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300011}),
 
 	// all the Classic McEliece's from our fork of circl
-	combiner.New(
+	builtin(combiner.New(
 		"mceliece348864-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece348864.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 1, Multicodec: 0x300012}),
+	builtin(combiner.New(
 		"mceliece348864f-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece348864f.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 1, Multicodec: 0x300013}),
+	builtin(combiner.New(
 		"mceliece460896-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece460896.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300014}),
+	builtin(combiner.New(
 		"mceliece460896f-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece460896f.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 3, Multicodec: 0x300015}),
+	builtin(combiner.New(
 		"mceliece6688128-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece6688128.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 5, Multicodec: 0x300016}),
+	builtin(combiner.New(
 		"mceliece6688128f-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece6688128f.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 5, Multicodec: 0x300017}),
+	builtin(combiner.New(
 		"mceliece6960119-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece6960119.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 5, Multicodec: 0x300018}),
+	builtin(combiner.New(
 		"mceliece6960119f-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece6960119f.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 5, Multicodec: 0x300019}),
+	builtin(combiner.New(
 		"mceliece8192128-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece8192128.Scheme(),
 		},
-	),
-	combiner.New(
+	), SchemeInfo{SecurityLevel: 5, Multicodec: 0x30001a}),
+	builtin(combiner.New(
 		"mceliece8192128f-X25519",
 		[]kem.Scheme{
 			adapter.FromNIKE(x25519.Scheme(rand.Reader)),
 			mceliece8192128f.Scheme(),
 		},
-	),
+	), SchemeInfo{SecurityLevel: 5, Multicodec: 0x30001b}),
 }
 
-var allSchemeNames map[string]kem.Scheme
+var (
+	// registry holds the entries of builtinSchemes and
+	// potentialSchemes, plus those registered by tests.
+	registry       []potentialScheme
+	allSchemes     []kem.Scheme
+	allSchemeNames map[string]kem.Scheme
+
+	// schemeInfos holds the SchemeInfo of every registry entry, built or
+	// not, keyed by lower case name.
+	schemeInfos map[string]SchemeInfo
+)
 
 func init() {
 	allSchemeNames = make(map[string]kem.Scheme)
-	for _, scheme := range potentialSchemes {
-		if scheme != nil {
-			allSchemes = append(allSchemes, scheme)
-		}
+	schemeInfos = make(map[string]SchemeInfo)
+	for _, p := range builtinSchemes {
+		register(p)
+	}
+	for _, p := range potentialSchemes {
+		register(p)
 	}
-	for _, scheme := range allSchemes {
-		allSchemeNames[strings.ToLower(scheme.Name())] = scheme
+}
+
+// register adds an entry to the registry. Its scheme, if built, is
+// appended to All.
+func register(p potentialScheme) {
+	registry = append(registry, p)
+	schemeInfos[strings.ToLower(p.name)] = p.info
+	if p.scheme != nil {
+		allSchemes = append(allSchemes, p.scheme)
+		allSchemeNames[strings.ToLower(p.scheme.Name())] = p.scheme
 	}
 }
 
+// Info returns the metadata of the named scheme, which may be left out
+// of this build. Names are compared case insensitively. The boolean is
+// false if the scheme isn't supported by this package.
+func Info(name string) (SchemeInfo, bool) {
+	info, ok := schemeInfos[strings.ToLower(name)]
+	return info, ok
+}
+
 // ByName returns the NIKE scheme by string name.
 func ByName(name string) kem.Scheme {
 	ret := allSchemeNames[strings.ToLower(name)]
@@ -213,3 +306,49 @@ func All() []kem.Scheme {
 	a := allSchemes
 	return a[:]
 }
+
+// CandidatesForCiphertextSize returns the supported schemes whose
+// ciphertexts are exactly n bytes long, in the same order as All, for
+// guessing the scheme of an untagged ciphertext. Several schemes may
+// share a size, and a ciphertext of the right size may still be
+// garbage, so the result only narrows down the possibilities.
+func CandidatesForCiphertextSize(n int) []kem.Scheme {
+	candidates := []kem.Scheme{}
+	for _, s := range All() {
+		if s.CiphertextSize() == n {
+			candidates = append(candidates, s)
+		}
+	}
+	return candidates
+}
+
+// BuiltWith returns true if the named scheme is available in this
+// build. Names are compared case insensitively.
+func BuiltWith(name string) bool {
+	return ByName(name) != nil
+}
+
+// UnavailableNames returns the names of the schemes which are supported
+// by this package but were left out of this build, for instance because
+// a build requirement wasn't met. See UnavailableReason.
+func UnavailableNames() []string {
+	var names []string
+	for _, p := range registry {
+		if p.scheme == nil {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// UnavailableReason returns a description of what the named scheme
+// requires if it's supported by this package but was left out of this
+// build. The boolean is false if the scheme is available or unknown.
+func UnavailableReason(name string) (string, bool) {
+	for _, p := range registry {
+		if p.scheme == nil && strings.EqualFold(p.name, name) {
+			return p.requires, true
+		}
+	}
+	return "", false
+}
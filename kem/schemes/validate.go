@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// ErrRegistry indicates an inconsistency in the scheme registry found
+// by Validate.
+var ErrRegistry = errors.New("schemes: inconsistent registry")
+
+// Validate checks that every registered scheme can be found by ByName
+// under its own Name, in upper and lower case alike, and that no two
+// schemes have names which differ only in case. It returns an error
+// wrapping ErrRegistry which describes the first problem found, or nil.
+func Validate() error {
+	return validate(All())
+}
+
+func validate(all []kem.Scheme) error {
+	seen := make(map[string]string, len(all))
+	for _, s := range all {
+		name := s.Name()
+		lower := strings.ToLower(name)
+		if other, ok := seen[lower]; ok {
+			return fmt.Errorf("%w: %q and %q differ only in case", ErrRegistry, other, name)
+		}
+		seen[lower] = name
+		for _, n := range []string{name, lower, strings.ToUpper(name)} {
+			if ByName(n) != s {
+				return fmt.Errorf("%w: ByName(%q) doesn't return the scheme named %q", ErrRegistry, n, name)
+			}
+		}
+	}
+	return nil
+}
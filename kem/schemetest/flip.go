@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemetest
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+// FlipAndDecapsulate decapsulates ct with sk, then flips each bit of ct
+// in turn and decapsulates the result, checking that every tampered
+// ciphertext either fails to decapsulate or yields a different shared
+// secret. It returns an error describing the first bit for which
+// decapsulation returned the original shared secret or panicked, or if
+// ct itself doesn't decapsulate.
+//
+// This costs one decapsulation per ciphertext bit, which adds up for
+// schemes with large ciphertexts such as FrodoKEM.
+func FlipAndDecapsulate(sch kem.Scheme, sk kem.PrivateKey, ct []byte) error {
+	ss, err := decapsulate(sch, sk, ct)
+	if err != nil {
+		return fmt.Errorf("schemetest: %s: untampered ciphertext: %w", sch.Name(), err)
+	}
+	tampered := bytes.Clone(ct)
+	for i := 0; i < len(ct)*8; i++ {
+		tampered[i/8] ^= 1 << (i % 8)
+		ss2, err := decapsulate(sch, sk, tampered)
+		tampered[i/8] ^= 1 << (i % 8)
+		if err == nil && bytes.Equal(ss, ss2) {
+			return fmt.Errorf("schemetest: %s: flipping bit %d of the ciphertext gave the same shared secret", sch.Name(), i)
+		}
+		if p, ok := err.(panicError); ok {
+			return fmt.Errorf("schemetest: %s: flipping bit %d of the ciphertext: %v", sch.Name(), i, p)
+		}
+	}
+	return nil
+}
+
+// panicError holds the value recovered from a panic in decapsulate.
+type panicError struct {
+	v interface{}
+}
+
+func (p panicError) Error() string {
+	return fmt.Sprintf("decapsulation panicked: %v", p.v)
+}
+
+func decapsulate(sch kem.Scheme, sk kem.PrivateKey, ct []byte) (ss []byte, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panicError{v}
+		}
+	}()
+	return sch.Decapsulate(sk, ct)
+}
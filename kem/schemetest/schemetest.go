@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package schemetest provides a conformance test suite which checks the
+// properties every kem.Scheme is expected to have, for use by the tests
+// of new schemes as well as over all the registered schemes.
+package schemetest
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/schemes"
+	"github.com/katzenpost/hpqc/rand"
+)
+
+// RunAll runs Run as a subtest for one scheme of each family of
+// schemes.ByFamily, the one with the smallest public key, so that every
+// kind of implementation is checked without taking the time of the
+// slowest schemes. CTIDH takes about a second per operation, so outside
+// of its own family hybrids with a CTIDH component are passed over, and
+// the CTIDH family is skipped in short mode.
+func RunAll(t *testing.T) {
+	for _, s := range representatives() {
+		s := s
+		t.Run(s.Name(), func(t *testing.T) {
+			Run(t, s)
+		})
+	}
+}
+
+// representatives returns the schemes tested by RunAll, sorted by
+// family name.
+func representatives() []kem.Scheme {
+	byFamily := schemes.ByFamily()
+	names := make([]string, 0, len(byFamily))
+	for name := range byFamily {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var picked []kem.Scheme
+	for _, name := range names {
+		if name == schemes.FamilyCTIDH && testing.Short() {
+			continue
+		}
+		var smallest kem.Scheme
+		for _, s := range byFamily[name] {
+			if name != schemes.FamilyCTIDH && hasCTIDH(s) {
+				continue
+			}
+			if smallest == nil || s.PublicKeySize() < smallest.PublicKeySize() {
+				smallest = s
+			}
+		}
+		if smallest != nil {
+			picked = append(picked, smallest)
+		}
+	}
+	return picked
+}
+
+// hasCTIDH returns true if the scheme is a CTIDH scheme or a hybrid
+// with a CTIDH component.
+func hasCTIDH(s kem.Scheme) bool {
+	if c, ok := s.(kem.Composite); ok {
+		for _, component := range c.Components() {
+			if hasCTIDH(component) {
+				return true
+			}
+		}
+		return false
+	}
+	return schemes.Family(s) == schemes.FamilyCTIDH
+}
+
+// Run checks that the scheme satisfies the kem.Scheme contract:
+//   - keys and ciphertexts have the advertised sizes
+//   - keys survive a marshal and unmarshal round trip
+//   - DeriveKeyPair is deterministic
+//   - decapsulation recovers the encapsulated shared secret
+//   - decapsulating a tampered ciphertext, or with the wrong private key,
+//     fails or yields a different shared secret
+//   - wrongly sized keys and ciphertexts are rejected with an error
+func Run(t *testing.T, s kem.Scheme) {
+	t.Run("Keys", func(t *testing.T) { testKeys(t, s) })
+	t.Run("DeriveKeyPair", func(t *testing.T) { testDeriveKeyPair(t, s) })
+	t.Run("EncapDecap", func(t *testing.T) { testEncapDecap(t, s) })
+	t.Run("WrongCiphertext", func(t *testing.T) { testWrongCiphertext(t, s) })
+	t.Run("WrongSizes", func(t *testing.T) { testWrongSizes(t, s) })
+}
+
+func testKeys(t *testing.T, s kem.Scheme) {
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	require.Equal(t, s, pk.Scheme())
+	require.Equal(t, s, sk.Scheme())
+	require.True(t, sk.Public().Equal(pk))
+
+	pkBytes, err := pk.MarshalBinary()
+	require.NoError(t, err)
+	require.Len(t, pkBytes, s.PublicKeySize())
+	skBytes, err := sk.MarshalBinary()
+	require.NoError(t, err)
+	require.Len(t, skBytes, s.PrivateKeySize())
+
+	pk2, err := s.UnmarshalBinaryPublicKey(pkBytes)
+	require.NoError(t, err)
+	require.True(t, pk.Equal(pk2))
+	sk2, err := s.UnmarshalBinaryPrivateKey(skBytes)
+	require.NoError(t, err)
+	require.True(t, sk.Equal(sk2))
+	require.True(t, sk2.Public().Equal(pk))
+}
+
+func testDeriveKeyPair(t *testing.T, s kem.Scheme) {
+	seed := make([]byte, s.SeedSize())
+	_, err := rand.Reader.Read(seed)
+	require.NoError(t, err)
+
+	pk1, sk1 := s.DeriveKeyPair(seed)
+	pk2, sk2 := s.DeriveKeyPair(seed)
+	require.True(t, pk1.Equal(pk2))
+	require.True(t, sk1.Equal(sk2))
+
+	// Flip every byte, since some schemes such as FrodoKEM only use
+	// part of the seed for the public key.
+	for i := range seed {
+		seed[i] ^= 0xff
+	}
+	pk3, _ := s.DeriveKeyPair(seed)
+	require.False(t, pk1.Equal(pk3))
+}
+
+func testEncapDecap(t *testing.T, s kem.Scheme) {
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	require.Len(t, ct, s.CiphertextSize())
+	require.Len(t, ss, s.SharedKeySize())
+
+	ss2, err := s.Decapsulate(sk, ct)
+	require.NoError(t, err)
+	require.Equal(t, ss, ss2)
+
+	ct2, ss3, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	require.NotEqual(t, ct, ct2)
+	require.NotEqual(t, ss, ss3)
+}
+
+func testWrongCiphertext(t *testing.T, s kem.Scheme) {
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, ss, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+
+	for _, i := range []int{0, len(ct) / 2, len(ct) - 1} {
+		tampered := bytes.Clone(ct)
+		tampered[i] ^= 0x01
+		ss2, err := s.Decapsulate(sk, tampered)
+		if err == nil {
+			require.NotEqual(t, ss, ss2, "tampered byte %d", i)
+		}
+	}
+
+	_, otherSk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ss2, err := s.Decapsulate(otherSk, ct)
+	if err == nil {
+		require.NotEqual(t, ss, ss2)
+	}
+}
+
+func testWrongSizes(t *testing.T, s kem.Scheme) {
+	pk, sk, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, _, err := s.Encapsulate(pk)
+	require.NoError(t, err)
+	pkBytes, err := pk.MarshalBinary()
+	require.NoError(t, err)
+	skBytes, err := sk.MarshalBinary()
+	require.NoError(t, err)
+
+	_, err = s.Decapsulate(sk, ct[:len(ct)-1])
+	require.Error(t, err)
+	_, err = s.Decapsulate(sk, append(bytes.Clone(ct), 0))
+	require.Error(t, err)
+	_, err = s.UnmarshalBinaryPublicKey(pkBytes[:len(pkBytes)-1])
+	require.Error(t, err)
+	_, err = s.UnmarshalBinaryPrivateKey(skBytes[:len(skBytes)-1])
+	require.Error(t, err)
+}
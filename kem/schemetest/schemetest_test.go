@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemetest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/schemes"
+)
+
+func TestAllSchemes(t *testing.T) {
+	RunAll(t)
+}
+
+func TestRepresentatives(t *testing.T) {
+	picked := make(map[string]string)
+	for _, s := range representatives() {
+		family := schemes.Family(s)
+		require.NotContains(t, picked, family)
+		picked[family] = s.Name()
+	}
+	require.Equal(t, "x25519", picked[schemes.FamilyClassical])
+	require.Equal(t, "mceliece348864", picked[schemes.FamilyMcEliece])
+	require.NotContains(t, picked[schemes.FamilyHybrid], "CTIDH")
+}
+
+func TestFlipAndDecapsulate(t *testing.T) {
+	for _, name := range []string{"x25519", "MLKEM768", "XWING", "MLKEM768-X25519"} {
+		s := schemes.ByName(name)
+		pk, sk, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		ct, _, err := s.Encapsulate(pk)
+		require.NoError(t, err)
+
+		require.NoError(t, FlipAndDecapsulate(s, sk, ct), name)
+	}
+}
+
+// panickingScheme is a broken KEM which panics on tampered ciphertexts.
+type panickingScheme struct {
+	kem.Scheme
+	ct []byte
+}
+
+func (s *panickingScheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
+	if !bytes.Equal(ct, s.ct) {
+		panic("tampered")
+	}
+	return s.Scheme.Decapsulate(sk, ct)
+}
+
+func TestFlipAndDecapsulatePanic(t *testing.T) {
+	inner := schemes.ByName("x25519")
+	pk, sk, err := inner.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, _, err := inner.Encapsulate(pk)
+	require.NoError(t, err)
+
+	err = FlipAndDecapsulate(&panickingScheme{inner, ct}, sk, ct)
+	require.ErrorContains(t, err, "flipping bit 0")
+	require.ErrorContains(t, err, "panicked: tampered")
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import "errors"
+
+// ErrNotResettable is returned by WithPrivateKey when the private key
+// can't be fully reset.
+var ErrNotResettable = errors.New("kem: private key cannot be reset")
+
+// Resettable returns true if Reset zeroizes all of the key material of
+// sk. That is the case if sk implements Resetter, unless it also has a
+// Resettable method reporting otherwise, as composite keys do when one
+// of their components isn't a Resetter.
+func Resettable(sk PrivateKey) bool {
+	if _, ok := sk.(Resetter); !ok {
+		return false
+	}
+	if r, ok := sk.(interface{ Resettable() bool }); ok {
+		return r.Resettable()
+	}
+	return true
+}
+
+// WithPrivateKey calls fn with the given private key and resets the key
+// once fn returns or panics, so that its secret material doesn't linger
+// in memory. It returns the error returned by fn, or ErrNotResettable
+// without calling fn if the key can't be fully reset.
+func WithPrivateKey(sk PrivateKey, fn func(PrivateKey) error) error {
+	if !Resettable(sk) {
+		return ErrNotResettable
+	}
+	defer sk.(Resetter).Reset()
+	return fn(sk)
+}
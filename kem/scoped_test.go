@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/katzenpost/circl/kem/kyber/kyber768"
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/adapter"
+	"github.com/katzenpost/hpqc/kem/combiner"
+	"github.com/katzenpost/hpqc/kem/hybrid"
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/util"
+)
+
+func TestWithPrivateKey(t *testing.T) {
+	s := combiner.New("X25519-X448", []kem.Scheme{
+		adapter.FromNIKE(x25519.Scheme(rand.Reader)),
+		adapter.FromNIKE(x448.Scheme(rand.Reader)),
+	})
+	pubKey, privKey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	ct, ss1, err := s.Encapsulate(pubKey)
+	require.NoError(t, err)
+
+	errTest := errors.New("test")
+	err = kem.WithPrivateKey(privKey, func(sk kem.PrivateKey) error {
+		ss2, err := s.Decapsulate(sk, ct)
+		require.NoError(t, err)
+		require.Equal(t, ss1, ss2)
+		return errTest
+	})
+	require.ErrorIs(t, err, errTest)
+
+	blob, err := privKey.MarshalBinary()
+	require.NoError(t, err)
+	require.True(t, util.CtIsZero(blob))
+
+	// keys are reset even when fn panics
+	_, privKey, err = s.GenerateKeyPair()
+	require.NoError(t, err)
+	require.Panics(t, func() {
+		kem.WithPrivateKey(privKey, func(kem.PrivateKey) error {
+			panic("oops")
+		})
+	})
+	blob, err = privKey.MarshalBinary()
+	require.NoError(t, err)
+	require.True(t, util.CtIsZero(blob))
+}
+
+func TestWithPrivateKeyNotResettable(t *testing.T) {
+	x25519Scheme := adapter.FromNIKE(x25519.Scheme(rand.Reader))
+	_, privKey, err := x25519Scheme.GenerateKeyPair()
+	require.NoError(t, err)
+	require.True(t, kem.Resettable(privKey))
+
+	// circl's keys don't implement kem.Resetter, so neither can
+	// composite keys containing them be fully reset
+	for _, s := range []kem.Scheme{
+		kyber768.Scheme(),
+		combiner.New("Kyber768-X25519", []kem.Scheme{kyber768.Scheme(), x25519Scheme}),
+		hybrid.New("Kyber768-X25519", kyber768.Scheme(), x25519Scheme),
+	} {
+		_, privKey, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		require.False(t, kem.Resettable(privKey), s.Name())
+
+		called := false
+		err = kem.WithPrivateKey(privKey, func(kem.PrivateKey) error {
+			called = true
+			return nil
+		})
+		require.ErrorIs(t, err, kem.ErrNotResettable, s.Name())
+		require.False(t, called, s.Name())
+	}
+}
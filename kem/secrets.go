@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"crypto/hmac"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/hkdf"
+)
+
+// The HKDF-Expand info strings used by SplitSecret.
+const (
+	SplitSecretEncLabel = "hpqc kem split secret v1 encryption"
+	SplitSecretMACLabel = "hpqc kem split secret v1 mac"
+)
+
+// SecretsEqual compares two shared secrets in constant time and should
+// be used instead of bytes.Equal, for example in key confirmation, to
+// avoid creating a timing oracle. Secrets of different lengths are never
+// equal; in that case it returns false immediately, which reveals only
+// that the lengths differ and nothing about the contents.
+func SecretsEqual(a, b []byte) bool {
+	return hmac.Equal(a, b)
+}
+
+// SplitSecret derives independent encryption and MAC keys from a shared
+// secret, so that the same bytes are never used for both. Each key is
+// the 32 byte output of HKDF-Expand (RFC 5869) with BLAKE2b-256 as the
+// hash, the shared secret as the pseudorandom key and
+// SplitSecretEncLabel or SplitSecretMACLabel as the info string. No
+// extract step is done since KEM shared secrets are already uniformly
+// random. It panics if ss is shorter than 32 bytes.
+func SplitSecret(ss []byte) (encKey, macKey [32]byte) {
+	if len(ss) < blake2b.Size256 {
+		panic("kem: shared secret too short to split")
+	}
+	expand(encKey[:], ss, SplitSecretEncLabel)
+	expand(macKey[:], ss, SplitSecretMACLabel)
+	return
+}
+
+func expand(out, prk []byte, info string) {
+	h := func() hash.Hash {
+		h, err := blake2b.New256(nil)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}
+	if _, err := io.ReadFull(hkdf.Expand(h, prk, []byte(info)), out); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"crypto/hmac"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestSecretsEqual(t *testing.T) {
+	a := []byte("0123456789abcdef0123456789abcdef")
+	b := []byte("0123456789abcdef0123456789abcdef")
+	c := []byte("0123456789abcdef0123456789abcdeF")
+
+	require.True(t, SecretsEqual(a, b))
+	require.False(t, SecretsEqual(a, c))
+	require.False(t, SecretsEqual(a, a[:31]))
+	require.True(t, SecretsEqual(nil, []byte{}))
+}
+
+func TestSplitSecret(t *testing.T) {
+	ss := []byte("0123456789abcdef0123456789abcdef")
+
+	encKey, macKey := SplitSecret(ss)
+	require.NotEqual(t, encKey, macKey)
+	require.NotEqual(t, ss, encKey[:])
+
+	// A single HKDF-Expand block is HMAC(prk, info || 0x01).
+	mac := hmac.New(func() hash.Hash {
+		h, _ := blake2b.New256(nil)
+		return h
+	}, ss)
+	mac.Write([]byte(SplitSecretEncLabel))
+	mac.Write([]byte{1})
+	require.Equal(t, mac.Sum(nil), encKey[:])
+
+	encKey2, macKey2 := SplitSecret(ss)
+	require.Equal(t, encKey, encKey2)
+	require.Equal(t, macKey, macKey2)
+
+	require.Panics(t, func() { SplitSecret(ss[:31]) })
+}
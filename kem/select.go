@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// ErrInvalidSelector is returned by SelectPublicKey if the bit isn't 0
+// or 1.
+var ErrInvalidSelector = errors.New("kem: selector bit must be 0 or 1")
+
+// SelectPublicKey returns a copy of a if bit is 1 and of b if bit is 0,
+// without branching on bit. Both keys are marshaled, the bytes of one
+// are chosen with subtle.ConstantTimeCopy and the result is unmarshaled
+// by the scheme of a.
+//
+// The keys must belong to the same scheme, whose public keys all have
+// the same length of PublicKeySize bytes, as is the case for every
+// scheme in this module. Only the selection is constant time: whether
+// UnmarshalBinaryPublicKey takes the same time for every key depends
+// on the scheme, so schemes which validate or decompress keys while
+// unmarshaling them, such as those built on elliptic curve NIKEs, may
+// leak which key was chosen through timing.
+func SelectPublicKey(bit int, a, b PublicKey) (PublicKey, error) {
+	if bit&^1 != 0 {
+		return nil, ErrInvalidSelector
+	}
+	if a.Scheme() != b.Scheme() {
+		return nil, ErrTypeMismatch
+	}
+	sch := a.Scheme()
+	aBytes, err := a.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	bBytes, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if len(aBytes) != sch.PublicKeySize() {
+		return nil, PubKeySizeError(sch.PublicKeySize(), len(aBytes))
+	}
+	if len(bBytes) != sch.PublicKeySize() {
+		return nil, PubKeySizeError(sch.PublicKeySize(), len(bBytes))
+	}
+	out := make([]byte, len(bBytes))
+	copy(out, bBytes)
+	subtle.ConstantTimeCopy(bit, out, aBytes)
+	return sch.UnmarshalBinaryPublicKey(out)
+}
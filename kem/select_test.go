@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+	"github.com/katzenpost/hpqc/kem/mlkem768"
+	"github.com/katzenpost/hpqc/kem/xwing"
+)
+
+func TestSelectPublicKey(t *testing.T) {
+	s := mlkem768.Scheme()
+	a, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	b, _, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+
+	pk, err := kem.SelectPublicKey(1, a, b)
+	require.NoError(t, err)
+	require.True(t, pk.Equal(a))
+
+	pk, err = kem.SelectPublicKey(0, a, b)
+	require.NoError(t, err)
+	require.True(t, pk.Equal(b))
+
+	_, err = kem.SelectPublicKey(2, a, b)
+	require.ErrorIs(t, err, kem.ErrInvalidSelector)
+	_, err = kem.SelectPublicKey(-1, a, b)
+	require.ErrorIs(t, err, kem.ErrInvalidSelector)
+
+	other, _, err := xwing.Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	_, err = kem.SelectPublicKey(1, a, other)
+	require.ErrorIs(t, err, kem.ErrTypeMismatch)
+}
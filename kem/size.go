@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem
+
+import "fmt"
+
+// SizeError reports a key or ciphertext of the wrong length, saying by
+// how much it is off. Err is one of ErrPubKeySize, ErrPrivKeySize,
+// ErrCiphertextSize or ErrSeedSize, so the sentinels can still be
+// matched with errors.Is, and errors.As gives access to the sizes:
+//
+//	var sizeErr *kem.SizeError
+//	if errors.As(err, &sizeErr) {
+//		log.Printf("expected %d got %d", sizeErr.Expected, sizeErr.Got)
+//	}
+type SizeError struct {
+	Err      error
+	Expected int
+	Got      int
+}
+
+// Error returns the sentinel's message followed by the sizes.
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("%s: expected %d got %d", e.Err, e.Expected, e.Got)
+}
+
+// Unwrap returns the sentinel error.
+func (e *SizeError) Unwrap() error {
+	return e.Err
+}
+
+// PubKeySizeError returns a SizeError wrapping ErrPubKeySize.
+func PubKeySizeError(expected, got int) error {
+	return &SizeError{Err: ErrPubKeySize, Expected: expected, Got: got}
+}
+
+// PrivKeySizeError returns a SizeError wrapping ErrPrivKeySize.
+func PrivKeySizeError(expected, got int) error {
+	return &SizeError{Err: ErrPrivKeySize, Expected: expected, Got: got}
+}
+
+// CiphertextSizeError returns a SizeError wrapping ErrCiphertextSize.
+func CiphertextSizeError(expected, got int) error {
+	return &SizeError{Err: ErrCiphertextSize, Expected: expected, Got: got}
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package kem_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/kem"
+)
+
+func TestSizeError(t *testing.T) {
+	err := kem.PubKeySizeError(1234, 1233)
+	require.ErrorIs(t, err, kem.ErrPubKeySize)
+	require.NotErrorIs(t, err, kem.ErrPrivKeySize)
+	require.Equal(t, kem.ErrPubKeySize.Error()+": expected 1234 got 1233", err.Error())
+
+	var sizeErr *kem.SizeError
+	require.True(t, errors.As(err, &sizeErr))
+	require.Equal(t, 1234, sizeErr.Expected)
+	require.Equal(t, 1233, sizeErr.Got)
+
+	require.ErrorIs(t, kem.PrivKeySizeError(1, 2), kem.ErrPrivKeySize)
+	require.ErrorIs(t, kem.CiphertextSizeError(1, 2), kem.ErrCiphertextSize)
+}
@@ -6,6 +6,7 @@ package sntrup
 
 import (
 	"crypto/hmac"
+	"errors"
 	"hash"
 	"io"
 
@@ -18,8 +19,13 @@ import (
 	sntrup "github.com/katzenpost/sntrup4591761"
 
 	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/util"
 )
 
+// ErrDecapsulationFailed is returned by Decapsulate if the ciphertext
+// doesn't decrypt to a value which re-encrypts to the same ciphertext.
+var ErrDecapsulationFailed = errors.New("sntrup: decapsulation failed")
+
 const (
 	// PublicKeySize is the public key size in bytes.
 	PublicKeySize = sntrup.PublicKeySize
@@ -165,7 +171,7 @@ func (*scheme) EncapsulateDeterministically(pk kem.PublicKey, seed []byte) (
 
 func (*scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
 	if len(ct) != CiphertextSize {
-		return nil, kem.ErrCiphertextSize
+		return nil, kem.CiphertextSizeError(CiphertextSize, len(ct))
 	}
 
 	priv, ok := sk.(*PrivateKey)
@@ -173,13 +179,27 @@ func (*scheme) Decapsulate(sk kem.PrivateKey, ct []byte) ([]byte, error) {
 		return nil, kem.ErrTypeMismatch
 	}
 	ss := make([]byte, SharedKeySize)
-	priv.DecapsulateTo(ss, ct)
+	if !priv.decapsulateTo(ss, ct) {
+		return nil, ErrDecapsulationFailed
+	}
 	return ss, nil
 }
 
+// ImplicitRejection returns false because this round 1 version of
+// Streamlined NTRU Prime rejects invalid ciphertexts explicitly, and
+// Decapsulate returns ErrDecapsulationFailed for them.
+func (*scheme) ImplicitRejection() bool {
+	return false
+}
+
+// Capabilities returns the capability flags of Streamlined NTRU Prime.
+func (*scheme) Capabilities() kem.Caps {
+	return kem.CapPostQuantum | kem.CapDeterministicKeygen
+}
+
 func (s *scheme) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
 	if len(buf) != PublicKeySize {
-		return nil, kem.ErrPubKeySize
+		return nil, kem.PubKeySizeError(PublicKeySize, len(buf))
 	}
 	pubKey := new(sntrup.PublicKey)
 	copy(pubKey[:], buf)
@@ -191,7 +211,7 @@ func (s *scheme) UnmarshalBinaryPublicKey(buf []byte) (kem.PublicKey, error) {
 
 func (s *scheme) UnmarshalBinaryPrivateKey(buf []byte) (kem.PrivateKey, error) {
 	if len(buf) != PrivateKeySize {
-		return nil, kem.ErrPrivKeySize
+		return nil, kem.PrivKeySizeError(PrivateKeySize, len(buf))
 	}
 	privKey := new(sntrup.PrivateKey)
 	copy(privKey[:], buf)
@@ -256,6 +276,15 @@ func (pk *PublicKey) MarshalBinary() (data []byte, err error) {
 	return pk.key[:], nil
 }
 
+// MarshalBinaryTo copies the public key into dst and returns the number
+// of bytes written.
+func (pk *PublicKey) MarshalBinaryTo(dst []byte) (int, error) {
+	if len(dst) < len(pk.key) {
+		return 0, kem.ErrPubKeySize
+	}
+	return copy(dst, pk.key[:]), nil
+}
+
 func (pk *PublicKey) MarshalText() (text []byte, err error) {
 	return pem.ToPublicPEMBytes(pk), nil
 }
@@ -277,6 +306,11 @@ func (sk *PrivateKey) Scheme() kem.Scheme {
 	return sk.scheme
 }
 
+// Reset zeroizes the private key.
+func (sk *PrivateKey) Reset() {
+	util.ExplicitBzero(sk.key[:])
+}
+
 func (sk *PrivateKey) Public() kem.PublicKey {
 	pubkey, _, err := Scheme().GenerateKeyPair()
 	if err != nil {
@@ -291,7 +325,8 @@ func (sk *PrivateKey) Public() kem.PublicKey {
 // for the private key.
 //
 // Panics if ct or ss are not of length CiphertextSize and SharedKeySize
-// respectively.
+// respectively, or if ct is rejected. Use the scheme's Decapsulate to
+// handle ciphertexts which aren't trusted.
 func (sk *PrivateKey) DecapsulateTo(ss, ct []byte) {
 	if len(ct) != CiphertextSize {
 		panic("ct must be of length CiphertextSize")
@@ -301,17 +336,28 @@ func (sk *PrivateKey) DecapsulateTo(ss, ct []byte) {
 		panic("ss must be of length SharedKeySize")
 	}
 
+	if !sk.decapsulateTo(ss, ct) {
+		panic("sntrup.Decapsulate failed")
+	}
+}
+
+// decapsulateTo writes the shared key to ss and returns true, or
+// returns false without touching ss if ct is rejected.
+func (sk *PrivateKey) decapsulateTo(ss, ct []byte) bool {
 	ciphertext := new(sntrup.Ciphertext)
 	copy(ciphertext[:], ct)
 	sharedkey, ok := sntrup.Decapsulate(ciphertext, sk.key)
 	if ok != 1 {
-		panic("sntrup.Decapsulate failed")
+		return false
 	}
 	copy(ss, sharedkey[:])
+	return true
 }
 
 func (sk *PrivateKey) MarshalBinary() (data []byte, err error) {
-	return sk.key[:], nil
+	data = make([]byte, PrivateKeySize)
+	copy(data, sk.key[:])
+	return data, nil
 }
 
 func (sk *PrivateKey) Equal(other kem.PrivateKey) bool {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/katzenpost/hpqc/kem"
 	"github.com/katzenpost/hpqc/util"
 )
 
@@ -64,3 +65,18 @@ func TestSNTRUPKEM(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, ss3, ss3b)
 }
+
+func TestDecapsulateRejects(t *testing.T) {
+	s := Scheme()
+
+	pubkey, privkey, err := s.GenerateKeyPair()
+	require.NoError(t, err)
+	ct, _, err := s.Encapsulate(pubkey)
+	require.NoError(t, err)
+
+	ct[0] ^= 0x01
+	ss, err := s.Decapsulate(privkey, ct)
+	require.ErrorIs(t, err, ErrDecapsulationFailed)
+	require.Nil(t, ss)
+	require.False(t, kem.ImplicitRejection(s))
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"golang.org/x/crypto/blake2b"
+)
+
+// MinExpandSeedSize is the smallest seed accepted by ExpandSeeds.
+const MinExpandSeedSize = 32
+
+// ExpandSeed expands seed into outLen bytes. The seed is first hashed
+// with BLAKE2b-256 and the digest is then used as the input of the
+// BLAKE2Xb XOF, not SHAKE, configured for an output of exactly outLen
+// bytes. As with any BLAKE2X instance the output length is part of the
+// XOF parameters, so a shorter expansion is not a prefix of a longer
+// one. It panics if seed is shorter than MinExpandSeedSize or outLen
+// is negative or too large for BLAKE2Xb.
+func ExpandSeed(seed []byte, outLen int) []byte {
+	if len(seed) < MinExpandSeedSize {
+		panic("seed too short")
+	}
+	if outLen < 0 {
+		panic("negative seed size")
+	}
+
+	h, err := blake2b.NewXOF(uint32(outLen), nil)
+	if err != nil {
+		panic(err)
+	}
+	seedHash := blake2b.Sum256(seed)
+	_, err = h.Write(seedHash[:])
+	if err != nil {
+		panic(err)
+	}
+	out := make([]byte, outLen)
+	_, err = h.Read(out)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// ExpandSeeds expands a single seed into independent seeds of the
+// given sizes, one per KEM component, by splitting the output of
+// ExpandSeed over their total size. Deriving the seeds this way means
+// a seed which isn't uniformly random doesn't bias any one of the
+// outputs more than the others, unlike simply slicing the seed.
+func ExpandSeeds(seed []byte, sizes []int) [][]byte {
+	total := 0
+	for _, size := range sizes {
+		if size < 0 {
+			panic("negative seed size")
+		}
+		total += size
+	}
+	out := ExpandSeed(seed, total)
+
+	seeds := make([][]byte, len(sizes))
+	offset := 0
+	for i, size := range sizes {
+		seeds[i] = out[offset : offset+size : offset+size]
+		offset += size
+	}
+	return seeds
+}
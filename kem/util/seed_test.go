@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestExpandSeed(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, MinExpandSeedSize)
+
+	out := ExpandSeed(seed, 100)
+	require.Len(t, out, 100)
+	require.Equal(t, out, ExpandSeed(seed, 100))
+
+	// BLAKE2Xb over the BLAKE2b-256 digest of the seed.
+	h, err := blake2b.NewXOF(100, nil)
+	require.NoError(t, err)
+	seedHash := blake2b.Sum256(seed)
+	_, err = h.Write(seedHash[:])
+	require.NoError(t, err)
+	want := make([]byte, 100)
+	_, err = h.Read(want)
+	require.NoError(t, err)
+	require.Equal(t, want, out)
+
+	// The output length is a XOF parameter, so shorter outputs are
+	// not prefixes of longer ones.
+	require.NotEqual(t, out[:32], ExpandSeed(seed, 32))
+
+	seeds := ExpandSeeds(seed, []int{30, 0, 70})
+	require.Equal(t, out, append(append(seeds[0], seeds[1]...), seeds[2]...))
+
+	require.Panics(t, func() { ExpandSeed(seed[1:], 32) })
+	require.Panics(t, func() { ExpandSeed(seed, -1) })
+}
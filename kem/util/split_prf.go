@@ -6,6 +6,9 @@ package util
 import (
 	"github.com/go-faster/xor"
 	"golang.org/x/crypto/blake2b"
+
+	"github.com/katzenpost/hpqc/kem/bufpool"
+	hpqcutil "github.com/katzenpost/hpqc/util"
 )
 
 // SplitPRF can be used with any number of KEMs
@@ -23,7 +26,7 @@ func SplitPRF(ss, cct [][]byte) []byte {
 		panic("mismatched slices")
 	}
 
-	cctcat := []byte{}
+	n := 0
 	for i := 0; i < len(cct); i++ {
 		if cct[i] == nil {
 			panic("ciphertext cannot be nil")
@@ -31,10 +34,22 @@ func SplitPRF(ss, cct [][]byte) []byte {
 		if len(cct[i]) == 0 {
 			panic("ciphertext cannot be zero length")
 		}
-		cctcat = append(cctcat, cct[i]...)
+		n += len(cct[i])
+	}
+	// The concatenated ciphertexts are only scratch space, and can be
+	// large, so draw them from the pool.
+	cctcat := bufpool.Get(n)
+	defer bufpool.Put(cctcat)
+	n = 0
+	for i := 0; i < len(cct); i++ {
+		n += copy(cctcat[n:], cct[i])
 	}
 
-	hashes := make([][]byte, len(ss))
+	// Each H(ssi || cct) is as secret as the shared secret itself, so
+	// it is accumulated through a single buffer which is wiped on return.
+	out := make([]byte, blake2b.Size256)
+	var sum [blake2b.Size256]byte
+	defer hpqcutil.ExplicitBzero(sum[:])
 	for i := 0; i < len(ss); i++ {
 		h, err := blake2b.New256(nil)
 		if err != nil {
@@ -54,16 +69,10 @@ func SplitPRF(ss, cct [][]byte) []byte {
 		if err != nil {
 			panic(err)
 		}
-		hashes[i] = h.Sum(nil)
+		h.Sum(sum[:0])
+		xor.Bytes(out, out, sum[:])
 	}
-
-	acc := hashes[0]
-	for i := 1; i < len(ss); i++ {
-		out := make([]byte, 32)
-		xor.Bytes(out, acc, hashes[i])
-		acc = out
-	}
-	return acc
+	return out
 }
 
 // PairSplitPRF is a split PRF that operates on only two KEMs.
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// These vectors pin the split-key PRF combiner from
+// "KEM Combiners" by Giacon, Heuer and Poettering
+// (https://eprint.iacr.org/2018/024.pdf), instantiated with
+// unkeyed BLAKE2b-256 as the PRF:
+//
+//	cct := cct1 || ... || cctN
+//	K   := H(ss1 || cct) XOR ... XOR H(ssN || cct)
+//
+// The expected values were computed with an independent
+// implementation (Python's hashlib.blake2b with digest_size=32),
+// so any change to the hashing, the input framing or the
+// ciphertext ordering will break interop and fail here.
+
+func vectorInputs() (ss [][]byte, cct [][]byte) {
+	ss1 := make([]byte, 32)
+	ss2 := make([]byte, 32)
+	ss3 := make([]byte, 32)
+	for i := 0; i < 32; i++ {
+		ss1[i] = byte(i)
+		ss2[i] = byte(32 + i)
+		ss3[i] = byte(64 + i)
+	}
+	ss = [][]byte{ss1, ss2, ss3}
+	cct = [][]byte{
+		[]byte("ciphertext one"),
+		[]byte("ciphertext two"),
+		[]byte("ciphertext three"),
+	}
+	return ss, cct
+}
+
+func TestPairSplitPRFVector(t *testing.T) {
+	ss, cct := vectorInputs()
+
+	out := PairSplitPRF(ss[0], ss[1], cct[0], cct[1])
+	require.Equal(t, "4ef26e26953bf2a4f484f3e5f0284879fd394ead818a677105684dff2557c366", hex.EncodeToString(out))
+
+	// The pair combiner must stay binary compatible with the
+	// N-KEM combiner when only two KEMs are used.
+	require.Equal(t, out, SplitPRF(ss[:2], cct[:2]))
+
+	// Component order matters because the ciphertexts are
+	// concatenated in order.
+	swapped := PairSplitPRF(ss[1], ss[0], cct[1], cct[0])
+	require.Equal(t, "1bd2871ed1046b3aadc7a9bf181602d120f460f88f8cfe469047a63449e2710d", hex.EncodeToString(swapped))
+}
+
+func TestSplitPRFVector(t *testing.T) {
+	ss, cct := vectorInputs()
+
+	out := SplitPRF(ss, cct)
+	require.Equal(t, "8fe539430e3973882b15f55489be66f7901c3b718e7c4b3399031b6571d5e842", hex.EncodeToString(out))
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package xwing
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loadVectors returns the named groups of hex encoded test vectors from
+// testdata/vectors.json, which holds the test vector of the X-Wing
+// draft implemented by filippo.io/mlkem768/xwing.
+func loadVectors(t *testing.T) map[string]map[string]string {
+	blob, err := os.ReadFile("testdata/vectors.json")
+	require.NoError(t, err)
+	vectors := make(map[string]map[string]string)
+	require.NoError(t, json.Unmarshal(blob, &vectors))
+	return vectors
+}
+
+func vector(t *testing.T, group map[string]string, name string) []byte {
+	h, ok := group[name]
+	require.True(t, ok, "missing vector %s", name)
+	v, err := hex.DecodeString(h)
+	require.NoError(t, err)
+	return v
+}
+
+func TestDraftVector(t *testing.T) {
+	group := loadVectors(t)["X-Wing draft-01"]
+	pkExp := vector(t, group, "pk")
+	skExp := vector(t, group, "sk")
+
+	// The draft's seed is followed by the encapsulation randomness,
+	// which can't be fed to Encapsulate.
+	pk, sk := Scheme().DeriveKeyPair(vector(t, group, "seed")[:KeySeedSize])
+	pkBytes, err := pk.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, pkExp, pkBytes)
+	skBytes, err := sk.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, append(append([]byte{}, skExp...), pkExp...), skBytes)
+
+	ss, err := Scheme().Decapsulate(sk, vector(t, group, "ct"))
+	require.NoError(t, err)
+	require.Equal(t, vector(t, group, "ss"), ss)
+}
@@ -42,6 +42,11 @@ func (e *CsidhNike) Name() string {
 	return "CSIDH-512-nobs"
 }
 
+// PostQuantum returns true, CSIDH is believed to resist quantum attacks.
+func (e *CsidhNike) PostQuantum() bool {
+	return true
+}
+
 func (e *CsidhNike) PublicKeySize() int {
 	return csidh.PublicKeySize
 }
@@ -5,6 +5,7 @@ package ctidh511
 
 import (
 	"encoding/base64"
+	"encoding/binary"
 	"io"
 
 	ctidh "codeberg.org/vula/highctidh/src/ctidh511"
@@ -28,6 +29,11 @@ func (e *scheme) Name() string {
 	return "ctidh511"
 }
 
+// PostQuantum returns true, CTIDH is believed to resist quantum attacks.
+func (e *scheme) PostQuantum() bool {
+	return true
+}
+
 // PublicKeySize returns the size in bytes of the public key.
 func (e *scheme) PublicKeySize() int {
 	return ctidh.PublicKeySize
@@ -143,6 +149,12 @@ func (p *PublicKey) Reset() {
 	p.publicKey.Reset()
 }
 
+// Bytes returns the public key in the encoding of the highctidh
+// library: the Montgomery coefficient A, a field element in Montgomery
+// form, as its 64 bit limbs, least significant limb first, each limb in
+// the host's byte order. The encoding is therefore not portable between
+// little and big endian hosts. PublicKeyToReferenceFormat gives the
+// portable encoding of the CTIDH reference code.
 func (p *PublicKey) Bytes() []byte {
 	return p.publicKey.Bytes()
 }
@@ -151,6 +163,40 @@ func (p *PublicKey) FromBytes(data []byte) error {
 	return p.publicKey.FromBytes(data)
 }
 
+// PublicKeyFromReferenceFormat decodes a public key serialized by the
+// public_key_to_bytes function of the CTIDH reference C code, which
+// writes the same limbs as Bytes but each in little endian byte order.
+// On little endian hosts the two encodings are identical.
+func PublicKeyFromReferenceFormat(data []byte) (*PublicKey, error) {
+	if len(data) != ctidh.PublicKeySize {
+		return nil, ctidh.ErrPublicKeySize
+	}
+	pubkey := ctidh.NewEmptyPublicKey()
+	if err := pubkey.FromBytes(swapLimbs(data)); err != nil {
+		return nil, err
+	}
+	return &PublicKey{
+		publicKey: pubkey,
+	}, nil
+}
+
+// PublicKeyToReferenceFormat serializes the public key like the
+// public_key_to_bytes function of the CTIDH reference C code, for
+// exchanging keys with it. See PublicKeyFromReferenceFormat.
+func PublicKeyToReferenceFormat(p *PublicKey) []byte {
+	return swapLimbs(p.Bytes())
+}
+
+// swapLimbs converts 64 bit limbs between the host's byte order and
+// little endian, in either direction.
+func swapLimbs(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := 0; i+8 <= len(data); i += 8 {
+		binary.LittleEndian.PutUint64(out[i:], binary.NativeEndian.Uint64(data[i:]))
+	}
+	return out
+}
+
 // MarshalBinary is an implementation of a method on the
 // BinaryMarshaler interface defined in https://golang.org/pkg/encoding/
 func (p *PublicKey) MarshalBinary() ([]byte, error) {
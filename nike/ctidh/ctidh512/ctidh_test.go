@@ -4,6 +4,7 @@
 package ctidh512
 
 import (
+	"encoding/binary"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -28,3 +29,25 @@ func TestCTIDH512_NIKE(t *testing.T) {
 	bobS := ctidh.DeriveSecret(bobPrivKey.(*PrivateKey).privateKey, alicePublicKey.(*PublicKey).publicKey)
 	require.Equal(t, bobS, aliceS)
 }
+
+func TestReferenceFormat(t *testing.T) {
+	pubKey, _, err := Scheme().GenerateKeyPair()
+	require.NoError(t, err)
+	native := pubKey.Bytes()
+
+	ref := PublicKeyToReferenceFormat(pubKey.(*PublicKey))
+	require.Len(t, ref, len(native))
+	for i := 0; i < len(native); i += 8 {
+		require.Equal(t, binary.NativeEndian.Uint64(native[i:]), binary.LittleEndian.Uint64(ref[i:]))
+	}
+	if binary.NativeEndian.Uint16([]byte{1, 0}) == 1 {
+		require.Equal(t, native, ref)
+	}
+
+	pubKey2, err := PublicKeyFromReferenceFormat(ref)
+	require.NoError(t, err)
+	require.Equal(t, native, pubKey2.Bytes())
+
+	_, err = PublicKeyFromReferenceFormat(ref[1:])
+	require.ErrorIs(t, err, ctidh.ErrPublicKeySize)
+}
@@ -41,6 +41,11 @@ func (s *Scheme) Name() string {
 	return s.name
 }
 
+// PostQuantum returns true if either component scheme is post quantum.
+func (s *Scheme) PostQuantum() bool {
+	return nike.PostQuantum(s.first) || nike.PostQuantum(s.second)
+}
+
 func (s *Scheme) PublicKeySize() int {
 	return s.first.PublicKeySize() + s.second.PublicKeySize()
 }
@@ -97,3 +97,42 @@ type Scheme interface {
 	// Unmarshals a PrivateKey from the provided buffer.
 	UnmarshalBinaryPrivateKey([]byte) (PrivateKey, error)
 }
+
+// ScalarMultiplier is implemented by NIKE schemes whose public keys are
+// group elements which can be multiplied by an arbitrary scalar, which
+// is useful for blinding and threshold constructions. It is kept
+// separate from Scheme since not every NIKE has such a structure.
+//
+// The x25519 and x448 schemes implement ScalarMultiplier. Their scalars
+// are clamped as in RFC 7748, so ScalarMult(a, ScalarMult(b, P)) equals
+// ScalarMult(b, ScalarMult(a, P)) but scalars don't otherwise compose
+// arithmetically. Neither scheme can offer point addition: public keys
+// only encode the Montgomery u-coordinate, which is the same for P and
+// -P, so the u-coordinate of P+Q isn't determined by those of P and Q.
+// CTIDH public keys are curves acted on by an ideal class group rather
+// than group elements, so the CTIDH schemes implement neither operation.
+type ScalarMultiplier interface {
+	Scheme
+
+	// ScalarMult returns the public key scalar * p. The scalar must be
+	// PrivateKeySize bytes long. It returns an error if the result is
+	// the identity, which happens when p is a low order point.
+	ScalarMult(scalar []byte, p PublicKey) (PublicKey, error)
+}
+
+// PostQuantumScheme is implemented by NIKE schemes which report whether
+// they are believed to resist quantum attacks, such as the CTIDH
+// schemes.
+type PostQuantumScheme interface {
+	// PostQuantum returns true if the scheme is believed to resist
+	// quantum attacks.
+	PostQuantum() bool
+}
+
+// PostQuantum returns true if the given scheme implements
+// PostQuantumScheme and reports being post quantum. Schemes which don't
+// implement it, such as x25519 and x448, are taken to be classical.
+func PostQuantum(s Scheme) bool {
+	p, ok := s.(PostQuantumScheme)
+	return ok && p.PostQuantum()
+}
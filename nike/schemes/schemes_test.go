@@ -175,3 +175,37 @@ func BenchmarkNIKE(b *testing.B) {
 		})
 	}
 }
+
+func TestScalarMult(t *testing.T) {
+	for _, s := range All() {
+		g, ok := s.(nike.ScalarMultiplier)
+		if !ok {
+			continue
+		}
+		pubkey1, privkey1, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+		pubkey2, privkey2, err := s.GenerateKeyPair()
+		require.NoError(t, err)
+
+		p, err := g.ScalarMult(privkey2.Bytes(), pubkey1)
+		require.NoError(t, err)
+		require.Equal(t, s.DeriveSecret(privkey2, pubkey1), p.Bytes())
+
+		// a * (b * P) == b * (a * P)
+		ab, err := g.ScalarMult(privkey1.Bytes(), p)
+		require.NoError(t, err)
+		p, err = g.ScalarMult(privkey1.Bytes(), pubkey1)
+		require.NoError(t, err)
+		ba, err := g.ScalarMult(privkey2.Bytes(), p)
+		require.NoError(t, err)
+		require.Equal(t, ab.Bytes(), ba.Bytes())
+
+		_, err = g.ScalarMult(privkey1.Bytes()[1:], pubkey2)
+		require.Error(t, err)
+
+		identity, err := s.UnmarshalBinaryPublicKey(make([]byte, s.PublicKeySize()))
+		require.NoError(t, err)
+		_, err = g.ScalarMult(privkey1.Bytes(), identity)
+		require.Error(t, err)
+	}
+}
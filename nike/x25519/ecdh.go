@@ -31,18 +31,23 @@ var (
 	ErrBlindDataSizeInvalid error = errors.New("ecdh: blinding data size invalid")
 
 	errInvalidKey = errors.New("ecdh: invalid key")
+
+	errInvalidScalar = errors.New("ecdh: invalid scalar")
 )
 
 var _ nike.PrivateKey = (*PrivateKey)(nil)
 var _ nike.PublicKey = (*PublicKey)(nil)
 var _ nike.Scheme = (*scheme)(nil)
+var _ nike.ScalarMultiplier = (*scheme)(nil)
 
 // EcdhNike implements the Nike interface using our ecdh module.
 type scheme struct {
 	rng io.Reader
 }
 
-// Scheme instantiates a new X25519 scheme given a CSPRNG.
+// Scheme instantiates a new X25519 scheme given a CSPRNG, which is
+// used by GenerateKeyPair. The scheme is only safe for concurrent use
+// if rng is, which is the case for rand.Reader.
 func Scheme(rng io.Reader) *scheme {
 	return &scheme{
 		rng: rng,
@@ -58,12 +63,10 @@ func (p *PrivateKey) Public() nike.PublicKey {
 	return Scheme(rand.Reader).DerivePublicKey(p)
 }
 
+// Reset zeroizes the private scalar and resets the cached public key.
 func (p *PrivateKey) Reset() {
-	b := make([]byte, PrivateKeySize)
-	err := p.FromBytes(b)
-	if err != nil {
-		panic(err)
-	}
+	util.ExplicitBzero(p.privBytes[:])
+	p.pubKey.Reset()
 }
 
 func (p *PrivateKey) Bytes() []byte {
@@ -229,8 +232,9 @@ func (e *scheme) DeriveSecret(privKey nike.PrivateKey, pubKey nike.PublicKey) []
 
 // DerivePublicKey derives a public key given a private key.
 func (e *scheme) DerivePublicKey(privKey nike.PrivateKey) nike.PublicKey {
-	pubKey := e.NewEmptyPublicKey()
-	expG(&pubKey.(*PublicKey).pubBytes, &privKey.(*PrivateKey).privBytes)
+	pubKey := new(PublicKey)
+	expG(&pubKey.pubBytes, &privKey.(*PrivateKey).privBytes)
+	pubKey.rebuildB64String()
 	return pubKey
 }
 
@@ -245,6 +249,27 @@ func (e *scheme) Blind(groupMember nike.PublicKey, blindingFactor nike.PrivateKe
 	return pubKey
 }
 
+// ScalarMult returns the public key scalar * p, where the scalar is
+// clamped as in RFC 7748.
+func (e *scheme) ScalarMult(scalar []byte, p nike.PublicKey) (nike.PublicKey, error) {
+	if len(scalar) != PrivateKeySize {
+		return nil, errInvalidScalar
+	}
+	pubKey, ok := p.(*PublicKey)
+	if !ok {
+		return nil, errInvalidKey
+	}
+	out, err := curve25519.X25519(scalar, pubKey.pubBytes[:])
+	if err != nil {
+		return nil, err
+	}
+	r := new(PublicKey)
+	if err = r.FromBytes(out); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
 // UnmarshalBinaryPublicKey loads a public key from byte slice.
 func (e *scheme) UnmarshalBinaryPublicKey(b []byte) (nike.PublicKey, error) {
 	pubKey := new(PublicKey)
@@ -281,6 +306,17 @@ func Exp(x, y []byte) []byte {
 	return sharedSecret
 }
 
+// SharedSecret performs the X25519 scalar multiplication of the given
+// public key by the given private key and returns the raw 32 byte
+// Montgomery u-coordinate. It is equivalent to calling DeriveSecret on
+// the NIKE scheme but doesn't require constructing one.
+//
+// Note that the output is not hashed; callers should pass it through
+// a KDF before using it as a symmetric key.
+func SharedSecret(priv *PrivateKey, pub *PublicKey) []byte {
+	return priv.Exp(pub)
+}
+
 func expG(dst, y *[GroupElementLength]byte) {
 	curve25519.ScalarBaseMult(dst, y)
 }
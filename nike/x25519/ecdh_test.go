@@ -92,3 +92,33 @@ func TestECDHOps(t *testing.T) {
 	curve25519.ScalarMult(&bobS, &bobSk, &tmp)
 	assert.Equal(bobS[:], aliceS, "Exp() mismatch against X25519 scalar mult")
 }
+
+func TestSharedSecret(t *testing.T) {
+	t.Parallel()
+
+	alice, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	bob, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	aliceS := SharedSecret(alice, bob.Public().(*PublicKey))
+	bobS := SharedSecret(bob, alice.Public().(*PublicKey))
+	require.Equal(t, aliceS, bobS)
+	require.Len(t, aliceS, GroupElementLength)
+
+	nikeS := Scheme(rand.Reader).DeriveSecret(alice, bob.Public())
+	require.Equal(t, nikeS, aliceS)
+}
+
+func TestPrivateKeyReset(t *testing.T) {
+	t.Parallel()
+
+	privKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	require.False(t, util.CtIsZero(privKey.privBytes[:]))
+
+	privKey.Reset()
+	require.True(t, util.CtIsZero(privKey.privBytes[:]))
+	require.True(t, util.CtIsZero(privKey.Bytes()))
+	require.True(t, util.CtIsZero(privKey.pubKey.pubBytes[:]))
+}
@@ -31,18 +31,25 @@ var (
 	ErrBlindDataSizeInvalid error = errors.New("x448: blinding data size invalid")
 
 	errInvalidKey = errors.New("x448: invalid key")
+
+	errInvalidScalar = errors.New("x448: invalid scalar")
+
+	errLowOrderPoint = errors.New("x448: low order point")
 )
 
 var _ nike.PrivateKey = (*PrivateKey)(nil)
 var _ nike.PublicKey = (*PublicKey)(nil)
 var _ nike.Scheme = (*scheme)(nil)
+var _ nike.ScalarMultiplier = (*scheme)(nil)
 
 // EcdhNike implements the Nike interface using our ecdh module.
 type scheme struct {
 	rng io.Reader
 }
 
-// Scheme instantiates a new X448 scheme given a CSPRNG.
+// Scheme instantiates a new X448 scheme given a CSPRNG, which is used
+// by GenerateKeyPair. The scheme is only safe for concurrent use if rng
+// is, which is the case for rand.Reader.
 func Scheme(rng io.Reader) *scheme {
 	return &scheme{
 		rng: rng,
@@ -126,6 +133,29 @@ func (e *scheme) Blind(groupMember nike.PublicKey, blindingFactor nike.PrivateKe
 	return pubKey
 }
 
+// ScalarMult returns the public key scalar * p, where the scalar is
+// clamped as in RFC 7748.
+func (e *scheme) ScalarMult(scalar []byte, p nike.PublicKey) (nike.PublicKey, error) {
+	if len(scalar) != PrivateKeySize {
+		return nil, errInvalidScalar
+	}
+	pubKey, ok := p.(*PublicKey)
+	if !ok {
+		return nil, errInvalidKey
+	}
+	k := new(x448.Key)
+	copy(k[:], scalar)
+	out := new(x448.Key)
+	ok = x448.Shared(out, k, pubKey.pubBytes)
+	util.ExplicitBzero(k[:])
+	if !ok {
+		return nil, errLowOrderPoint
+	}
+	return &PublicKey{
+		pubBytes: out,
+	}, nil
+}
+
 // UnmarshalBinaryPublicKey loads a public key from byte slice.
 func (e *scheme) UnmarshalBinaryPublicKey(b []byte) (nike.PublicKey, error) {
 	pubKey := new(PublicKey)
@@ -170,12 +200,12 @@ func (p *PrivateKey) Public() nike.PublicKey {
 	return pubKey
 }
 
+// Reset zeroizes the private scalar in place.
 func (p *PrivateKey) Reset() {
-	zeros := make([]byte, PrivateKeySize)
-	err := p.FromBytes(zeros)
-	if err != nil {
-		panic(err)
+	if p.privBytes == nil {
+		return
 	}
+	util.ExplicitBzero(p.privBytes[:])
 }
 
 func (p *PrivateKey) Bytes() []byte {
@@ -231,11 +261,10 @@ func (p *PublicKey) Blind(blindingFactor nike.PrivateKey) error {
 }
 
 func (p *PublicKey) Reset() {
-	zeros := make([]byte, PublicKeySize)
-	err := p.FromBytes(zeros)
-	if err != nil {
-		panic(err)
+	if p.pubBytes == nil {
+		return
 	}
+	util.ExplicitBzero(p.pubBytes[:])
 }
 
 func (p *PublicKey) Bytes() []byte {
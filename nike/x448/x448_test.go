@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package x448
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/util"
+)
+
+func TestPrivateKeyReset(t *testing.T) {
+	t.Parallel()
+
+	k, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	privKey := k.(*PrivateKey)
+	scalar := privKey.privBytes
+	require.False(t, util.CtIsZero(scalar[:]))
+
+	// the scalar must be wiped in place, not replaced
+	privKey.Reset()
+	require.True(t, util.CtIsZero(scalar[:]))
+	require.True(t, util.CtIsZero(privKey.Bytes()))
+
+	new(PrivateKey).Reset()
+}
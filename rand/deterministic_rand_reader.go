@@ -24,6 +24,8 @@ import (
 )
 
 // DeterministicRandReader is a random Reader whose output is a chacha20 keystream.
+// It is not safe for concurrent use, so it must not be given to a scheme
+// which is shared between goroutines.
 type DeterministicRandReader struct {
 	cipher *chacha20.Cipher
 	key    []byte
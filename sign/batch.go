@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sign
+
+// BatchVerifier is implemented by schemes with their own way of
+// verifying many signatures at once.
+type BatchVerifier interface {
+	// VerifyBatch verifies each signature in sigs over the message and
+	// against the public key at the same index, and returns the result
+	// of each verification. The results must be the same as those of
+	// calling Verify on each signature with nil options. Nil keys and
+	// keys of other schemes fail to verify. The slices have the same
+	// length.
+	VerifyBatch(keys []PublicKey, msgs, sigs [][]byte) []bool
+}
+
+// VerifyBatch verifies each signature in sigs over the message and
+// against the public key at the same index, and returns whether all of
+// them are valid together with the result of each verification. An
+// empty batch isn't valid, and if the slices differ in length nothing
+// is verified and VerifyBatch returns false and nil results. Schemes
+// implementing BatchVerifier verify the batch themselves; for any other
+// scheme VerifyBatch falls back to calling Verify on each signature in
+// turn with nil options, which gives the same results more slowly. Nil
+// keys always fail to verify.
+func VerifyBatch(scheme Scheme, keys []PublicKey, msgs, sigs [][]byte) (bool, []bool) {
+	if len(keys) != len(msgs) || len(keys) != len(sigs) {
+		return false, nil
+	}
+	var results []bool
+	if b, ok := scheme.(BatchVerifier); ok {
+		results = b.VerifyBatch(keys, msgs, sigs)
+	} else {
+		results = make([]bool, len(keys))
+		for i := range keys {
+			if keys[i] == nil {
+				continue
+			}
+			results[i] = scheme.Verify(keys[i], msgs[i], sigs[i], nil)
+		}
+	}
+	all := len(results) > 0
+	for _, ok := range results {
+		all = all && ok
+	}
+	return all, results
+}
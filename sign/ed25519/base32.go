@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"encoding/base32"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// crockfordCheckSymbols are the check symbols for the values 0 to
+	// 36, the first 32 being the same as the encoding alphabet.
+	crockfordCheckSymbols = crockfordAlphabet + "*~$=U"
+
+	// Base32Size is the length of the string returned by Base32: 52
+	// symbols for the key and one check symbol.
+	Base32Size = 53
+)
+
+var crockford = base32.NewEncoding(crockfordAlphabet).WithPadding(base32.NoPadding)
+
+var (
+	// ErrInvalidBase32 is returned by PublicKeyFromBase32 for strings
+	// which aren't a base32 encoded public key.
+	ErrInvalidBase32 = errors.New("eddsa: invalid base32 public key")
+
+	// ErrBase32Checksum is returned by PublicKeyFromBase32 if the check
+	// symbol doesn't match, which usually means a typo.
+	ErrBase32Checksum = errors.New("eddsa: base32 public key checksum mismatch")
+)
+
+// base32Check returns the Crockford check symbol of the encoded key,
+// which is the value of the key's symbols taken as one big base 32
+// number, modulo 37. Since 37 is a prime larger than 32, changing any
+// one symbol always changes the check symbol.
+func base32Check(key []byte) byte {
+	// The 52 symbols hold the 256 key bits followed by 4 zero bits.
+	v := new(big.Int).SetBytes(key)
+	v.Lsh(v, 4)
+	v.Mod(v, big.NewInt(37))
+	return crockfordCheckSymbols[v.Int64()]
+}
+
+// Base32 returns the public key as 52 symbols of unpadded Crockford
+// base32 followed by a Crockford mod 37 check symbol, for QR codes and
+// manual entry. The check symbol catches any single mistyped symbol.
+// Use PublicKeyFromBase32 to decode it.
+func (p *PublicKey) Base32() string {
+	return crockford.EncodeToString(p.pubKey) + string(base32Check(p.pubKey))
+}
+
+// PublicKeyFromBase32 decodes a public key encoded by Base32. As in
+// Crockford base32, it ignores case and hyphens and reads O as 0 and I
+// and L as 1. It returns ErrBase32Checksum if the check symbol doesn't
+// match the key.
+func PublicKeyFromBase32(s string) (*PublicKey, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, "-", ""))
+	s = strings.NewReplacer("O", "0", "I", "1", "L", "1").Replace(s)
+	if len(s) != Base32Size {
+		return nil, ErrInvalidBase32
+	}
+	encoded, check := s[:Base32Size-1], s[Base32Size-1]
+	key, err := crockford.DecodeString(encoded)
+	if err != nil || len(key) != PublicKeySize {
+		return nil, ErrInvalidBase32
+	}
+	// Reject nonzero padding bits, so every key has one encoding.
+	if crockford.EncodeToString(key) != encoded {
+		return nil, ErrInvalidBase32
+	}
+	if base32Check(key) != check {
+		return nil, ErrBase32Checksum
+	}
+	pubKey := new(PublicKey)
+	if err := pubKey.FromBytes(key); err != nil {
+		return nil, err
+	}
+	return pubKey, nil
+}
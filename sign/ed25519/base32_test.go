@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestBase32(t *testing.T) {
+	t.Parallel()
+	_, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	s := pubKey.Base32()
+	require.Len(t, s, Base32Size)
+	require.Equal(t, strings.ToUpper(s), s)
+
+	pubKey2, err := PublicKeyFromBase32(s)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+
+	// Case, hyphens and the look-alike letters are forgiven.
+	lower := strings.ToLower(s[:10]) + "-" + s[10:]
+	lower = strings.ReplaceAll(lower, "0", "o")
+	lower = strings.ReplaceAll(lower, "1", "l")
+	pubKey2, err = PublicKeyFromBase32(lower)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+}
+
+func TestBase32DetectsTypos(t *testing.T) {
+	t.Parallel()
+	_, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	s := pubKey.Base32()
+
+	// Every single symbol substitution is caught.
+	for i := 0; i < len(s); i++ {
+		alphabet := crockfordAlphabet
+		if i == len(s)-1 {
+			alphabet = crockfordCheckSymbols
+		}
+		for _, c := range alphabet {
+			if byte(c) == s[i] {
+				continue
+			}
+			typo := s[:i] + string(c) + s[i+1:]
+			_, err := PublicKeyFromBase32(typo)
+			require.Error(t, err, typo)
+		}
+	}
+
+	_, err = PublicKeyFromBase32(s[1:])
+	require.ErrorIs(t, err, ErrInvalidBase32)
+	_, err = PublicKeyFromBase32(s[:10] + "U" + s[11:])
+	require.ErrorIs(t, err, ErrInvalidBase32)
+}
@@ -43,6 +43,9 @@ const (
 	BlindFactorSize = ed25519.PublicKeySize
 )
 
+// ErrEmptyBlindingFactor is returned when blinding with an empty factor.
+var ErrEmptyBlindingFactor = errors.New("eddsa: empty blinding factor")
+
 // Sanity checking of public keys.
 // We do NOT do check for small-order points here or otherwise validate the point.
 // This function is just here to catch accidentally-bad keys, basically.
@@ -290,3 +293,36 @@ func (k *PublicKey) Blind(factor []byte) *PublicKey {
 	}
 	return newkey
 }
+
+// BlindPublicKey returns the public key blinded by the given factor,
+// giving an unlinkable public key under which signatures made by the
+// BlindedPrivateKey returned by BlindPrivateKey with the same factor
+// verify. It is the error returning counterpart to PublicKey.Blind.
+//
+// The blinding scalar is derived as in Tor's key blinding scheme, but
+// with different hashing of the factor, so blinded keys are not
+// interchangeable with Tor's; see the note at the top of this file.
+func BlindPublicKey(pub *PublicKey, factor []byte) (*PublicKey, error) {
+	if pub == nil || len(pub.pubKey) != PublicKeySize {
+		return nil, errInvalidKey
+	}
+	if len(factor) == 0 {
+		return nil, ErrEmptyBlindingFactor
+	}
+	if _, err := new(edwards25519.Point).SetBytes(pub.Bytes()); err != nil {
+		return nil, errInvalidKey
+	}
+	return pub.Blind(factor), nil
+}
+
+// BlindPrivateKey returns the private key blinded by the given factor.
+// It is the error returning counterpart to PrivateKey.Blind.
+func BlindPrivateKey(priv *PrivateKey, factor []byte) (*BlindedPrivateKey, error) {
+	if priv == nil || len(priv.privKey) != PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	if len(factor) == 0 {
+		return nil, ErrEmptyBlindingFactor
+	}
+	return priv.Blind(factor), nil
+}
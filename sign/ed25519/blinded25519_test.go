@@ -194,3 +194,31 @@ func TestBlinding(t *testing.T) {
 		t.Error("failed bothwork", err)
 	}
 }
+
+func TestBlindPublicPrivateKey(t *testing.T) {
+	t.Parallel()
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	priv, pub, err := NewKeypair(rng)
+	require.NoError(t, err)
+	factor := []byte("session 1")
+
+	bpub, err := BlindPublicKey(pub, factor)
+	require.NoError(t, err)
+	bpriv, err := BlindPrivateKey(priv, factor)
+	require.NoError(t, err)
+	require.Equal(t, bpub.Bytes(), bpriv.PublicKey().Bytes())
+	require.NotEqual(t, pub.Bytes(), bpub.Bytes())
+
+	message := []byte("hello")
+	require.True(t, bpub.Verify(bpriv.Sign(message), message))
+	require.False(t, pub.Verify(bpriv.Sign(message), message))
+
+	_, err = BlindPublicKey(pub, nil)
+	require.ErrorIs(t, err, ErrEmptyBlindingFactor)
+	_, err = BlindPrivateKey(priv, []byte{})
+	require.ErrorIs(t, err, ErrEmptyBlindingFactor)
+	_, err = BlindPublicKey(new(PublicKey), factor)
+	require.Error(t, err)
+	_, err = BlindPrivateKey(nil, factor)
+	require.Error(t, err)
+}
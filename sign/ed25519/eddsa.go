@@ -5,12 +5,15 @@
 package ed25519
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ed25519"
 	"crypto/hmac"
 	"encoding/base64"
 	"errors"
 	"io"
+	"sort"
+	"sync/atomic"
 
 	"golang.org/x/crypto/blake2b"
 
@@ -117,6 +120,17 @@ func (s *scheme) SupportsContext() bool {
 	return false
 }
 
+// PrehashRequired returns false because pure Ed25519 signs the
+// message itself, hashing it internally with SHA-512.
+func (s *scheme) PrehashRequired() bool {
+	return false
+}
+
+// DigestAlgorithm returns 0 since the message is not pre-hashed.
+func (s *scheme) DigestAlgorithm() crypto.Hash {
+	return 0
+}
+
 type PrivateKey struct {
 	pubKey  PublicKey
 	privKey ed25519.PrivateKey
@@ -140,6 +154,15 @@ func (p *PrivateKey) MarshalBinary() ([]byte, error) {
 	return p.Bytes(), nil
 }
 
+// MarshalBinaryTo copies the private key into dst, which must be at
+// least PrivateKeySize bytes long, and returns the number of bytes written.
+func (p *PrivateKey) MarshalBinaryTo(dst []byte) (int, error) {
+	if len(dst) < PrivateKeySize {
+		return 0, errInvalidKey
+	}
+	return copy(dst, p.privKey), nil
+}
+
 func (p *PrivateKey) UnmarshalBinary(b []byte) error {
 	return p.FromBytes(b)
 }
@@ -191,6 +214,25 @@ func (p *PrivateKey) FromBytes(b []byte) error {
 	return nil
 }
 
+// FromSeed loads the PrivateKey from a 32 byte RFC 8032 seed, as stored
+// by many tools instead of the full 64 byte private key.
+func (p *PrivateKey) FromSeed(seed []byte) error {
+	if len(seed) != ed25519.SeedSize {
+		return errInvalidKey
+	}
+
+	p.privKey = ed25519.NewKeyFromSeed(seed)
+	p.pubKey.pubKey = p.privKey.Public().(ed25519.PublicKey)
+	p.pubKey.rebuildB64String()
+	return nil
+}
+
+// Seed returns a copy of the 32 byte RFC 8032 seed the PrivateKey was
+// derived from, which is the compact form accepted by FromSeed.
+func (p *PrivateKey) Seed() []byte {
+	return p.privKey.Seed()
+}
+
 // Identity returns the key's identity, in this case it's our
 // public key in bytes.
 func (p *PrivateKey) Identity() []byte {
@@ -206,6 +248,16 @@ func (p *PrivateKey) PublicKey() *PublicKey {
 type PublicKey struct {
 	pubKey    ed25519.PublicKey
 	b64String string
+
+	// ecdh caches the result of ToECDH.
+	ecdh atomic.Pointer[ecdhCache]
+}
+
+// ecdhCache holds an X25519 public key converted from the Ed25519
+// public key from.
+type ecdhCache struct {
+	from [PublicKeySize]byte
+	to   [x25519.PublicKeySize]byte
 }
 
 func (p *PublicKey) Scheme() sign.Scheme {
@@ -220,11 +272,30 @@ func (p *PublicKey) MarshalBinary() ([]byte, error) {
 	return p.Bytes(), nil
 }
 
+// MarshalBinaryTo copies the public key into dst, which must be at
+// least PublicKeySize bytes long, and returns the number of bytes written.
+func (p *PublicKey) MarshalBinaryTo(dst []byte) (int, error) {
+	if len(dst) < PublicKeySize {
+		return 0, errInvalidKey
+	}
+	return copy(dst, p.pubKey), nil
+}
+
 // ToECDH converts the PublicKey to the corresponding ecdh.PublicKey.
+// The conversion is cached, so only the first call for given key bytes
+// pays for the point decompression. Each call returns a new
+// x25519.PublicKey which the caller may modify.
 func (p *PublicKey) ToECDH() *x25519.PublicKey {
-	ed_pub, _ := new(edwards25519.Point).SetBytes(p.Bytes())
+	c := p.ecdh.Load()
+	if c == nil || !bytes.Equal(c.from[:], p.pubKey) {
+		ed_pub, _ := new(edwards25519.Point).SetBytes(p.Bytes())
+		c = new(ecdhCache)
+		copy(c.from[:], p.pubKey)
+		copy(c.to[:], ed_pub.BytesMontgomery())
+		p.ecdh.Store(c)
+	}
 	r := new(x25519.PublicKey)
-	if r.FromBytes(ed_pub.BytesMontgomery()) != nil {
+	if r.FromBytes(c.to[:]) != nil {
 		panic("edwards.Point from pub.BytesMontgomery failed, impossible. ")
 	}
 	return r
@@ -244,11 +315,93 @@ func (p *PublicKey) Sum256() [32]byte {
 	return blake2b.Sum256(p.Bytes())
 }
 
+// IsValid returns true if the key is the canonical encoding of a point
+// on the curve which is not of small order. It rejects:
+//
+//   - encodings which don't decode to a point on the curve;
+//   - non-canonical encodings, whose y coordinate isn't reduced modulo
+//     the field prime or which encode x = 0 with the sign bit set. These
+//     alias canonical keys, so two different byte strings would verify
+//     the same signatures;
+//   - the eight points of small order, including the identity. Anyone
+//     can forge signatures which verify under such keys.
+//
+// Keys whose point has a small order component but isn't itself of
+// small order are accepted, as they are by the key generation of other
+// implementations. Verify doesn't call IsValid, so check peer keys with
+// it when they are first accepted.
+func (p *PublicKey) IsValid() bool {
+	if len(p.pubKey) != PublicKeySize {
+		return false
+	}
+	point, err := new(edwards25519.Point).SetBytes(p.pubKey)
+	if err != nil {
+		return false
+	}
+	if !bytes.Equal(point.Bytes(), p.pubKey) {
+		return false
+	}
+	return new(edwards25519.Point).MultByCofactor(point).Equal(edwards25519.NewIdentityPoint()) != 1
+}
+
 func (p *PublicKey) Verify(signature, message []byte) bool {
 	return ed25519.Verify(p.pubKey, message, signature)
 }
 
+// VerifySameMessage verifies each signature in sigs against the public
+// key at the same index in keys, where every signature is over the same
+// message, and returns the result of each verification. Nil keys always
+// fail to verify. It panics if keys and sigs differ in length.
+//
+// Note that Ed25519 hashes the message together with each signature's R
+// and the signer's public key, so the message hash itself can't be shared
+// between signers; only the per-call setup is amortized.
+func VerifySameMessage(message []byte, keys []*PublicKey, sigs [][]byte) []bool {
+	if len(keys) != len(sigs) {
+		panic("eddsa: mismatched number of keys and signatures")
+	}
+	results := make([]bool, len(keys))
+	for i := range keys {
+		if keys[i] == nil || len(keys[i].pubKey) != PublicKeySize {
+			continue
+		}
+		results[i] = ed25519.Verify(keys[i].pubKey, message, sigs[i])
+	}
+	return results
+}
+
+// SignaturesEqual reports whether a and b are the same signature,
+// comparing them in constant time. Signatures of different lengths are
+// unequal, and only their lengths, not their contents, affect the
+// timing.
+func SignaturesEqual(a, b []byte) bool {
+	return hmac.Equal(a, b)
+}
+
+var _ sign.BatchVerifier = (*scheme)(nil)
+
+// VerifyBatch verifies each signature against the key and message at
+// the same index, as described by sign.BatchVerifier. Ed25519 has
+// randomized batch verification which is faster, but it checks the
+// cofactored verification equation, which can accept crafted signatures
+// that Verify rejects. So that a signature never verifies in one place
+// and not the other, each signature is checked with the same equation
+// as Verify, only saving the interface dispatch of sign.VerifyBatch's
+// generic fallback.
+func (s *scheme) VerifyBatch(keys []sign.PublicKey, msgs, sigs [][]byte) []bool {
+	results := make([]bool, len(keys))
+	for i, k := range keys {
+		pubKey, ok := k.(*PublicKey)
+		if !ok || pubKey == nil || len(pubKey.pubKey) != PublicKeySize {
+			continue
+		}
+		results[i] = ed25519.Verify(pubKey.pubKey, msgs[i], sigs[i])
+	}
+	return results
+}
+
 func (p *PublicKey) Reset() {
+	p.ecdh.Store(nil)
 	util.ExplicitBzero(p.pubKey)
 	p.b64String = "[scrubbed]"
 }
@@ -265,6 +418,18 @@ func (p *PublicKey) ByteArray() [PublicKeySize]byte {
 	return pk
 }
 
+// SortPublicKeys sorts keys in place by their raw bytes in lexicographic
+// order, which gives a deterministic order to keys collected from a map
+// keyed by ByteArray. Nil keys sort first.
+func SortPublicKeys(keys []*PublicKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == nil || keys[j] == nil {
+			return keys[i] == nil && keys[j] != nil
+		}
+		return bytes.Compare(keys[i].pubKey, keys[j].pubKey) < 0
+	})
+}
+
 func (p *PublicKey) rebuildB64String() {
 	p.b64String = base64.StdEncoding.EncodeToString(p.Bytes())
 }
@@ -277,6 +442,7 @@ func (p *PublicKey) FromBytes(data []byte) error {
 	p.pubKey = make([]byte, PublicKeySize)
 	copy(p.pubKey, data)
 	p.rebuildB64String()
+	p.ecdh.Store(nil)
 	return nil
 }
 
@@ -18,11 +18,18 @@
 package ed25519
 
 import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
 
+	"github.com/katzenpost/hpqc/nike/x25519"
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/sign"
 	"github.com/katzenpost/hpqc/util"
 )
 
@@ -140,3 +147,252 @@ func TestCheckEdDSA(t *testing.T) {
 	verify_res = rsk.PublicKey().Verify(vector_signed[:], []byte{1})
 	assert.Equal(false, verify_res)
 }
+
+func TestVerifySameMessage(t *testing.T) {
+	t.Parallel()
+	message := []byte("block 1234")
+
+	keys := make([]*PublicKey, 4)
+	sigs := make([][]byte, 4)
+	for i := range keys {
+		privKey, pubKey, err := NewKeypair(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = pubKey
+		sigs[i] = privKey.SignMessage(message)
+	}
+	// index 1 signed a different message and index 3 is missing
+	privKey, _, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	sigs[1] = privKey.SignMessage([]byte("block 1235"))
+	keys[3] = nil
+
+	require.Equal(t, []bool{true, false, true, false}, VerifySameMessage(message, keys, sigs))
+	require.Panics(t, func() {
+		VerifySameMessage(message, keys, sigs[:2])
+	})
+}
+
+func TestVerifyBatch(t *testing.T) {
+	t.Parallel()
+	keys := make([]sign.PublicKey, 4)
+	msgs := make([][]byte, 4)
+	sigs := make([][]byte, 4)
+	for i := range keys {
+		privKey, pubKey, err := NewKeypair(rand.Reader)
+		require.NoError(t, err)
+		keys[i] = pubKey
+		msgs[i] = []byte{byte(i)}
+		sigs[i] = privKey.SignMessage(msgs[i])
+	}
+
+	all, results := sign.VerifyBatch(Scheme(), keys, msgs, sigs)
+	require.True(t, all)
+	require.Equal(t, []bool{true, true, true, true}, results)
+
+	// index 1 has a signature over another message and index 3 is missing
+	sigs[1] = sigs[0]
+	keys[3] = nil
+	all, results = sign.VerifyBatch(Scheme(), keys, msgs, sigs)
+	require.False(t, all)
+	require.Equal(t, []bool{true, false, true, false}, results)
+
+	all, results = sign.VerifyBatch(Scheme(), keys[:1], msgs[:1], sigs[:1])
+	require.True(t, all)
+	require.Equal(t, []bool{true}, results)
+
+	all, results = sign.VerifyBatch(Scheme(), keys, msgs[:2], sigs)
+	require.False(t, all)
+	require.Nil(t, results)
+}
+
+func TestSignaturesEqual(t *testing.T) {
+	t.Parallel()
+	privKey, _, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	sig := privKey.SignMessage([]byte("submission 1"))
+	require.True(t, SignaturesEqual(sig, privKey.SignMessage([]byte("submission 1"))))
+	require.False(t, SignaturesEqual(sig, privKey.SignMessage([]byte("submission 2"))))
+
+	flipped := append([]byte{}, sig...)
+	flipped[SignatureSize-1] ^= 0x80
+	require.False(t, SignaturesEqual(sig, flipped))
+	require.False(t, SignaturesEqual(sig, sig[:SignatureSize-1]))
+	require.False(t, SignaturesEqual(sig, nil))
+	require.True(t, SignaturesEqual(nil, nil))
+}
+
+func TestMarshalBinaryTo(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	dst := make([]byte, PrivateKeySize)
+	n, err := privKey.MarshalBinaryTo(dst)
+	require.NoError(t, err)
+	require.Equal(t, PrivateKeySize, n)
+	require.Equal(t, privKey.Bytes(), dst)
+
+	n, err = pubKey.MarshalBinaryTo(dst)
+	require.NoError(t, err)
+	require.Equal(t, PublicKeySize, n)
+	require.Equal(t, pubKey.Bytes(), dst[:n])
+
+	_, err = pubKey.MarshalBinaryTo(dst[:PublicKeySize-1])
+	require.Error(t, err)
+}
+
+func BenchmarkPublicKeyMarshalBinaryTo(b *testing.B) {
+	_, pubKey, err := NewKeypair(rand.Reader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dst := make([]byte, PublicKeySize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pubKey.MarshalBinaryTo(dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWithPrivateKey(t *testing.T) {
+	t.Parallel()
+	pubKey, privKey, err := Scheme().GenerateKey()
+	require.NoError(t, err)
+
+	message := []byte("hello")
+	err = sign.WithPrivateKey(privKey, func(sk sign.PrivateKey) error {
+		signature := Scheme().Sign(sk, message, nil)
+		require.True(t, Scheme().Verify(pubKey, message, signature, nil))
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, util.CtIsZero(privKey.(*PrivateKey).Bytes()))
+}
+
+func TestFromSeed(t *testing.T) {
+	t.Parallel()
+	privKey, _, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	privKey2 := new(PrivateKey)
+	err = privKey2.FromSeed(privKey.Bytes()[:KeySeedSize])
+	require.NoError(t, err)
+	require.Equal(t, privKey.Bytes(), privKey2.Bytes())
+	require.Equal(t, privKey.PublicKey().Bytes(), privKey2.PublicKey().Bytes())
+
+	require.Error(t, privKey2.FromSeed(privKey.Bytes()))
+	require.Error(t, privKey2.FromSeed(nil))
+}
+
+func TestSeed(t *testing.T) {
+	t.Parallel()
+	privKey, _, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	seed := privKey.Seed()
+	require.Len(t, seed, KeySeedSize)
+	require.Equal(t, privKey.Bytes()[:KeySeedSize], seed)
+
+	privKey2 := new(PrivateKey)
+	require.NoError(t, privKey2.FromSeed(seed))
+	require.True(t, privKey.Equal(privKey2))
+
+	// Mutating the returned seed must not touch the key.
+	seed[0] ^= 0xff
+	require.Equal(t, privKey2.Bytes()[:KeySeedSize], privKey.Seed())
+}
+
+func TestSortPublicKeys(t *testing.T) {
+	t.Parallel()
+	byArray := make(map[[PublicKeySize]byte]*PublicKey)
+	for i := 0; i < 16; i++ {
+		_, pubKey, err := NewKeypair(rand.Reader)
+		require.NoError(t, err)
+		byArray[pubKey.ByteArray()] = pubKey
+	}
+
+	keys := []*PublicKey{nil}
+	for _, pubKey := range byArray {
+		keys = append(keys, pubKey)
+	}
+	SortPublicKeys(keys)
+
+	require.Nil(t, keys[0])
+	for i := 2; i < len(keys); i++ {
+		require.Negative(t, bytes.Compare(keys[i-1].Bytes(), keys[i].Bytes()))
+	}
+}
+
+func TestToECDHCache(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	// The converted key matches the X25519 key of the same secret.
+	h := sha512.Sum512(privKey.Seed())
+	var want [x25519.PublicKeySize]byte
+	curve25519.ScalarBaseMult(&want, (*[32]byte)(h[:32]))
+
+	ecdh1 := pubKey.ToECDH()
+	require.Equal(t, want[:], ecdh1.Bytes())
+	ecdh2 := pubKey.ToECDH()
+	require.Equal(t, ecdh1.Bytes(), ecdh2.Bytes())
+
+	// Callers get their own copy of the cached key.
+	ecdh1.Reset()
+	require.Equal(t, want[:], pubKey.ToECDH().Bytes())
+
+	// The cache follows the key bytes.
+	_, pubKey2, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	uncached := new(PublicKey)
+	require.NoError(t, uncached.FromBytes(pubKey2.Bytes()))
+	require.NoError(t, pubKey.FromBytes(pubKey2.Bytes()))
+	require.Equal(t, uncached.ToECDH().Bytes(), pubKey.ToECDH().Bytes())
+	require.NotEqual(t, want[:], pubKey.ToECDH().Bytes())
+}
+
+func TestPublicKeyIsValid(t *testing.T) {
+	t.Parallel()
+	_, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	require.True(t, pubKey.IsValid())
+
+	// y = 3 is a point of large order, and y = 3 + p its non-canonical
+	// encoding. y = 2 isn't on the curve.
+	canonical := make([]byte, PublicKeySize)
+	canonical[0] = 3
+	nonCanonical := bytes.Repeat([]byte{0xff}, PublicKeySize)
+	nonCanonical[0] = 0xf0
+	nonCanonical[31] = 0x7f
+	notOnCurve := make([]byte, PublicKeySize)
+	notOnCurve[0] = 2
+	identity := make([]byte, PublicKeySize)
+	identity[0] = 1
+	negativeZero := make([]byte, PublicKeySize)
+	negativeZero[0] = 1
+	negativeZero[31] = 0x80
+	orderEight, err := hex.DecodeString("c7176a703d4dd84fba3c0b760d10670f2a2053fa2c39ccc64ec7fd7792ac037a")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		key   []byte
+		valid bool
+	}{
+		{canonical, true},
+		{nonCanonical, false},
+		{notOnCurve, false},
+		{identity, false},
+		{negativeZero, false},
+		{orderEight, false},
+	} {
+		pubKey := new(PublicKey)
+		require.NoError(t, pubKey.FromBytes(tc.key))
+		require.Equal(t, tc.valid, pubKey.IsValid(), "%x", tc.key)
+	}
+
+	require.False(t, new(PublicKey).IsValid())
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"crypto"
+	"errors"
+	"io"
+
+	"github.com/katzenpost/hpqc/sign"
+)
+
+var (
+	// ErrNotEd25519Signer is returned by NewExternalPrivateKey when the
+	// signer's public key isn't an Ed25519 public key.
+	ErrNotEd25519Signer = errors.New("eddsa: external signer public key is not ed25519")
+
+	// ErrExternalKey is returned when trying to serialize a private key
+	// held by an external signer.
+	ErrExternalKey = errors.New("eddsa: private key is held by an external signer")
+)
+
+var _ sign.PrivateKey = (*ExternalPrivateKey)(nil)
+
+// ExternalPrivateKey adapts a sign.Signer holding an Ed25519 key, such
+// as an HSM client, to the sign.PrivateKey interface so that it can be
+// used with Scheme().Sign and anywhere else a private key is expected.
+// The key material can't be marshaled.
+type ExternalPrivateKey struct {
+	signer sign.Signer
+	pubKey *PublicKey
+}
+
+// NewExternalPrivateKey returns an ExternalPrivateKey which signs using
+// the given signer.
+func NewExternalPrivateKey(signer sign.Signer) (*ExternalPrivateKey, error) {
+	pubKey, ok := signer.Public().(*PublicKey)
+	if !ok {
+		return nil, ErrNotEd25519Signer
+	}
+	return &ExternalPrivateKey{
+		signer: signer,
+		pubKey: pubKey,
+	}, nil
+}
+
+func (p *ExternalPrivateKey) Scheme() sign.Scheme {
+	return Scheme()
+}
+
+// Equal returns true if key is an ExternalPrivateKey for the same
+// public key.
+func (p *ExternalPrivateKey) Equal(key crypto.PrivateKey) bool {
+	k, ok := key.(*ExternalPrivateKey)
+	if !ok {
+		return false
+	}
+	return p.pubKey.Equal(k.pubKey)
+}
+
+func (p *ExternalPrivateKey) Public() crypto.PublicKey {
+	return p.pubKey
+}
+
+// PublicKey returns the signer's public key.
+func (p *ExternalPrivateKey) PublicKey() *PublicKey {
+	return p.pubKey
+}
+
+// Sign signs the message with the external signer. The rand and opts
+// arguments are ignored, as with PrivateKey.
+func (p *ExternalPrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	sig, err := p.signer.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != SignatureSize {
+		return nil, errInvalidKey
+	}
+	return sig, nil
+}
+
+func (p *ExternalPrivateKey) MarshalBinary() ([]byte, error) {
+	return nil, ErrExternalKey
+}
+
+func (p *ExternalPrivateKey) UnmarshalBinary([]byte) error {
+	return ErrExternalKey
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/sign"
+)
+
+// testSigner stands in for an HSM client.
+type testSigner struct {
+	privKey *PrivateKey
+	fail    bool
+}
+
+func (s *testSigner) Public() sign.PublicKey {
+	return s.privKey.PublicKey()
+}
+
+func (s *testSigner) Sign(message []byte) ([]byte, error) {
+	if s.fail {
+		return nil, errors.New("device unavailable")
+	}
+	return s.privKey.SignMessage(message), nil
+}
+
+func TestExternalPrivateKey(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	signer := &testSigner{privKey: privKey}
+	ext, err := NewExternalPrivateKey(signer)
+	require.NoError(t, err)
+	require.True(t, ext.PublicKey().Equal(pubKey))
+
+	msg := []byte("hello world")
+	sig := Scheme().Sign(ext, msg, nil)
+	require.True(t, Scheme().Verify(pubKey, msg, sig, nil))
+	require.Equal(t, privKey.SignMessage(msg), sig)
+
+	_, err = ext.MarshalBinary()
+	require.ErrorIs(t, err, ErrExternalKey)
+
+	ext2, err := NewExternalPrivateKey(&testSigner{privKey: privKey})
+	require.NoError(t, err)
+	require.True(t, ext.Equal(ext2))
+	require.False(t, ext.Equal(privKey))
+
+	signer.fail = true
+	_, err = ext.Sign(nil, msg, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"crypto/sha512"
+	"io"
+
+	"filippo.io/edwards25519"
+
+	"github.com/katzenpost/hpqc/util"
+)
+
+// hedgeSize is the number of random bytes mixed into each hedged nonce.
+const hedgeSize = 32
+
+// SignHedged signs message like SignMessage, but mixes fresh randomness
+// from rand into the nonce, so that a fault injected while signing the
+// same message twice doesn't hand out two signatures with the same
+// nonce, from which the private key could be computed. The signatures
+// are ordinary Ed25519 signatures which Verify and any RFC 8032
+// verifier accept, but they aren't deterministic.
+//
+// The construction follows RFC 8032, section 5.1.6, except for the
+// nonce. With the secret scalar s and prefix, the upper half of
+// SHA-512(seed), derived as in RFC 8032, and Z being 32 bytes read from
+// rand, the signature of M under the public key A = s*B is R || S where
+//
+//	r = SHA-512(prefix || Z || M) mod L
+//	R = r*B
+//	k = SHA-512(R || A || M) mod L
+//	S = (r + k*s) mod L
+//
+// The nonce stays secret as long as either prefix is secret or rand is
+// a good random source, so a broken rand is no worse than deterministic
+// signing. It returns an error if reading from rand fails.
+func (p *PrivateKey) SignHedged(message []byte, rand io.Reader) ([]byte, error) {
+	if len(p.privKey) != PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	z := make([]byte, hedgeSize)
+	defer util.ExplicitBzero(z)
+	if _, err := io.ReadFull(rand, z); err != nil {
+		return nil, err
+	}
+
+	seed := p.privKey.Seed()
+	defer util.ExplicitBzero(seed)
+	h := sha512.Sum512(seed)
+	defer util.ExplicitBzero(h[:])
+	s, err := edwards25519.NewScalar().SetBytesWithClamping(h[:32])
+	if err != nil {
+		panic(err)
+	}
+	prefix := h[32:]
+
+	mh := sha512.New()
+	mh.Write(prefix)
+	mh.Write(z)
+	mh.Write(message)
+	nonce := mh.Sum(make([]byte, 0, sha512.Size))
+	defer util.ExplicitBzero(nonce)
+	r, err := edwards25519.NewScalar().SetUniformBytes(nonce)
+	if err != nil {
+		panic(err)
+	}
+	R := new(edwards25519.Point).ScalarBaseMult(r)
+
+	kh := sha512.New()
+	kh.Write(R.Bytes())
+	kh.Write(p.pubKey.pubKey)
+	kh.Write(message)
+	k, err := edwards25519.NewScalar().SetUniformBytes(kh.Sum(make([]byte, 0, sha512.Size)))
+	if err != nil {
+		panic(err)
+	}
+	S := edwards25519.NewScalar().MultiplyAdd(k, s, r)
+
+	signature := make([]byte, 0, SignatureSize)
+	signature = append(signature, R.Bytes()...)
+	signature = append(signature, S.Bytes()...)
+	return signature, nil
+}
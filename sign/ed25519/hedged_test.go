@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("entropy source failed")
+}
+
+func TestSignHedged(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	message := []byte("firmware image 1.2.3")
+
+	sig1, err := privKey.SignHedged(message, rand.Reader)
+	require.NoError(t, err)
+	require.Len(t, sig1, SignatureSize)
+	sig2, err := privKey.SignHedged(message, rand.Reader)
+	require.NoError(t, err)
+	require.NotEqual(t, sig1, sig2)
+
+	for _, sig := range [][]byte{sig1, sig2} {
+		require.True(t, pubKey.Verify(sig, message))
+		require.True(t, ed25519.Verify(pubKey.Bytes(), message, sig))
+		require.True(t, Scheme().Verify(pubKey, message, sig, nil))
+		require.False(t, pubKey.Verify(sig, []byte("firmware image 1.2.4")))
+	}
+
+	// The same randomness gives the same signature, which isn't the
+	// deterministic RFC 8032 one.
+	z := bytes.Repeat([]byte{0x5a}, hedgeSize)
+	sig3, err := privKey.SignHedged(message, bytes.NewReader(z))
+	require.NoError(t, err)
+	sig4, err := privKey.SignHedged(message, bytes.NewReader(z))
+	require.NoError(t, err)
+	require.Equal(t, sig3, sig4)
+	require.NotEqual(t, privKey.SignMessage(message), sig3)
+	require.True(t, pubKey.Verify(sig3, message))
+
+	_, err = privKey.SignHedged(message, failingReader{})
+	require.Error(t, err)
+	_, err = privKey.SignHedged(message, bytes.NewReader(z[:hedgeSize-1]))
+	require.Error(t, err)
+}
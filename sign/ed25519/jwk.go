@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/katzenpost/hpqc/util"
+)
+
+// ErrInvalidJWK is returned when a JSON Web Key isn't a valid RFC 8037
+// OKP key on Ed25519. The error never includes any of the key material.
+var ErrInvalidJWK = errors.New("eddsa: invalid Ed25519 JWK")
+
+// jwk is the RFC 8037 JSON Web Key form of an Ed25519 key. D is only
+// set for private keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	D   string `json:"d,omitempty"`
+}
+
+// MarshalJWK returns the public key as an RFC 8037 JSON Web Key, with
+// kty "OKP", crv "Ed25519" and the base64url encoded key as x, suitable
+// for publishing in a JWKS document.
+func (p *PublicKey) MarshalJWK() ([]byte, error) {
+	if len(p.pubKey) != PublicKeySize {
+		return nil, errInvalidKey
+	}
+	return json.Marshal(&jwk{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(p.pubKey),
+	})
+}
+
+// PublicKeyFromJWK parses an RFC 8037 Ed25519 JSON Web Key as produced
+// by PublicKey.MarshalJWK. The private key member d, if present, is
+// ignored.
+func PublicKeyFromJWK(b []byte) (*PublicKey, error) {
+	k, err := parseJWK(b)
+	if err != nil {
+		return nil, err
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, ErrInvalidJWK
+	}
+	pubKey := new(PublicKey)
+	if err := pubKey.FromBytes(x); err != nil {
+		return nil, ErrInvalidJWK
+	}
+	return pubKey, nil
+}
+
+// MarshalJWK returns the private key as an RFC 8037 JSON Web Key, which
+// holds the 32 byte seed as d alongside the public key members. The
+// result is secret key material: don't log it, and wipe it once it has
+// been stored.
+func (p *PrivateKey) MarshalJWK() ([]byte, error) {
+	if len(p.privKey) != PrivateKeySize {
+		return nil, errInvalidKey
+	}
+	seed := p.privKey.Seed()
+	defer util.ExplicitBzero(seed)
+	return json.Marshal(&jwk{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		X:   base64.RawURLEncoding.EncodeToString(p.pubKey.pubKey),
+		D:   base64.RawURLEncoding.EncodeToString(seed),
+	})
+}
+
+// PrivateKeyFromJWK parses an RFC 8037 Ed25519 private JSON Web Key as
+// produced by PrivateKey.MarshalJWK. It fails if d is missing or if x
+// isn't the public key of d.
+func PrivateKeyFromJWK(b []byte) (*PrivateKey, error) {
+	k, err := parseJWK(b)
+	if err != nil {
+		return nil, err
+	}
+	if k.D == "" {
+		return nil, ErrInvalidJWK
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, ErrInvalidJWK
+	}
+	seed, err := base64.RawURLEncoding.DecodeString(k.D)
+	if err != nil {
+		return nil, ErrInvalidJWK
+	}
+	defer util.ExplicitBzero(seed)
+	if len(seed) != ed25519.SeedSize {
+		return nil, ErrInvalidJWK
+	}
+	privKey := NewEmptyPrivateKey()
+	if err := privKey.FromSeed(seed); err != nil {
+		return nil, ErrInvalidJWK
+	}
+	if !hmac.Equal(privKey.pubKey.pubKey, x) {
+		privKey.Reset()
+		return nil, ErrInvalidJWK
+	}
+	return privKey, nil
+}
+
+func parseJWK(b []byte) (*jwk, error) {
+	k := new(jwk)
+	// The json error may quote the input, which can hold d.
+	if err := json.Unmarshal(b, k); err != nil {
+		return nil, ErrInvalidJWK
+	}
+	if k.Kty != "OKP" || k.Crv != "Ed25519" {
+		return nil, ErrInvalidJWK
+	}
+	return k, nil
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+)
+
+// rfc8037Key is the Ed25519 private key from RFC 8037, Appendix A.1.
+const rfc8037Key = `{"kty":"OKP","crv":"Ed25519",
+	"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+	"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`
+
+func TestJWK(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	b, err := pubKey.MarshalJWK()
+	require.NoError(t, err)
+	var m map[string]string
+	require.NoError(t, json.Unmarshal(b, &m))
+	require.Equal(t, "OKP", m["kty"])
+	require.Equal(t, "Ed25519", m["crv"])
+	require.NotContains(t, m, "d")
+	pubKey2, err := PublicKeyFromJWK(b)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+
+	b, err = privKey.MarshalJWK()
+	require.NoError(t, err)
+	privKey2, err := PrivateKeyFromJWK(b)
+	require.NoError(t, err)
+	require.True(t, privKey.Equal(privKey2))
+	pubKey2, err = PublicKeyFromJWK(b)
+	require.NoError(t, err)
+	require.True(t, pubKey.Equal(pubKey2))
+}
+
+func TestJWKVector(t *testing.T) {
+	t.Parallel()
+	privKey, err := PrivateKeyFromJWK([]byte(rfc8037Key))
+	require.NoError(t, err)
+
+	b, err := privKey.MarshalJWK()
+	require.NoError(t, err)
+	require.JSONEq(t, rfc8037Key, string(b))
+
+	b, err = privKey.PublicKey().MarshalJWK()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`, string(b))
+}
+
+func TestJWKInvalid(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{
+		`not json nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A`,
+		strings.Replace(rfc8037Key, `"OKP"`, `"EC"`, 1),
+		strings.Replace(rfc8037Key, `"Ed25519"`, `"X25519"`, 1),
+		strings.Replace(rfc8037Key, `11qY`, `11qZ`, 1),
+		strings.Replace(rfc8037Key, `"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A"`, `"d":"AAAA"`, 1),
+		strings.Replace(rfc8037Key, `nWGx`, `nWG+`, 1),
+	} {
+		_, err := PrivateKeyFromJWK([]byte(s))
+		require.ErrorIs(t, err, ErrInvalidJWK, s)
+		require.NotContains(t, err.Error(), "nWGx")
+	}
+
+	_, err := PrivateKeyFromJWK([]byte(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`))
+	require.ErrorIs(t, err, ErrInvalidJWK)
+	_, err = PublicKeyFromJWK([]byte(`{"kty":"OKP","crv":"Ed25519","x":"11qY"}`))
+	require.ErrorIs(t, err, ErrInvalidJWK)
+}
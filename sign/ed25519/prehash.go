@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"errors"
+	"hash"
+)
+
+// ErrNoDataWritten is reported by Verifier.Err if Verify was called
+// before any data was written.
+var ErrNoDataWritten = errors.New("eddsa: verify called before any data was written")
+
+// prehashOptions selects Ed25519ph from RFC 8032 with an empty context.
+var prehashOptions = &ed25519.Options{Hash: crypto.SHA512}
+
+// Signer computes an Ed25519ph signature over a message written to it
+// incrementally, so that large messages need not be held in memory.
+// Note that Ed25519ph signatures are not interchangeable with the pure
+// Ed25519 signatures made by PrivateKey.SignMessage.
+type Signer struct {
+	privKey *PrivateKey
+	h       hash.Hash
+}
+
+// NewSigner returns a Signer which signs with the given private key.
+func NewSigner(privKey *PrivateKey) *Signer {
+	return &Signer{
+		privKey: privKey,
+		h:       sha512.New(),
+	}
+}
+
+// Write adds more of the message to the running hash. It never returns
+// an error.
+func (s *Signer) Write(p []byte) (int, error) {
+	return s.h.Write(p)
+}
+
+// Sign returns the Ed25519ph signature of the message written so far.
+func (s *Signer) Sign() ([]byte, error) {
+	return s.privKey.privKey.Sign(nil, s.h.Sum(nil), prehashOptions)
+}
+
+// Verifier checks an Ed25519ph signature over a message written to it
+// incrementally, such as a large file streamed from disk.
+type Verifier struct {
+	pubKey  *PublicKey
+	sig     []byte
+	h       hash.Hash
+	written bool
+	err     error
+}
+
+// NewVerifier returns a Verifier which checks sig against the message
+// written to it using the given public key.
+func NewVerifier(pubKey *PublicKey, sig []byte) *Verifier {
+	return &Verifier{
+		pubKey: pubKey,
+		sig:    sig,
+		h:      sha512.New(),
+	}
+}
+
+// Write adds more of the message to the running hash. It never returns
+// an error.
+func (v *Verifier) Write(p []byte) (int, error) {
+	v.written = true
+	return v.h.Write(p)
+}
+
+// Verify returns true if the signature is valid for the message written
+// so far. When it returns false, Err reports why.
+func (v *Verifier) Verify() bool {
+	if !v.written {
+		v.err = ErrNoDataWritten
+		return false
+	}
+	v.err = ed25519.VerifyWithOptions(v.pubKey.pubKey, v.h.Sum(nil), v.sig, prehashOptions)
+	return v.err == nil
+}
+
+// Err returns the reason the last call to Verify returned false, or nil.
+func (v *Verifier) Err() error {
+	return v.err
+}
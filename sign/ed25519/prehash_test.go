@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/sign"
+)
+
+func TestStreamingPrehash(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	msg := make([]byte, 1<<20)
+	_, err = rand.Reader.Read(msg)
+	require.NoError(t, err)
+
+	signer := NewSigner(privKey)
+	_, err = io.Copy(signer, bytes.NewReader(msg))
+	require.NoError(t, err)
+	sig, err := signer.Sign()
+	require.NoError(t, err)
+	require.Len(t, sig, SignatureSize)
+
+	// Ed25519ph signatures differ from pure Ed25519 ones.
+	require.False(t, pubKey.Verify(sig, msg))
+
+	v := NewVerifier(pubKey, sig)
+	_, err = io.CopyBuffer(v, bytes.NewReader(msg), make([]byte, 4096))
+	require.NoError(t, err)
+	require.True(t, v.Verify())
+	require.NoError(t, v.Err())
+
+	v = NewVerifier(pubKey, sig)
+	_, err = v.Write(msg[1:])
+	require.NoError(t, err)
+	require.False(t, v.Verify())
+	require.Error(t, v.Err())
+
+	v = NewVerifier(pubKey, sig)
+	require.False(t, v.Verify())
+	require.ErrorIs(t, v.Err(), ErrNoDataWritten)
+}
+
+func TestSignLarge(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	msg := bytes.Repeat([]byte("large message "), 100000)
+
+	// Ed25519 can't be streamed, so SignLarge buffers the message and
+	// makes pure Ed25519 signatures rather than Ed25519ph ones.
+	sig, err := sign.SignLarge(Scheme(), privKey, bytes.NewReader(msg))
+	require.NoError(t, err)
+	require.True(t, Scheme().Verify(pubKey, msg, sig, nil))
+	verifier := NewVerifier(pubKey, sig)
+	_, err = verifier.Write(msg)
+	require.NoError(t, err)
+	require.False(t, verifier.Verify())
+
+	ok, err := sign.VerifyLarge(Scheme(), pubKey, bytes.NewReader(msg), sig)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = sign.VerifyLarge(Scheme(), pubKey, bytes.NewReader(msg[1:]), sig)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	sig, err = sign.SignLarge(Scheme(), privKey, bytes.NewReader(nil))
+	require.NoError(t, err)
+	ok, err = sign.VerifyLarge(Scheme(), pubKey, bytes.NewReader(nil), sig)
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import "errors"
+
+// crossSignLabel domain separates key rotation statements from any
+// other message signed with the same key.
+const crossSignLabel = "hpqc ed25519 key rotation v1"
+
+// ErrSameKey is returned by CrossSign when the new key is the old key.
+var ErrSameKey = errors.New("eddsa: rotation to the same key")
+
+// crossSignMessage returns the canonical rotation statement
+//
+//	"hpqc ed25519 key rotation v1" || 0x00 || oldPub || newPub
+//
+// where both keys are in their 32 byte encoding. Every field has a
+// fixed length, so the statement can't be mistaken for one endorsing
+// any other pair of keys.
+func crossSignMessage(oldPub, newPub *PublicKey) []byte {
+	msg := make([]byte, 0, len(crossSignLabel)+1+2*PublicKeySize)
+	msg = append(msg, crossSignLabel...)
+	msg = append(msg, 0)
+	msg = append(msg, oldPub.Bytes()...)
+	msg = append(msg, newPub.Bytes()...)
+	return msg
+}
+
+// CrossSign returns a signature by the old identity key endorsing
+// newPub as its successor, so that peers which trust the old key can
+// follow the rotation. A chain of rotations is a list of such
+// signatures, each checked with VerifyCrossSign against the key
+// endorsed by the previous one. The signature is a plain Ed25519
+// signature of the statement described by crossSignMessage.
+func CrossSign(old *PrivateKey, newPub *PublicKey) ([]byte, error) {
+	if old == nil || newPub == nil || len(newPub.Bytes()) != PublicKeySize {
+		return nil, errInvalidKey
+	}
+	oldPub := old.PublicKey()
+	if oldPub.Equal(newPub) {
+		return nil, ErrSameKey
+	}
+	return old.SignMessage(crossSignMessage(oldPub, newPub)), nil
+}
+
+// VerifyCrossSign returns true if sig is a signature made by CrossSign
+// with the private key of oldPub endorsing newPub.
+func VerifyCrossSign(oldPub, newPub *PublicKey, sig []byte) bool {
+	if oldPub == nil || newPub == nil ||
+		len(oldPub.Bytes()) != PublicKeySize || len(newPub.Bytes()) != PublicKeySize {
+		return false
+	}
+	if oldPub.Equal(newPub) {
+		return false
+	}
+	return oldPub.Verify(sig, crossSignMessage(oldPub, newPub))
+}
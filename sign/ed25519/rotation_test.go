@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/katzenpost/hpqc/rand"
+)
+
+func TestCrossSign(t *testing.T) {
+	t.Parallel()
+	oldPriv, oldPub, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	newPriv, newPub, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	_, otherPub, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	sig, err := CrossSign(oldPriv, newPub)
+	require.NoError(t, err)
+	require.True(t, VerifyCrossSign(oldPub, newPub, sig))
+
+	require.False(t, VerifyCrossSign(newPub, oldPub, sig))
+	require.False(t, VerifyCrossSign(oldPub, otherPub, sig))
+	require.False(t, VerifyCrossSign(otherPub, newPub, sig))
+	require.False(t, VerifyCrossSign(oldPub, newPub, sig[:len(sig)-1]))
+	require.False(t, VerifyCrossSign(nil, newPub, sig))
+
+	// The signature only covers the rotation statement.
+	require.False(t, oldPub.Verify(sig, newPub.Bytes()))
+
+	// Follow a chain of two rotations.
+	_, nextPub, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+	sig2, err := CrossSign(newPriv, nextPub)
+	require.NoError(t, err)
+	require.True(t, VerifyCrossSign(newPub, nextPub, sig2))
+
+	_, err = CrossSign(oldPriv, oldPub)
+	require.ErrorIs(t, err, ErrSameKey)
+	_, err = CrossSign(oldPriv, nil)
+	require.Error(t, err)
+}
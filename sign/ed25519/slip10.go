@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: (c) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// slip10HardenedOffset is the first hardened child index.
+	slip10HardenedOffset = 0x80000000
+
+	// slip10MinSeedSize and slip10MaxSeedSize are the seed length
+	// bounds (128 to 512 bits) given by BIP-0032 and SLIP-0010.
+	slip10MinSeedSize = 16
+	slip10MaxSeedSize = 64
+)
+
+var slip10Curve = []byte("ed25519 seed")
+
+var (
+	// ErrInvalidDerivationPath indicates a malformed SLIP-0010 path.
+	ErrInvalidDerivationPath = errors.New("eddsa: invalid derivation path")
+
+	// ErrNonHardenedDerivation indicates that a path contained a
+	// non-hardened index, which SLIP-0010 does not define for ed25519.
+	ErrNonHardenedDerivation = errors.New("eddsa: ed25519 only supports hardened derivation")
+)
+
+// FromMnemonicSeed derives a PrivateKey from a BIP-0039 seed (the
+// output of the mnemonic's PBKDF2 stretching, not the mnemonic itself)
+// using SLIP-0010 hierarchical derivation for ed25519.
+//
+// The path uses the usual notation, for example "m/44'/501'/0'". Both
+// "'" and "H" mark a hardened index. SLIP-0010 only defines hardened
+// derivation for ed25519 so any non-hardened index is rejected.
+func FromMnemonicSeed(seed []byte, path string) (*PrivateKey, error) {
+	if len(seed) < slip10MinSeedSize || len(seed) > slip10MaxSeedSize {
+		return nil, fmt.Errorf("eddsa: seed length %d out of range [%d, %d]", len(seed), slip10MinSeedSize, slip10MaxSeedSize)
+	}
+	indexes, err := parseSLIP10Path(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, chainCode := slip10Master(seed)
+	for _, index := range indexes {
+		key, chainCode = slip10Child(key, chainCode, index)
+	}
+
+	privKey := new(PrivateKey)
+	privKey.privKey = ed25519.NewKeyFromSeed(key)
+	privKey.pubKey.pubKey = privKey.privKey.Public().(ed25519.PublicKey)
+	privKey.pubKey.rebuildB64String()
+	return privKey, nil
+}
+
+func slip10Master(seed []byte) (key, chainCode []byte) {
+	mac := hmac.New(sha512.New, slip10Curve)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+func slip10Child(key, chainCode []byte, index uint32) ([]byte, []byte) {
+	var data [1 + 32 + 4]byte
+	copy(data[1:], key)
+	binary.BigEndian.PutUint32(data[33:], index)
+
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data[:])
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
+
+func parseSLIP10Path(path string) ([]uint32, error) {
+	segments := strings.Split(path, "/")
+	if segments[0] != "m" {
+		return nil, ErrInvalidDerivationPath
+	}
+	indexes := make([]uint32, 0, len(segments)-1)
+	for _, segment := range segments[1:] {
+		hardened := strings.HasSuffix(segment, "'") || strings.HasSuffix(segment, "H")
+		if !hardened {
+			return nil, ErrNonHardenedDerivation
+		}
+		index, err := strconv.ParseUint(segment[:len(segment)-1], 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidDerivationPath, segment)
+		}
+		indexes = append(indexes, uint32(index)+slip10HardenedOffset)
+	}
+	return indexes, nil
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: (c) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed25519
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test vector 1 for ed25519 from
+// https://github.com/satoshilabs/slips/blob/master/slip-0010.md
+func TestFromMnemonicSeedVectors(t *testing.T) {
+	t.Parallel()
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+
+	vectors := []struct {
+		path string
+		seed string
+		pub  string
+	}{
+		{
+			path: "m",
+			seed: "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+			pub:  "a4b2856bfec510abab89753fac1ac0e1112364e7d250545963f135f2a33188ed",
+		},
+		{
+			path: "m/0'",
+			seed: "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3",
+			pub:  "8c8a13df77a28f3445213a0f432fde644acaa215fc72dcdf300d5efaa85d350c",
+		},
+		{
+			path: "m/0H/1H",
+			seed: "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2",
+			pub:  "1932a5270f335bed617d5b935c80aedb1a35bd9fc1e31acafd5372c30f5c1187",
+		},
+		{
+			path: "m/0'/1'/2'",
+			seed: "92a5b23c0b8a99e37d07df3fb9966917f5d06e02ddbd909c7e184371463e9fc9",
+			pub:  "ae98736566d30ed0e9d2f4486a64bc95740d89c7db33f52121f8ea8f76ff0fc1",
+		},
+	}
+
+	for _, v := range vectors {
+		privKey, err := FromMnemonicSeed(seed, v.path)
+		require.NoError(t, err, v.path)
+		require.Equal(t, v.seed, hex.EncodeToString(privKey.Bytes()[:KeySeedSize]), v.path)
+		require.Equal(t, v.pub, hex.EncodeToString(privKey.PublicKey().Bytes()), v.path)
+	}
+}
+
+func TestFromMnemonicSeedRejectsBadPaths(t *testing.T) {
+	t.Parallel()
+	seed := make([]byte, 64)
+
+	_, err := FromMnemonicSeed(seed, "m/44'/0")
+	require.ErrorIs(t, err, ErrNonHardenedDerivation)
+
+	_, err = FromMnemonicSeed(seed, "44'/0'")
+	require.ErrorIs(t, err, ErrInvalidDerivationPath)
+
+	_, err = FromMnemonicSeed(seed, "m/x'")
+	require.ErrorIs(t, err, ErrInvalidDerivationPath)
+
+	_, err = FromMnemonicSeed(seed[:8], "m/0'")
+	require.Error(t, err)
+}
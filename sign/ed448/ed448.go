@@ -0,0 +1,415 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+// Package ed448 is our Ed448 wrapper type which also conforms to our
+// generic interfaces for signature schemes, mirroring package ed25519.
+package ed448
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"github.com/katzenpost/circl/math/fp448"
+	"github.com/katzenpost/circl/sign/ed448"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/katzenpost/hpqc/nike/x448"
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/sign"
+	"github.com/katzenpost/hpqc/sign/pem"
+	"github.com/katzenpost/hpqc/util"
+)
+
+const (
+	// PublicKeySize is the size of a serialized PublicKey in bytes (57 bytes).
+	PublicKeySize = ed448.PublicKeySize
+
+	// PrivateKeySize is the size of a serialized PrivateKey in bytes (114 bytes).
+	PrivateKeySize = ed448.PrivateKeySize
+
+	// SignatureSize is the size of a serialized Signature in bytes (114 bytes).
+	SignatureSize = ed448.SignatureSize
+
+	// KeySeedSize is the size of the RFC 8032 seed used by NewKeyFromSeed
+	// to generate a new key deterministically (57 bytes).
+	KeySeedSize = ed448.SeedSize
+
+	// ContextMaxSize is the maximum length of a signature context.
+	ContextMaxSize = ed448.ContextMaxSize
+)
+
+var errInvalidKey = errors.New("ed448: invalid key")
+
+// paramD is the absolute value of the constant d = -39081 of the
+// edwards448 curve.
+var paramD = fp448.Elt{0xa9, 0x98}
+
+var _ sign.Scheme = (*scheme)(nil)
+var _ sign.PrehashPolicy = (*scheme)(nil)
+
+// Scheme implements our sign.Scheme interface using the Ed448 wrapper.
+type scheme struct{}
+
+var sch *scheme = &scheme{}
+
+// Scheme returns a sign Scheme interface.
+func Scheme() *scheme { return sch }
+
+// Name returns "Ed448", the same name as the circl scheme this package
+// replaces, so PEM files and hybrid names are unchanged.
+func (s *scheme) Name() string {
+	return "Ed448"
+}
+
+func (s *scheme) GenerateKey() (sign.PublicKey, sign.PrivateKey, error) {
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pubKey, privKey, nil
+}
+
+// Sign signs the message with pure Ed448, using the context of opts
+// if it is given. It panics if sk isn't a *PrivateKey or the context is
+// longer than ContextMaxSize.
+func (s *scheme) Sign(sk sign.PrivateKey, message []byte, opts *sign.SignatureOpts) []byte {
+	privKey, ok := sk.(*PrivateKey)
+	if !ok {
+		panic(sign.ErrTypeMismatch)
+	}
+	return ed448.Sign(privKey.privKey, message, contextOf(opts))
+}
+
+// Verify checks a pure Ed448 signature made with the context of opts.
+// It panics if pk isn't a *PublicKey.
+func (s *scheme) Verify(pk sign.PublicKey, message []byte, signature []byte, opts *sign.SignatureOpts) bool {
+	pubKey, ok := pk.(*PublicKey)
+	if !ok {
+		panic(sign.ErrTypeMismatch)
+	}
+	if len(pubKey.pubKey) != PublicKeySize {
+		return false
+	}
+	return ed448.Verify(pubKey.pubKey, message, signature, contextOf(opts))
+}
+
+// DeriveKey derives the key pair of the given RFC 8032 seed, which must
+// be KeySeedSize bytes long.
+func (s *scheme) DeriveKey(seed []byte) (sign.PublicKey, sign.PrivateKey) {
+	return NewKeyFromSeed(seed)
+}
+
+func (s *scheme) UnmarshalBinaryPublicKey(b []byte) (sign.PublicKey, error) {
+	pubKey := new(PublicKey)
+	err := pubKey.UnmarshalBinary(b)
+	if err != nil {
+		return nil, err
+	}
+	return pubKey, nil
+}
+
+func (s *scheme) UnmarshalBinaryPrivateKey(b []byte) (sign.PrivateKey, error) {
+	privKey := new(PrivateKey)
+	err := privKey.FromBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	return privKey, nil
+}
+
+func (s *scheme) PublicKeySize() int {
+	return PublicKeySize
+}
+
+func (s *scheme) PrivateKeySize() int {
+	return PrivateKeySize
+}
+
+func (s *scheme) SignatureSize() int {
+	return SignatureSize
+}
+
+func (s *scheme) SeedSize() int {
+	return KeySeedSize
+}
+
+// SupportsContext returns true since Ed448 signatures bind a context
+// of up to ContextMaxSize bytes.
+func (s *scheme) SupportsContext() bool {
+	return true
+}
+
+// PrehashRequired returns false because pure Ed448 signs the message
+// itself, hashing it internally with SHAKE256.
+func (s *scheme) PrehashRequired() bool {
+	return false
+}
+
+// DigestAlgorithm returns 0 since the message is not pre-hashed.
+func (s *scheme) DigestAlgorithm() crypto.Hash {
+	return 0
+}
+
+func contextOf(opts *sign.SignatureOpts) string {
+	if opts == nil {
+		return ""
+	}
+	return opts.Context
+}
+
+type PrivateKey struct {
+	pubKey  PublicKey
+	privKey ed448.PrivateKey
+}
+
+func NewEmptyPrivateKey() *PrivateKey {
+	return &PrivateKey{
+		privKey: make([]byte, PrivateKeySize),
+	}
+}
+
+func (p *PrivateKey) Scheme() sign.Scheme {
+	return Scheme()
+}
+
+func (p *PrivateKey) Equal(key crypto.PrivateKey) bool {
+	privKey, ok := key.(*PrivateKey)
+	return ok && hmac.Equal(p.Bytes(), privKey.Bytes())
+}
+
+func (p *PrivateKey) MarshalBinary() ([]byte, error) {
+	return p.Bytes(), nil
+}
+
+func (p *PrivateKey) UnmarshalBinary(b []byte) error {
+	return p.FromBytes(b)
+}
+
+// signer interface methods
+
+func (p *PrivateKey) Public() crypto.PublicKey {
+	return p.PublicKey()
+}
+
+// Sign signs message with pure Ed448 and an empty context. The
+// crypto.Signer interface doesn't allow a context, so use the scheme's
+// Sign for that. It fails if opts asks for a pre-hashed message.
+func (p *PrivateKey) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) (signature []byte, err error) {
+	if opts != nil && opts.HashFunc() != 0 {
+		return nil, errors.New("ed448: cannot sign hashed message")
+	}
+	return p.SignMessage(message), nil
+}
+
+// InternalPtr returns a pointer to the internal (circl ed448) data
+// structure. Most people should not use this.
+func (p *PrivateKey) InternalPtr() *ed448.PrivateKey {
+	return &p.privKey
+}
+
+func (p *PrivateKey) KeyType() string {
+	return "ED448 PRIVATE KEY"
+}
+
+// SignMessage signs message with pure Ed448 and an empty context.
+func (p *PrivateKey) SignMessage(message []byte) (signature []byte) {
+	return ed448.Sign(p.privKey, message, "")
+}
+
+func (p *PrivateKey) Reset() {
+	p.pubKey.Reset()
+	util.ExplicitBzero(p.privKey)
+}
+
+func (p *PrivateKey) Bytes() []byte {
+	return p.privKey
+}
+
+// FromBytes deserializes the byte slice b into the PrivateKey. The
+// public key half of b is recomputed from the seed rather than trusted.
+func (p *PrivateKey) FromBytes(b []byte) error {
+	if len(b) != PrivateKeySize {
+		return errInvalidKey
+	}
+	return p.FromSeed(b[:KeySeedSize])
+}
+
+// FromSeed loads the PrivateKey from a 57 byte RFC 8032 seed.
+func (p *PrivateKey) FromSeed(seed []byte) error {
+	if len(seed) != KeySeedSize {
+		return errInvalidKey
+	}
+
+	p.privKey = ed448.NewKeyFromSeed(seed)
+	p.pubKey.pubKey = p.privKey.Public().(ed448.PublicKey)
+	p.pubKey.rebuildB64String()
+	return nil
+}
+
+// Seed returns a copy of the 57 byte RFC 8032 seed the PrivateKey was
+// derived from, which is the compact form accepted by FromSeed.
+func (p *PrivateKey) Seed() []byte {
+	return p.privKey.Seed()
+}
+
+// Identity returns the key's identity, in this case it's our
+// public key in bytes.
+func (p *PrivateKey) Identity() []byte {
+	return p.PublicKey().Bytes()
+}
+
+// PublicKey returns the PublicKey corresponding to the PrivateKey.
+func (p *PrivateKey) PublicKey() *PublicKey {
+	return &p.pubKey
+}
+
+// PublicKey is the EdDSA public key using Ed448.
+type PublicKey struct {
+	pubKey    ed448.PublicKey
+	b64String string
+}
+
+func (p *PublicKey) Scheme() sign.Scheme {
+	return Scheme()
+}
+
+func (p *PublicKey) Equal(pubKey crypto.PublicKey) bool {
+	k, ok := pubKey.(*PublicKey)
+	return ok && hmac.Equal(p.pubKey, k.pubKey)
+}
+
+func (p *PublicKey) MarshalBinary() ([]byte, error) {
+	return p.Bytes(), nil
+}
+
+// ToECDH converts the PublicKey to the corresponding X448 public key,
+// using the 4-isogeny u = y^2/x^2 from RFC 7748. The X448 private key
+// matching it is the first 56 bytes of the SHAKE256 hash of the seed,
+// which is the Ed448 secret scalar before clamping.
+func (p *PublicKey) ToECDH() *x448.PublicKey {
+	var y fp448.Elt
+	copy(y[:], p.pubKey[:fp448.Size])
+	fp448.Modp(&y)
+
+	// x^2 = (1 - y^2) / (1 - d*y^2) on the Edwards curve, so
+	// u = y^2 * (1 - d*y^2) / (1 - y^2) with d = -paramD.
+	var yy, num, den, u fp448.Elt
+	one := fp448.One()
+	fp448.Sqr(&yy, &y)
+	fp448.Mul(&num, &yy, &paramD)
+	fp448.Add(&num, &num, &one)
+	fp448.Mul(&num, &num, &yy)
+	fp448.Sub(&den, &one, &yy)
+	fp448.Inv(&den, &den)
+	fp448.Mul(&u, &num, &den)
+	fp448.Modp(&u)
+
+	r := new(x448.PublicKey)
+	if r.FromBytes(u[:]) != nil {
+		panic("ed448: x448 public key of the wrong size, impossible")
+	}
+	return r
+}
+
+// InternalPtr returns a pointer to the internal (circl ed448) data
+// structure. Most people should not use this.
+func (p *PublicKey) InternalPtr() *ed448.PublicKey {
+	return &p.pubKey
+}
+
+func (p *PublicKey) KeyType() string {
+	return "ED448 PUBLIC KEY"
+}
+
+func (p *PublicKey) Sum256() [32]byte {
+	return blake2b.Sum256(p.Bytes())
+}
+
+// Verify checks a pure Ed448 signature of message with an empty context.
+func (p *PublicKey) Verify(signature, message []byte) bool {
+	if len(p.pubKey) != PublicKeySize {
+		return false
+	}
+	return ed448.Verify(p.pubKey, message, signature, "")
+}
+
+func (p *PublicKey) Reset() {
+	util.ExplicitBzero(p.pubKey)
+	p.b64String = "[scrubbed]"
+}
+
+func (p *PublicKey) Bytes() []byte {
+	return p.pubKey
+}
+
+// ByteArray returns the raw public key as an array suitable for use as a map
+// key.
+func (p *PublicKey) ByteArray() [PublicKeySize]byte {
+	var pk [PublicKeySize]byte
+	copy(pk[:], p.pubKey[:])
+	return pk
+}
+
+func (p *PublicKey) rebuildB64String() {
+	p.b64String = base64.StdEncoding.EncodeToString(p.Bytes())
+}
+
+func (p *PublicKey) FromBytes(data []byte) error {
+	if len(data) != PublicKeySize {
+		return errInvalidKey
+	}
+
+	p.pubKey = make([]byte, PublicKeySize)
+	copy(p.pubKey, data)
+	p.rebuildB64String()
+	return nil
+}
+
+func (p *PublicKey) UnmarshalBinary(data []byte) error {
+	return p.FromBytes(data)
+}
+
+func (p *PublicKey) MarshalText() (text []byte, err error) {
+	return pem.ToPublicPEMBytes(p), nil
+}
+
+func (p *PublicKey) UnmarshalText(text []byte) error {
+	b, err := pem.FromPublicPEMToBytes(text, p.Scheme())
+	if err != nil {
+		return err
+	}
+	return p.FromBytes(b)
+}
+
+// NewKeypair generates a new PrivateKey sampled from the provided entropy
+// source.
+func NewKeypair(r io.Reader) (*PrivateKey, *PublicKey, error) {
+	seed := make([]byte, KeySeedSize)
+	defer util.ExplicitBzero(seed)
+	if _, err := io.ReadFull(r, seed); err != nil {
+		return nil, nil, err
+	}
+
+	k := new(PrivateKey)
+	if err := k.FromSeed(seed); err != nil {
+		return nil, nil, err
+	}
+	return k, k.PublicKey(), nil
+}
+
+// NewKeyFromSeed derives the key pair of the given RFC 8032 seed, as
+// circl's Ed448 scheme does, so keys derived before this package existed
+// are unchanged. It panics if seed isn't KeySeedSize bytes long.
+func NewKeyFromSeed(seed []byte) (*PublicKey, *PrivateKey) {
+	if len(seed) != KeySeedSize {
+		panic("seed must be of length KeySeedSize")
+	}
+	k := new(PrivateKey)
+	if err := k.FromSeed(seed); err != nil {
+		panic(err)
+	}
+	return k.PublicKey(), k
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package ed448
+
+import (
+	"encoding/hex"
+	"testing"
+
+	circlx448 "github.com/katzenpost/circl/dh/x448"
+	circled448 "github.com/katzenpost/circl/sign/ed448"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/katzenpost/hpqc/rand"
+	"github.com/katzenpost/hpqc/sign"
+)
+
+func TestEd448Scheme(t *testing.T) {
+	t.Parallel()
+	message := []byte("hello world")
+	pubKey, privKey, err := Scheme().GenerateKey()
+	require.NoError(t, err)
+	signature := Scheme().Sign(privKey, message, nil)
+	require.Equal(t, Scheme().SignatureSize(), len(signature))
+	require.True(t, Scheme().Verify(pubKey, message, signature, nil))
+	require.True(t, pubKey.(*PublicKey).Verify(signature, message))
+
+	opts := &sign.SignatureOpts{Context: "context"}
+	signature = Scheme().Sign(privKey, message, opts)
+	require.True(t, Scheme().Verify(pubKey, message, signature, opts))
+	require.False(t, Scheme().Verify(pubKey, message, signature, nil))
+}
+
+func TestRFC8032(t *testing.T) {
+	t.Parallel()
+	// RFC 8032 section 7.4, "1 octet".
+	seed, _ := hex.DecodeString("c4eab05d357007c632f3dbb48489924d552b08fe0c353a0d4a1f00acda2c463afbea67c5e8d2877c5e3bc397a659949ef8021e954e0a12274e")
+	wantPub, _ := hex.DecodeString("43ba28f430cdff456ae531545f7ecd0ac834a55d9358c0372bfa0c6c6798c0866aea01eb00742802b8438ea4cb82169c235160627b4c3a9480")
+	wantSig, _ := hex.DecodeString("26b8f91727bd62897af15e41eb43c377efb9c610d48f2335cb0bd0087810f4352541b143c4b981b7e18f62de8ccdf633fc1bf037ab7cd779805e0dbcc0aae1cbcee1afb2e027df36bc04dcecbf154336c19f0af7e0a6472905e799f1953d2a0ff3348ab21aa4adafd1d234441cf807c03a00")
+
+	pubKey, privKey := NewKeyFromSeed(seed)
+	require.Equal(t, wantPub, pubKey.Bytes())
+	require.Equal(t, seed, privKey.Seed())
+	require.Equal(t, wantSig, privKey.SignMessage([]byte{0x03}))
+	require.True(t, pubKey.Verify(wantSig, []byte{0x03}))
+}
+
+func TestCirclCompatible(t *testing.T) {
+	t.Parallel()
+	seed := make([]byte, KeySeedSize)
+	_, err := rand.Reader.Read(seed)
+	require.NoError(t, err)
+
+	// Keys and signatures are interchangeable with the circl scheme
+	// which was registered as Ed448 before.
+	circlPub, circlPriv := circled448.Scheme().DeriveKey(seed)
+	pubKey, privKey := Scheme().DeriveKey(seed)
+	circlPubBytes, err := circlPub.MarshalBinary()
+	require.NoError(t, err)
+	circlPrivBytes, err := circlPriv.MarshalBinary()
+	require.NoError(t, err)
+	pubBytes, err := pubKey.MarshalBinary()
+	require.NoError(t, err)
+	privBytes, err := privKey.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, circlPubBytes, pubBytes)
+	require.Equal(t, circlPrivBytes, privBytes)
+
+	privKey2, err := Scheme().UnmarshalBinaryPrivateKey(circlPrivBytes)
+	require.NoError(t, err)
+	require.True(t, privKey.Equal(privKey2))
+
+	msg := []byte("message")
+	opts := &sign.SignatureOpts{Context: "ctx"}
+	sig := Scheme().Sign(privKey, msg, opts)
+	require.True(t, circled448.Scheme().Verify(circlPub, msg, sig, opts))
+}
+
+func TestToECDH(t *testing.T) {
+	t.Parallel()
+	for i := 0; i < 8; i++ {
+		privKey, pubKey, err := NewKeypair(rand.Reader)
+		require.NoError(t, err)
+
+		// The converted key matches the X448 key of the same secret.
+		h := make([]byte, 2*KeySeedSize)
+		sha3.ShakeSum256(h, privKey.Seed())
+		var secret, want circlx448.Key
+		copy(secret[:], h)
+		circlx448.KeyGen(&want, &secret)
+
+		require.Equal(t, want[:], pubKey.ToECDH().Bytes())
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	t.Parallel()
+	privKey, pubKey, err := NewKeypair(rand.Reader)
+	require.NoError(t, err)
+
+	text, err := pubKey.MarshalText()
+	require.NoError(t, err)
+	pubKey2 := new(PublicKey)
+	require.NoError(t, pubKey2.UnmarshalText(text))
+	require.True(t, pubKey.Equal(pubKey2))
+
+	privKey2 := NewEmptyPrivateKey()
+	require.NoError(t, privKey2.FromSeed(privKey.Seed()))
+	require.True(t, privKey.Equal(privKey2))
+	require.Equal(t, pubKey.Bytes(), privKey2.Identity())
+
+	require.Error(t, pubKey2.FromBytes(pubKey.Bytes()[1:]))
+	require.Error(t, privKey2.FromBytes(privKey.Bytes()[1:]))
+	require.False(t, pubKey.Equal(privKey))
+
+	privKey.Reset()
+	require.Equal(t, make([]byte, PrivateKeySize), privKey.Bytes())
+}
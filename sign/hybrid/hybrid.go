@@ -10,6 +10,7 @@ import (
 
 	"github.com/katzenpost/hpqc/sign"
 	"github.com/katzenpost/hpqc/sign/pem"
+	"github.com/katzenpost/hpqc/util"
 )
 
 // Scheme is for hybrid signature schemes.
@@ -57,9 +58,15 @@ func (s *Scheme) GenerateKey() (sign.PublicKey, sign.PrivateKey, error) {
 		}, nil
 }
 
-func (s *Scheme) Sign(sk sign.PrivateKey, message []byte, opts *sign.SignatureOpts) []byte {
-	return append(s.first.Sign(sk.(*PrivateKey).first, message, opts),
-		s.second.Sign(sk.(*PrivateKey).second, message, opts)...)
+// Sign signs the message with both component schemes and returns the
+// concatenation of their signatures. Panics of the component schemes
+// are redacted by util.SafePanic.
+func (s *Scheme) Sign(sk sign.PrivateKey, message []byte, opts *sign.SignatureOpts) (sig []byte) {
+	util.SafePanic(func() {
+		sig = append(s.first.Sign(sk.(*PrivateKey).first, message, opts),
+			s.second.Sign(sk.(*PrivateKey).second, message, opts)...)
+	})
+	return sig
 }
 
 func (s *Scheme) Verify(pk sign.PublicKey, message []byte, signature []byte, opts *sign.SignatureOpts) bool {
@@ -150,6 +157,22 @@ func (s *Scheme) SupportsContext() bool {
 	return true
 }
 
+// PrehashRequired returns true if either component scheme requires a
+// pre-hashed message. Both components are given the same message, so
+// a hybrid only works if its components agree on the digest algorithm.
+func (s *Scheme) PrehashRequired() bool {
+	return sign.PrehashRequired(s.first) || sign.PrehashRequired(s.second)
+}
+
+// DigestAlgorithm returns the digest algorithm of the first component
+// which requires one, or 0 if neither does.
+func (s *Scheme) DigestAlgorithm() crypto.Hash {
+	if h := sign.DigestAlgorithm(s.first); h != 0 {
+		return h
+	}
+	return sign.DigestAlgorithm(s.second)
+}
+
 // PrivateKey is the private key in hybrid signature scheme.
 type PrivateKey struct {
 	scheme    *Scheme
@@ -7,9 +7,8 @@
 package hybrid
 
 import (
-	"github.com/katzenpost/circl/sign/ed448"
-
 	"github.com/katzenpost/hpqc/sign/ed25519"
+	"github.com/katzenpost/hpqc/sign/ed448"
 	"github.com/katzenpost/hpqc/sign/sphincsplus"
 )
 
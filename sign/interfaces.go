@@ -146,6 +146,44 @@ type Scheme interface {
 	SupportsContext() bool
 }
 
+// PrehashPolicy is implemented by schemes which report how they expect
+// the message to be presented to Sign and Verify. It is kept separate
+// from Scheme so that schemes provided by external packages remain
+// valid Schemes; use the PrehashRequired and DigestAlgorithm functions
+// to query any Scheme.
+type PrehashPolicy interface {
+	// PrehashRequired returns true if the message passed to Sign and
+	// Verify must already be a digest computed with DigestAlgorithm,
+	// and false if the scheme hashes arbitrary length messages itself.
+	PrehashRequired() bool
+
+	// DigestAlgorithm returns the hash the caller must apply to the
+	// message before signing when PrehashRequired is true, and 0 when
+	// the scheme takes the message as is.
+	DigestAlgorithm() crypto.Hash
+}
+
+// PrehashRequired reports whether the given scheme expects a pre-hashed
+// message. Schemes not implementing PrehashPolicy are assumed to hash
+// the message internally.
+func PrehashRequired(s Scheme) bool {
+	p, ok := s.(PrehashPolicy)
+	if !ok {
+		return false
+	}
+	return p.PrehashRequired()
+}
+
+// DigestAlgorithm returns the digest algorithm the given scheme expects
+// the message to be hashed with, or 0 if it takes the message as is.
+func DigestAlgorithm(s Scheme) crypto.Hash {
+	p, ok := s.(PrehashPolicy)
+	if !ok {
+		return 0
+	}
+	return p.DigestAlgorithm()
+}
+
 var (
 	// ErrTypeMismatch is the error used if types of, for instance, private
 	// and public keys don't match.
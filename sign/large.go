@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sign
+
+import (
+	"errors"
+	"io"
+
+	"github.com/katzenpost/hpqc/util"
+)
+
+// ErrDigestUnavailable is returned by SignLarge and VerifyLarge if the
+// scheme requires a prehashed message but its digest algorithm isn't
+// linked into the binary.
+var ErrDigestUnavailable = errors.New("sign: digest algorithm unavailable")
+
+// StreamSigner is implemented by schemes which can sign and verify a
+// message read from an io.Reader without holding it in memory. The
+// signatures must be those Scheme.Sign makes for the whole message, so
+// that they can be checked with Scheme.Verify. A prehashed variant of a
+// scheme, such as Ed25519ph for Ed25519, makes different signatures and
+// so must not be passed off as the scheme itself; Ed25519ph is offered
+// by the ed25519 package's Signer and Verifier instead.
+type StreamSigner interface {
+	// SignStream signs the message read from r until EOF.
+	SignStream(sk PrivateKey, r io.Reader) ([]byte, error)
+
+	// VerifyStream checks sig against the message read from r until
+	// EOF. The error is only non-nil if reading r failed.
+	VerifyStream(pk PublicKey, r io.Reader, sig []byte) (bool, error)
+}
+
+// SignLarge signs the message read from r until EOF in the way best
+// suited to the scheme: schemes implementing StreamSigner stream the
+// message, schemes for which PrehashRequired is true get its digest,
+// and the message is buffered in memory for all other schemes. Except
+// for schemes requiring a prehashed message, whose signatures are of
+// the digest, the signature is the one Scheme.Sign makes for the whole
+// message, and can be checked with Scheme.Verify as well as with
+// VerifyLarge. Panics of the scheme are redacted by util.SafePanic.
+func SignLarge(scheme Scheme, sk PrivateKey, r io.Reader) (sig []byte, err error) {
+	util.SafePanic(func() {
+		sig, err = signLarge(scheme, sk, r)
+	})
+	return sig, err
+}
+
+func signLarge(scheme Scheme, sk PrivateKey, r io.Reader) ([]byte, error) {
+	if s, ok := scheme.(StreamSigner); ok {
+		return s.SignStream(sk, r)
+	}
+	msg, err := readLarge(scheme, r)
+	if err != nil {
+		return nil, err
+	}
+	return scheme.Sign(sk, msg, nil), nil
+}
+
+// VerifyLarge checks a signature made by SignLarge against the message
+// read from r until EOF. The error is only non-nil if reading r failed.
+// Panics of the scheme are redacted by util.SafePanic.
+func VerifyLarge(scheme Scheme, pk PublicKey, r io.Reader, sig []byte) (ok bool, err error) {
+	util.SafePanic(func() {
+		ok, err = verifyLarge(scheme, pk, r, sig)
+	})
+	return ok, err
+}
+
+func verifyLarge(scheme Scheme, pk PublicKey, r io.Reader, sig []byte) (bool, error) {
+	if s, ok := scheme.(StreamSigner); ok {
+		return s.VerifyStream(pk, r, sig)
+	}
+	msg, err := readLarge(scheme, r)
+	if err != nil {
+		return false, err
+	}
+	return scheme.Verify(pk, msg, sig, nil), nil
+}
+
+// readLarge returns the message read from r as the scheme expects it,
+// which is either its digest or the message itself.
+func readLarge(scheme Scheme, r io.Reader) ([]byte, error) {
+	if !PrehashRequired(scheme) {
+		return io.ReadAll(r)
+	}
+	hash := DigestAlgorithm(scheme)
+	if !hash.Available() {
+		return nil, ErrDigestUnavailable
+	}
+	h := hash.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
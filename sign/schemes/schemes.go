@@ -6,12 +6,12 @@ package schemes
 import (
 	"strings"
 
-	"github.com/katzenpost/circl/sign/ed448"
 	"github.com/katzenpost/circl/sign/eddilithium2"
 	"github.com/katzenpost/circl/sign/eddilithium3"
 
 	"github.com/katzenpost/hpqc/sign"
 	"github.com/katzenpost/hpqc/sign/ed25519"
+	"github.com/katzenpost/hpqc/sign/ed448"
 	"github.com/katzenpost/hpqc/sign/hybrid"
 	"github.com/katzenpost/hpqc/sign/sphincsplus"
 )
@@ -31,6 +31,10 @@ var allSchemes = []sign.Scheme{
 	ed448.Scheme(),
 
 	// hybrid post quantum
+
+	// Our circl fork only has round 3 Dilithium, whose keys and
+	// signatures are not compatible with FIPS 204 ML-DSA, so a standalone
+	// ML-DSA-65 scheme will be added here once the fork provides it.
 	eddilithium2.Scheme(),
 	eddilithium3.Scheme(),
 }
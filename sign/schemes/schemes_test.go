@@ -62,6 +62,8 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package schemes_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -214,3 +216,143 @@ func BenchmarkVerify(b *testing.B) {
 		})
 	}
 }
+
+func TestPrehashPolicy(t *testing.T) {
+	ed := schemes.ByName("Ed25519")
+	if sign.PrehashRequired(ed) || sign.DigestAlgorithm(ed) != 0 {
+		t.Fatal("ed25519 must not require a pre-hashed message")
+	}
+	for _, scheme := range schemes.All() {
+		if !sign.PrehashRequired(scheme) && sign.DigestAlgorithm(scheme) != 0 {
+			t.Fatalf("%s reports a digest algorithm without requiring prehashing", scheme.Name())
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	msg := []byte("signed artifact")
+	for _, scheme := range schemes.All() {
+		pk, sk, err := scheme.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		packedPk, err := pk.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := scheme.Sign(sk, msg, nil)
+
+		ok, err := schemes.Verify(scheme.Name(), packedPk, sig, msg)
+		if err != nil || !ok {
+			t.Fatalf("%s: valid signature rejected: %v", scheme.Name(), err)
+		}
+		ok, err = schemes.Verify(scheme.Name(), packedPk, sig, []byte("tampered"))
+		if err != nil || ok {
+			t.Fatalf("%s: signature of another message accepted", scheme.Name())
+		}
+		ok, err = schemes.Verify(scheme.Name(), packedPk, sig[1:], msg)
+		if err != nil || ok {
+			t.Fatalf("%s: truncated signature accepted", scheme.Name())
+		}
+		_, err = schemes.Verify(scheme.Name(), packedPk[1:], sig, msg)
+		if err == nil {
+			t.Fatalf("%s: malformed public key not reported", scheme.Name())
+		}
+	}
+
+	_, err := schemes.Verify("bogus", nil, nil, nil)
+	if !errors.Is(err, schemes.ErrUnknownScheme) {
+		t.Fatal(err)
+	}
+}
+
+func TestSignLargeBuffered(t *testing.T) {
+	var scheme sign.Scheme
+	for _, s := range schemes.All() {
+		if _, ok := s.(sign.StreamSigner); !ok {
+			scheme = s
+			break
+		}
+	}
+	if scheme == nil {
+		t.Fatal("expected a scheme without streaming support")
+	}
+	pk, sk, err := scheme.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := bytes.Repeat([]byte("large message "), 10000)
+
+	sig, err := sign.SignLarge(scheme, sk, bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scheme.Verify(pk, msg, sig, nil) {
+		t.Fatal("buffered SignLarge should match Sign")
+	}
+	ok, err := sign.VerifyLarge(scheme, pk, bytes.NewReader(msg), sig)
+	if err != nil || !ok {
+		t.Fatalf("VerifyLarge failed: %v", err)
+	}
+}
+
+func TestSignLargeVerify(t *testing.T) {
+	msg := bytes.Repeat([]byte("large message "), 1000)
+	for _, scheme := range schemes.All() {
+		if sign.PrehashRequired(scheme) {
+			continue
+		}
+		pk, sk, err := scheme.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		packedPk, err := pk.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := sign.SignLarge(scheme, sk, bytes.NewReader(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the signature is the scheme's own, whatever SignLarge did
+		ok, err := schemes.Verify(scheme.Name(), packedPk, sig, msg)
+		if err != nil || !ok {
+			t.Fatalf("%s: SignLarge signature rejected by Verify: %v", scheme.Name(), err)
+		}
+	}
+}
+
+func TestVerifyBatchFallback(t *testing.T) {
+	scheme := schemes.ByName("ed448")
+	if _, ok := scheme.(sign.BatchVerifier); ok {
+		t.Fatal("expected ed448 to use the sequential fallback")
+	}
+	keys := make([]sign.PublicKey, 3)
+	msgs := make([][]byte, 3)
+	sigs := make([][]byte, 3)
+	for i := range keys {
+		pk, sk, err := scheme.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = pk
+		msgs[i] = []byte{byte(i)}
+		sigs[i] = scheme.Sign(sk, msgs[i], nil)
+	}
+	all, results := sign.VerifyBatch(scheme, keys, msgs, sigs)
+	if !all || len(results) != 3 {
+		t.Fatalf("expected all signatures to verify, got %v", results)
+	}
+
+	keys[2] = nil
+	msgs[0] = []byte("tampered")
+	all, results = sign.VerifyBatch(scheme, keys, msgs, sigs)
+	if all || results[0] || !results[1] || results[2] {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	all, results = sign.VerifyBatch(scheme, nil, nil, nil)
+	if all || len(results) != 0 {
+		t.Fatal("an empty batch should not be valid")
+	}
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package schemes
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/katzenpost/hpqc/util"
+)
+
+// ErrUnknownScheme is returned by Verify if no signature scheme has the
+// given name.
+var ErrUnknownScheme = errors.New("schemes: unknown signature scheme")
+
+// Verify looks up the named signature scheme, unmarshals the public key
+// and returns whether sig is a valid signature of msg. An error is
+// returned if the scheme is unknown or the public key is malformed,
+// while an invalid or wrongly sized signature is reported as false
+// with a nil error. Panics of the scheme are redacted by util.SafePanic.
+func Verify(schemeName string, pubBytes, sig, msg []byte) (bool, error) {
+	scheme := ByName(schemeName)
+	if scheme == nil {
+		return false, fmt.Errorf("%w: %q", ErrUnknownScheme, schemeName)
+	}
+	pubKey, err := scheme.UnmarshalBinaryPublicKey(pubBytes)
+	if err != nil {
+		return false, fmt.Errorf("schemes: malformed %s public key: %w", scheme.Name(), err)
+	}
+	if len(sig) != scheme.SignatureSize() {
+		return false, nil
+	}
+	ok := false
+	util.SafePanic(func() {
+		ok = scheme.Verify(pubKey, msg, sig, nil)
+	})
+	return ok, nil
+}
@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sign
+
+import "errors"
+
+// ErrNotResettable is returned by WithPrivateKey when the private key
+// doesn't implement Resetter.
+var ErrNotResettable = errors.New("sign: private key cannot be reset")
+
+// Resetter is implemented by private keys, such as ed25519's, which can
+// overwrite their secret key material with zeros.
+type Resetter interface {
+	Reset()
+}
+
+// WithPrivateKey calls fn with the given signing key and resets the key
+// when fn returns or panics. If the key can't be reset, fn isn't called
+// and ErrNotResettable is returned.
+func WithPrivateKey(sk PrivateKey, fn func(PrivateKey) error) error {
+	r, ok := sk.(Resetter)
+	if !ok {
+		return ErrNotResettable
+	}
+	defer r.Reset()
+	return fn(sk)
+}
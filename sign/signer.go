@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package sign
+
+// Signer is implemented by signing keys whose secret material is held
+// outside of this process, such as in an HSM or a remote signing
+// service. Only the public key and the ability to sign are exposed.
+//
+// Signatures produced by a Signer are verified like any other, by
+// passing the PublicKey returned by Public to the Scheme's Verify.
+type Signer interface {
+	// Public returns the public key corresponding to the signing key.
+	Public() PublicKey
+
+	// Sign signs the given message and returns the signature.
+	Sign(message []byte) ([]byte, error)
+}
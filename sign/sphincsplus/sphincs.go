@@ -4,6 +4,11 @@
 // SPDX-License-Identifier: AGPL-3.0-only
 
 // Package sphincsplus implements interface wrapper around a specific parameterization of Sphincs+.
+//
+// It wraps the C reference implementation from
+// github.com/katzenpost/sphincsplus, since circl has no SPHINCS+, and is
+// registered in the sign/schemes package as "Sphincs+" on platforms
+// other than windows.
 package sphincsplus
 
 import (
@@ -116,6 +121,16 @@ func (s *scheme) SupportsContext() bool {
 	return false
 }
 
+// PrehashRequired returns false, SPHINCS+ signs arbitrary length messages.
+func (s *scheme) PrehashRequired() bool {
+	return false
+}
+
+// DigestAlgorithm returns 0 since the message is not pre-hashed.
+func (s *scheme) DigestAlgorithm() crypto.Hash {
+	return 0
+}
+
 type privateKey struct {
 	scheme     *scheme
 	privateKey *sphincs.PrivateKey
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+)
+
+// redactPatterns match the forms key material usually takes when it is
+// formatted into a panic message: long hex and base64 strings, and the
+// "[1 2 3 ...]" form fmt gives byte slices.
+var redactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9+/_-]{43,}={0,2}`),
+	regexp.MustCompile(`[0-9a-fA-F]{32,}`),
+	regexp.MustCompile(`\[(?:[0-9]{1,3} ){7,}[0-9]{1,3}\]`),
+}
+
+// RedactedPanic is the value SafePanic panics with in place of a panic
+// value which looked like it held secret material.
+type RedactedPanic struct {
+	// Msg is the panic message with anything resembling key material
+	// replaced by "[redacted]".
+	Msg string
+}
+
+// Error returns the redacted panic message.
+func (p *RedactedPanic) Error() string {
+	return p.Msg
+}
+
+// SafePanic calls fn, and if it panics, zeroizes the given secrets and
+// panics again with a value which can't carry raw key bytes into logs,
+// crash reports or the callers which recover it. Pass it the temporary
+// secret buffers fn works with, not keys owned by the caller.
+//
+// Panic values are kept as they are unless they might hold key
+// material: byte slices and arrays are replaced by a RedactedPanic
+// naming only their type, and strings, errors and other values whose
+// message contains long hex or base64 strings or a formatted byte slice
+// are replaced by a RedactedPanic with those parts redacted. Runtime
+// errors, such as an index out of range, only ever name indices and
+// types and are kept.
+//
+// A panic value which is kept is raised again before the stack of fn is
+// unwound, so the trace printed if it is never recovered still shows
+// where fn panicked. A redacted panic is raised once fn has returned,
+// so neither the original value nor the stack of fn appears in it.
+func SafePanic(fn func(), secrets ...[]byte) {
+	var redacted interface{}
+	func() {
+		panicked := true
+		defer func() {
+			if !panicked {
+				return
+			}
+			v := recover()
+			for _, s := range secrets {
+				ExplicitBzero(s)
+			}
+			r, ok := redactPanic(v)
+			if !ok {
+				panic(v)
+			}
+			redacted = r
+		}()
+		fn()
+		panicked = false
+	}()
+	if redacted != nil {
+		panic(redacted)
+	}
+}
+
+// redactPanic returns the value to panic with in place of v, and
+// whether it differs from v.
+func redactPanic(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case runtime.Error:
+		return v, false
+	case string:
+		if msg := redactString(x); msg != x {
+			return &RedactedPanic{Msg: msg}, true
+		}
+		return v, false
+	case error:
+		if msg := x.Error(); redactString(msg) != msg {
+			return &RedactedPanic{Msg: redactString(msg)}, true
+		}
+		return v, false
+	}
+	if t := reflect.TypeOf(v); t != nil {
+		if k := t.Kind(); (k == reflect.Slice || k == reflect.Array) && t.Elem().Kind() == reflect.Uint8 {
+			return &RedactedPanic{Msg: fmt.Sprintf("panic with a %T value [redacted]", v)}, true
+		}
+	}
+	if msg := fmt.Sprint(v); redactString(msg) != msg {
+		return &RedactedPanic{Msg: redactString(msg)}, true
+	}
+	return v, false
+}
+
+func redactString(s string) string {
+	for _, p := range redactPatterns {
+		s = p.ReplaceAllString(s, "[redacted]")
+	}
+	return s
+}
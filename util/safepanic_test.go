@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: Copyright (C) 2024 David Stainton
+// SPDX-License-Identifier: AGPL-3.0-only
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func recoverSafePanic(fn func(), secrets ...[]byte) (v interface{}) {
+	defer func() {
+		v = recover()
+	}()
+	SafePanic(fn, secrets...)
+	return nil
+}
+
+func recoverSafePanicStack(fn func()) (stack string) {
+	defer func() {
+		recover()
+		stack = string(debug.Stack())
+	}()
+	SafePanic(fn)
+	return ""
+}
+
+//go:noinline
+func panicInFn(msg string) {
+	panic(msg)
+}
+
+func TestSafePanic(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	hexKey := fmt.Sprintf("%x", key)
+
+	// No panic, no change.
+	ran := false
+	require.Nil(t, recoverSafePanic(func() { ran = true }))
+	require.True(t, ran)
+
+	// Secret material is redacted from every kind of panic value.
+	for _, v := range []interface{}{
+		"bad key " + hexKey,
+		fmt.Errorf("bad key %x", key),
+		fmt.Sprintf("bad key %v", key),
+		key,
+		[32]byte{},
+		struct{ k []byte }{key},
+	} {
+		r := recoverSafePanic(func() { panic(v) })
+		p, ok := r.(*RedactedPanic)
+		require.True(t, ok, "%T", v)
+		require.NotContains(t, p.Error(), hexKey)
+		require.NotContains(t, p.Error(), fmt.Sprint(key))
+		require.NotContains(t, p.Error(), fmt.Sprint(key[:8]))
+	}
+	r := recoverSafePanic(func() { panic("bad key " + hexKey) })
+	require.Equal(t, "bad key [redacted]", r.(*RedactedPanic).Msg)
+
+	// Other panic values are kept.
+	sentinel := errors.New("seed size must be 64")
+	require.Equal(t, sentinel, recoverSafePanic(func() { panic(sentinel) }))
+	require.Equal(t, "invalid length 12", recoverSafePanic(func() { panic("invalid length 12") }))
+	require.Equal(t, 7, recoverSafePanic(func() { panic(7) }))
+	r = recoverSafePanic(func() {
+		var b []byte
+		_ = b[len(key)]
+	})
+	require.True(t, strings.Contains(fmt.Sprint(r), "index out of range"))
+
+	// Kept panics are raised again from where fn panicked, redacted ones
+	// once the stack of fn has been unwound.
+	require.Contains(t, recoverSafePanicStack(func() { panicInFn("oops") }), "panicInFn")
+	require.NotContains(t, recoverSafePanicStack(func() { panicInFn(hexKey) }), "panicInFn")
+
+	// The secrets are only zeroized when fn panics.
+	secret := []byte("secret")
+	require.Nil(t, recoverSafePanic(func() {}, secret))
+	require.Equal(t, []byte("secret"), secret)
+	recoverSafePanic(func() { panic("oops") }, secret)
+	require.True(t, CtIsZero(secret))
+}